@@ -4,6 +4,7 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"time"
 )
 
 // --- Configuration Structs ---
@@ -17,6 +18,255 @@ type VWZScoreConfig struct {
 	MinStdDev float64 `json:"minStdDev"`
 }
 
+// HigherTimeframeConfig declares a higher timeframe whose indicators are made
+// available alongside the base candles, e.g. a 1h DMI filter on 5m entries. It is shorthand for
+// the indicator declarations "ema_short_<timeframe>" (ema) and "adx_<timeframe>" (dmi) with
+// that timeframe; a declaration of the same name in Indicators replaces them.
+type HigherTimeframeConfig struct {
+	Timeframe string `json:"timeframe"`
+	EmaPeriod int    `json:"emaPeriod"` // defaults to the base emaPeriod
+	ADXPeriod int    `json:"adxPeriod"` // defaults to the base adxPeriod
+}
+
+// SessionConfig restricts entries to a trading session expressed in a named timezone.
+// Start and End are "HH:MM" wall-clock times; a session with End before Start spans midnight.
+// Days lists the weekdays ("Mon", "Tue", ...) the session is open; empty means every day.
+type SessionConfig struct {
+	Timezone string   `json:"timezone"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+	Days     []string `json:"days"`
+}
+
+// BarConfig selects how trade prints from TickFilePath are aggregated into candles.
+// Type is one of "time", "tick", "volume", "dollar", "range" or "renko".
+// Time bars use Timeframe; the others close on Size ticks, quantity, notional or price units.
+// A tick bar Size must be a whole number.
+type BarConfig struct {
+	Type      string  `json:"type"`
+	Size      float64 `json:"size"`
+	Timeframe string  `json:"timeframe"`
+}
+
+// IndicatorConfig declares a named indicator series. Type selects the computation
+// (e.g. "ema", "dmi", "macd") and Params overrides its default parameters.
+// With a Timeframe the indicator is computed on resampled bars and aligned to the base candles.
+// Anchor is the start time of anchored indicators such as "anchored_vwap", in the same
+// formats as StartTime.
+type IndicatorConfig struct {
+	Name      string             `json:"name"`
+	Type      string             `json:"type"`
+	Timeframe string             `json:"timeframe,omitempty"`
+	Anchor    string             `json:"anchor,omitempty"`
+	Params    map[string]float64 `json:"params"`
+}
+
+// ChartConfig controls the HTML chart. Overlays lists indicator series drawn over the price,
+// e.g. "ema_short" or "bbw.upper"; Panels lists the panels drawn under the price chart and
+// defaults to one with the box filter and VWZ. BBWShading shades the price chart by the BBW
+// state of each candle; HeikinAshi draws Heikin-Ashi candles instead of the raw ones.
+type ChartConfig struct {
+	Overlays   []string           `json:"overlays"`
+	Panels     []ChartPanelConfig `json:"panels"`
+	BBWShading bool               `json:"bbwShading"`
+	HeikinAshi bool               `json:"heikinAshi"`
+}
+
+// ChartPanelConfig is a panel under the price chart sharing its time axis. Series are drawn as
+// lines and Histograms as bars, e.g. "macd.histogram"; Levels draws horizontal reference lines.
+type ChartPanelConfig struct {
+	Title      string    `json:"title"`
+	Series     []string  `json:"series"`
+	Histograms []string  `json:"histograms"`
+	Levels     []float64 `json:"levels"`
+}
+
+// RuleConfig declares a condition in the rule language instead of by registry name.
+// Entry opens a trade; the optional Stop closes an open trade in the same direction.
+type RuleConfig struct {
+	Entry string `json:"entry"`
+	Stop  string `json:"stop,omitempty"`
+}
+
+// ConditionConfig is a node of a condition tree. A leaf names a registered condition (Name) or
+// holds an entry rule (Rule); the operators "all", "any", "not", "vote", "confirm" and
+// "cooldown" combine their Children.
+type ConditionConfig struct {
+	Op       string            `json:"op,omitempty"`
+	Name     string            `json:"name,omitempty"`
+	Rule     string            `json:"rule,omitempty"`
+	Children []ConditionConfig `json:"children,omitempty"`
+	// K is the number of children that must agree for "vote".
+	K int `json:"k,omitempty"`
+	// Bars is the number of candles "confirm" requires and "cooldown" waits after an exit.
+	Bars int `json:"bars,omitempty"`
+	// Trace logs the result of this node and everything below it on every evaluation.
+	Trace bool `json:"trace,omitempty"`
+	// Regime gates this node; every filter must pass for it to enter or stop.
+	Regime []RegimeFilterConfig `json:"regime,omitempty"`
+}
+
+// ConditionParams holds one parameter block per registered condition. A zero value takes the
+// default noted on the field.
+type ConditionParams struct {
+	Default  DefaultConditionParams  `json:"default"`
+	MACD     MACDConditionParams     `json:"macd"`
+	BBW      BBWConditionParams      `json:"bbw"`
+	Combined CombinedConditionParams `json:"combined"`
+	Inverse  InverseConditionParams  `json:"inverse"`
+	DMI      DMIConditionParams      `json:"dmi"`
+}
+
+type DefaultConditionParams struct {
+	// ZScoreLevel is the z-score a long entry must be below and a short entry above (default 0).
+	ZScoreLevel float64 `json:"zscoreLevel"`
+}
+
+type MACDConditionParams struct {
+	// HistogramLevel is the level the MACD histogram must cross (default 0).
+	HistogramLevel float64 `json:"histogramLevel"`
+}
+
+type BBWConditionParams struct {
+	// MinBoxFilter is the box filter value an entry must exceed (default 2).
+	MinBoxFilter float64 `json:"minBoxFilter"`
+}
+
+type CombinedConditionParams struct {
+	// VolatilityRatio is the bar-to-bar BBW ratio counted as a volatility explosion (default 1.5).
+	VolatilityRatio float64 `json:"volatilityRatio"`
+	// ZScoreSpike and VWZSpike are the bar-to-bar jumps counted as spikes (defaults 1.2 and 1.0).
+	ZScoreSpike float64 `json:"zscoreSpike"`
+	VWZSpike    float64 `json:"vwzSpike"`
+}
+
+type InverseConditionParams struct {
+	// BBWZExtreme is the absolute BBW z-score an entry must exceed (default 2.5).
+	BBWZExtreme float64 `json:"bbwzExtreme"`
+}
+
+type DMIConditionParams struct {
+	// ADXThreshold is the ADX an entry must exceed and DXThreshold the minimum spread between
+	// +DI and -DI; both default to the top-level adxThreshold.
+	ADXThreshold float64 `json:"adxThreshold"`
+	DXThreshold  float64 `json:"dxThreshold"`
+}
+
+// ExitParams holds one parameter block per registered exit condition. A zero value takes the
+// default noted on the field.
+type ExitParams struct {
+	ADXFalling   ADXFallingExitParams   `json:"adxFalling"`
+	VWZReversion VWZReversionExitParams `json:"vwzReversion"`
+	MaxBars      MaxBarsExitParams      `json:"maxBars"`
+	Trailing     TrailingExitParams     `json:"trailing"`
+}
+
+type ADXFallingExitParams struct {
+	// Bars is the number of consecutive falling ADX values that closes the trade (default 1).
+	Bars int `json:"bars"`
+}
+
+type VWZReversionExitParams struct {
+	// Band is how close to zero the VWZ score must come back to close the trade (default 0).
+	Band float64 `json:"band"`
+}
+
+type MaxBarsExitParams struct {
+	// Bars is the longest a trade is held, in candles; required when "max_bars" is used.
+	Bars int `json:"bars"`
+}
+
+type TrailingExitParams struct {
+	// Giveback is the fraction of the entry price a trade may give back from its best
+	// excursion before it is closed; required when "trailing" is used.
+	Giveback float64 `json:"giveback"`
+}
+
+// RegimeFilterConfig restricts a condition to a market regime. Type is one of:
+//   - "adx_band": ADX strictly between Min and Max (defaults adxThreshold and adxUpperThreshold).
+//   - "bbw_state": the BBW regime is one of States (e.g. "Squeeze", "ExpandingBullish").
+//   - "volatility_percentile": the percentile rank (0-100) of the current ATR among the last
+//     Window values (default 100) is between Min and Max (default 100).
+//   - "regime": the classified market regime (see RegimeConfig) is one of States
+//     (e.g. "trending_up", "ranging").
+//   - "time_of_day": the candle falls inside Session.
+//   - "score": the probability that the signal's trade wins, estimated by the score model at
+//     Model (default scoring.model), is at least Min (default 0.5).
+//   - "none": always passes.
+//
+// Min and Max take their defaults only when they are left out, so an explicit 0 is a bound
+// of 0; Min must be below Max.
+type RegimeFilterConfig struct {
+	Type    string         `json:"type"`
+	Min     *float64       `json:"min,omitempty"`
+	Max     *float64       `json:"max,omitempty"`
+	States  []string       `json:"states,omitempty"`
+	Window  int            `json:"window,omitempty"`
+	Session *SessionConfig `json:"session,omitempty"`
+	Model   string         `json:"model,omitempty"`
+}
+
+// RegimeConfig configures the market regime classifier, which labels every candle
+// "trending_up", "trending_down", "ranging", "high_volatility", "low_volatility" or, while
+// its inputs are still warming up, "unknown".
+type RegimeConfig struct {
+	// Method is "rules" (default), which thresholds the inputs candle by candle, or "hmm",
+	// which fits a Gaussian hidden Markov model to them and labels its states with the same rules.
+	Method string `json:"method"`
+	// Inputs are the features classified: "adx" (ADX and the DI spread), "bbwz" (the BBW
+	// z-score) and "realized_vol" (the z-score of realized volatility). Empty means all three.
+	Inputs []string `json:"inputs"`
+	// TrendADX is the ADX at or above which a candle is trending (default adxThreshold).
+	TrendADX float64 `json:"trendADX"`
+	// HighVolZ and LowVolZ are the volatility z-scores at or beyond which a candle that is not
+	// trending is high or low volatility (defaults 1 and -1).
+	HighVolZ float64 `json:"highVolZ"`
+	LowVolZ  float64 `json:"lowVolZ"`
+	// VolWindow is the number of log returns realized volatility is measured over (default 20)
+	// and VolLookback the number of earlier values its z-score is taken against (default 100).
+	VolWindow   int `json:"volWindow"`
+	VolLookback int `json:"volLookback"`
+	// HMM configures the "hmm" method.
+	HMM HMMConfig `json:"hmm"`
+	// Sizing scales the position size of trades entered in a regime, keyed by regime name.
+	// Regimes not listed trade at size 1; a size of 0 skips entries in that regime.
+	Sizing map[string]float64 `json:"sizing"`
+}
+
+// HMMConfig configures the hidden Markov model regime classifier.
+type HMMConfig struct {
+	States     int `json:"states"`     // defaults to 3
+	Iterations int `json:"iterations"` // Baum-Welch iterations, defaults to 100
+	// FitBars is the number of leading candles the model is fitted on. It defaults to the
+	// warmup candles before startTime and may not exceed them, so the model never sees the
+	// candles it trades on; the "hmm" method therefore needs a startTime with warmupBars.
+	FitBars int `json:"fitBars"`
+}
+
+// ScoringConfig configures the logistic model that scores signals by their chance of winning.
+// The "score" command trains it on the trades of a backtest and writes it to Model.
+type ScoringConfig struct {
+	Model string `json:"model"`
+	// Lambda is the L2 penalty on the weights (default 1).
+	Lambda float64 `json:"lambda"`
+	// TestFraction is the share of the latest trades held out to evaluate the model (default 0.3).
+	TestFraction float64 `json:"testFraction"`
+}
+
+// ExportConfig configures the "export" run mode, which writes a research dataset of indicator
+// values, regimes, condition verdicts and forward-looking labels.
+type ExportConfig struct {
+	// Path is the output file; a ".parquet" extension writes Parquet, anything else CSV.
+	Path string `json:"path"`
+	// Rows is "candles" (default) for one row per candle or "signals" for one row per entry signal.
+	Rows string `json:"rows"`
+	// Horizons are the forward-return horizons in candles (default [1, 5, 20]).
+	Horizons []int `json:"horizons"`
+	// MaxHolding is the vertical barrier of the triple-barrier labels in candles (default 20);
+	// the horizontal barriers are TPRate and SLRate.
+	MaxHolding int `json:"maxHolding"`
+}
+
 type Config struct {
 	FilePath          string          `json:"filePath"`
 	VWZPeriod         int             `json:"vwzPeriod"`
@@ -35,6 +285,75 @@ type Config struct {
 	LongCondition     string          `json:"longCondition"`
 	ShortCondition    string          `json:"shortCondition"`
 	RunMode           string          `json:"run_mode"`
+	// BaseTimeframe is the bar interval of the input file (e.g. "5m").
+	// It is inferred from the candle spacing when empty.
+	BaseTimeframe    string                  `json:"baseTimeframe"`
+	HigherTimeframes []HigherTimeframeConfig `json:"higherTimeframes"`
+	// SourceTimezone is the timezone of the timestamps in the input file (default UTC).
+	// All times are normalized to UTC internally.
+	SourceTimezone string `json:"sourceTimezone"`
+	// DisplayTimezone is the timezone used for reports and the chart (default UTC).
+	DisplayTimezone string         `json:"displayTimezone"`
+	Session         *SessionConfig `json:"session"`
+	// TickFilePath, when set, loads trade prints instead of FilePath and builds bars per Bars.
+	TickFilePath string    `json:"tickFilePath"`
+	Bars         BarConfig `json:"bars"`
+	// CandleCache loads FilePath from a binary cache when it is valid and rebuilds it otherwise.
+	// Caches are written to CacheDir, or next to the CSV file when CacheDir is empty.
+	CandleCache bool   `json:"candleCache"`
+	CacheDir    string `json:"cacheDir"`
+	// StartTime and EndTime limit trading to [start, end), as "YYYY-MM-DD", "YYYY-MM-DD HH:MM:SS"
+	// in the source timezone, or RFC 3339. A date-only end includes that whole day.
+	// WarmupBars candles before the start are kept so indicators are settled at the first tradable bar.
+	StartTime  string `json:"startTime"`
+	EndTime    string `json:"endTime"`
+	WarmupBars int    `json:"warmupBars"`
+	// ATRPeriod is used by the default "atr" series and BBW state detection (default 14).
+	ATRPeriod int `json:"atrPeriod"`
+	// Indicators declares additional indicator series, or replaces a default one of the same name.
+	Indicators []IndicatorConfig `json:"indicators"`
+	// LookbackDepth is the number of recent values each indicator window passed to the
+	// conditions holds (default 3, at least 2 so conditions can compare with the previous value).
+	LookbackDepth int `json:"lookbackDepth"`
+	// ConditionParams configures the registered conditions named by LongCondition,
+	// ShortCondition and the condition trees.
+	ConditionParams ConditionParams `json:"conditionParams"`
+	// LongExits and ShortExits name the exit conditions for each direction; a trade is closed
+	// when any of them holds, besides the TPRate/SLRate price exits. They default to
+	// ["signal_stop", "reverse_signal"].
+	LongExits  []string   `json:"longExits"`
+	ShortExits []string   `json:"shortExits"`
+	ExitParams ExitParams `json:"exitParams"`
+	// LongRule and ShortRule, when their Entry is set, replace LongCondition and ShortCondition.
+	LongRule  RuleConfig `json:"longRule"`
+	ShortRule RuleConfig `json:"shortRule"`
+	// LongRegime and ShortRegime gate each direction's condition; every filter must pass.
+	// They default to a single "adx_band" filter; an empty list disables the gate.
+	LongRegime  []RegimeFilterConfig `json:"longRegime"`
+	ShortRegime []RegimeFilterConfig `json:"shortRegime"`
+	// ConflictPolicy settles candles where both directions enter: "long_first" (default)
+	// takes the long entry, "short_first" the short one and "skip" neither. Stop flags play
+	// no part in it; they only close open trades.
+	ConflictPolicy string `json:"conflictPolicy"`
+	// LongConditions and ShortConditions, when set, combine registered conditions and rules
+	// and take precedence over LongCondition and ShortCondition.
+	LongConditions  *ConditionConfig `json:"longConditions"`
+	ShortConditions *ConditionConfig `json:"shortConditions"`
+	// DecisionTrace, when set, is the path of a JSON Lines file receiving every entry decision
+	// with the checks behind it; a summary of the most frequent rejections is printed at the end.
+	DecisionTrace string `json:"decisionTrace"`
+	// Regime configures the per-candle market regime used by "regime" filters, position
+	// sizing and the regime report.
+	Regime RegimeConfig `json:"regime"`
+	// Scoring configures the signal score model used by "score" filters.
+	Scoring ScoringConfig `json:"scoring"`
+	// Export configures the dataset written in the "export" run mode.
+	Export ExportConfig `json:"export"`
+	// OutputDir is the directory chart.html and the JSON and CSV result files are written to
+	// (default the current directory).
+	OutputDir string `json:"outputDir"`
+	// Chart selects what the HTML chart draws besides the candles.
+	Chart ChartConfig `json:"chart"`
 }
 
 // LoadConfig reads and parses the configuration file.
@@ -53,3 +372,83 @@ func LoadConfig(path string) (*Config, error) {
 	}
 	return cfg, nil
 }
+
+// SourceLocation returns the timezone of the input file timestamps.
+func (c *Config) SourceLocation() (*time.Location, error) {
+	return loadLocation(c.SourceTimezone)
+}
+
+// DisplayLocation returns the timezone used to present times in reports and charts.
+func (c *Config) DisplayLocation() (*time.Location, error) {
+	return loadLocation(c.DisplayTimezone)
+}
+
+// TimeRange returns the configured backtest window. Zero times mean the range is open on that side.
+func (c *Config) TimeRange() (start, end time.Time, err error) {
+	loc, err := c.SourceLocation()
+	if err != nil {
+		return start, end, err
+	}
+	if c.StartTime != "" {
+		if start, _, err = parseTime(c.StartTime, loc); err != nil {
+			return start, end, fmt.Errorf("invalid startTime: %w", err)
+		}
+	}
+	if c.EndTime != "" {
+		var dateOnly bool
+		if end, dateOnly, err = parseTime(c.EndTime, loc); err != nil {
+			return start, end, fmt.Errorf("invalid endTime: %w", err)
+		}
+		if dateOnly {
+			end = end.AddDate(0, 0, 1)
+		}
+	}
+	if !start.IsZero() && !end.IsZero() && !start.Before(end) {
+		return start, end, fmt.Errorf("startTime %s is not before endTime %s", c.StartTime, c.EndTime)
+	}
+	if c.WarmupBars < 0 {
+		return start, end, fmt.Errorf("warmupBars must not be negative")
+	}
+	return start.UTC(), end.UTC(), nil
+}
+
+// ParseTime parses a date or date-time in the source timezone, in the same formats as StartTime,
+// and returns it in UTC.
+func (c *Config) ParseTime(value string) (time.Time, error) {
+	loc, err := c.SourceLocation()
+	if err != nil {
+		return time.Time{}, err
+	}
+	t, _, err := parseTime(value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
+
+// parseTime parses a date or date-time in loc, reporting whether only a date was given.
+func parseTime(value string, loc *time.Location) (time.Time, bool, error) {
+	if t, err := time.ParseInLocation("2006-01-02", value, loc); err == nil {
+		return t, true, nil
+	}
+	if t, err := time.ParseInLocation("2006-01-02 15:04:05", value, loc); err == nil {
+		return t, false, nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return t, false, fmt.Errorf("expected YYYY-MM-DD, YYYY-MM-DD HH:MM:SS or RFC 3339, got %q", value)
+	}
+	return t, false, nil
+}
+
+// loadLocation resolves a timezone name, treating an empty name as UTC.
+func loadLocation(name string) (*time.Location, error) {
+	if name == "" {
+		return time.UTC, nil
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("unknown timezone %q: %w", name, err)
+	}
+	return loc, nil
+}
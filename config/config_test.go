@@ -3,6 +3,7 @@ package config_test
 import (
 	"os"
 	"testing"
+	"time"
 
 	"go-backtesting/config"
 )
@@ -72,3 +73,37 @@ func TestLoadConfig(t *testing.T) {
 		t.Errorf("Expected VWZScore.MinStdDev to be 1e-5, but got %f", cfg.VWZScore.MinStdDev)
 	}
 }
+
+func TestTimeRange(t *testing.T) {
+	cfg := &config.Config{
+		SourceTimezone: "Asia/Seoul",
+		StartTime:      "2023-01-01 09:00:00",
+		EndTime:        "2023-01-31",
+	}
+	start, end, err := cfg.TimeRange()
+	if err != nil {
+		t.Fatalf("TimeRange failed: %v", err)
+	}
+	if expected := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC); !start.Equal(expected) {
+		t.Errorf("Expected start to be %v, but got %v", expected, start)
+	}
+	// A date-only end includes the whole day in the source timezone.
+	if expected := time.Date(2023, 1, 31, 15, 0, 0, 0, time.UTC); !end.Equal(expected) {
+		t.Errorf("Expected end to be %v, but got %v", expected, end)
+	}
+
+	open := &config.Config{}
+	if start, end, err := open.TimeRange(); err != nil || !start.IsZero() || !end.IsZero() {
+		t.Errorf("Expected an open range, but got %v, %v, %v", start, end, err)
+	}
+
+	for _, invalid := range []*config.Config{
+		{StartTime: "01/02/2023"},
+		{StartTime: "2023-02-01", EndTime: "2023-01-01"},
+		{WarmupBars: -1},
+	} {
+		if _, _, err := invalid.TimeRange(); err == nil {
+			t.Errorf("Expected TimeRange to fail for %+v", invalid)
+		}
+	}
+}
@@ -1,9 +1,13 @@
 package main
 
 import (
+	"fmt"
 	"log"
+	"os"
+	_ "time/tzdata" // embed the timezone database so named timezones work on any host
 
 	"go-backtesting/config"
+	"go-backtesting/market"
 	"go-backtesting/reporting"
 	"go-backtesting/strategy"
 )
@@ -15,40 +19,107 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	// --- 2. Initialize All Strategy Data ---
-	strategyData, err := strategy.InitializeStrategyDataContext(cfg)
+	displayLoc, err := cfg.DisplayLocation()
 	if err != nil {
-		log.Fatalf("Failed to initialize strategy data: %v", err)
+		log.Fatalf("Invalid display timezone: %v", err)
+	}
+	reporting.DisplayLocation = displayLoc
+	if cfg.OutputDir != "" {
+		if err := os.MkdirAll(cfg.OutputDir, 0o755); err != nil {
+			log.Fatalf("Failed to create output directory: %v", err)
+		}
+		reporting.OutputDir = cfg.OutputDir
 	}
 
-	if len(strategyData.Candles) == 0 {
-		log.Println("No data available for the specified date range.")
+	if len(os.Args) > 1 && os.Args[1] == "cache" {
+		runCacheCommand(cfg, os.Args[2:])
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "score" {
+		runScoreCommand(cfg)
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "compare" {
+		runCompareCommand(os.Args[2:])
 		return
 	}
 
-	// --- 3. Get Entry Conditions ---
-	longCondition, err := strategy.GetEntryCondition(cfg.LongCondition, "long")
+	// --- 2. Get Entry Conditions ---
+	longCondition, err := strategy.ResolveEntryCondition(cfg, "long")
 	if err != nil {
 		log.Fatalf("Failed to get long entry condition: %v", err)
 	}
 
-	shortCondition, err := strategy.GetEntryCondition(cfg.ShortCondition, "short")
+	shortCondition, err := strategy.ResolveEntryCondition(cfg, "short")
 	if err != nil {
 		log.Fatalf("Failed to get short entry condition: %v", err)
 	}
 
+	exits, err := strategy.ResolveExits(cfg, longCondition, shortCondition)
+	if err != nil {
+		log.Fatalf("Failed to get exit conditions: %v", err)
+	}
+
+	// --- 3. Initialize All Strategy Data ---
+	strategyData, err := strategy.InitializeStrategyDataContext(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize strategy data: %v", err)
+	}
+
+	if len(strategyData.Candles) == 0 {
+		log.Println("No data available for the specified date range.")
+		return
+	}
+
+	if cfg.DecisionTrace != "" {
+		trace, err := strategy.CreateDecisionTrace(cfg.DecisionTrace)
+		if err != nil {
+			log.Fatalf("Failed to start decision trace: %v", err)
+		}
+		strategyData.Trace = trace
+		defer func() {
+			if err := trace.Close(); err != nil {
+				log.Printf("Failed to write decision trace: %v", err)
+			}
+			reporting.PrintRejectionSummary(trace.Rejections())
+		}()
+	}
+
 	// --- 4. Run Selected Mode ---
-	if cfg.RunMode == "signals" {
+	if cfg.RunMode == "export" {
+		// --- Write the Research Dataset ---
+		if cfg.Export.Path == "" {
+			log.Fatalf("Set export.path to the file the dataset should be written to")
+		}
+		dataset, err := strategy.BuildDataset(strategyData, cfg, longCondition, shortCondition)
+		if err != nil {
+			log.Fatalf("Failed to build dataset: %v", err)
+		}
+		if err := reporting.ExportDataset(cfg.Export.Path, dataset); err != nil {
+			log.Fatalf("Failed to export dataset: %v", err)
+		}
+		fmt.Printf("Wrote %d rows to %s\n", dataset.Len(), cfg.Export.Path)
+	} else if cfg.RunMode == "signals" {
 		// --- Generate and Print All Signals ---
 		signals := strategy.GenerateAllSignals(strategyData, cfg, longCondition, shortCondition)
 		reporting.PrintAllSignals(signals)
-		reporting.GenerateHTMLChart(strategyData.Candles, strategyData.BoxFilter, strategyData.VwzScores, signals)
+		if err := reporting.ExportSignals(signals); err != nil {
+			log.Printf("Failed to export signals: %v", err)
+		}
+		reporting.GenerateHTMLChart(chartCandles(cfg, strategyData), signals, chartSpec(cfg, strategyData))
 	} else {
 		// --- Run Backtest and Print Results ---
-		result := strategy.RunBacktest(strategyData, cfg, longCondition, shortCondition)
+		result := strategy.RunBacktest(strategyData, cfg, longCondition, shortCondition, exits)
 		reporting.PrintDetailedTradeRecords(result)
 		reporting.PrintTradeAnalysis(result, strategyData)
 		reporting.PrintBacktestSummary(result)
+		reporting.PrintRegimeSummary(result, strategyData.Regimes)
+		if err := reporting.ExportBacktestResult(result); err != nil {
+			log.Printf("Failed to export backtest result: %v", err)
+		}
+		if err := reporting.GenerateTearsheet(strategyData.Candles, result, cfg.TPRate, cfg.SLRate); err != nil {
+			log.Printf("Failed to generate tearsheet: %v", err)
+		}
 
 		var entrySignals []strategy.EntrySignal
 		for _, trade := range result.Trades {
@@ -58,6 +129,156 @@ func main() {
 				Direction: trade.Direction,
 			})
 		}
-		reporting.GenerateHTMLChart(strategyData.Candles, strategyData.BoxFilter, strategyData.VwzScores, entrySignals)
+		spec := chartSpec(cfg, strategyData)
+		spec.Trades, spec.TakeProfitRate, spec.StopLossRate = result.Trades, cfg.TPRate, cfg.SLRate
+		reporting.GenerateHTMLChart(chartCandles(cfg, strategyData), entrySignals, spec)
+	}
+}
+
+// chartCandles returns the candles to draw, Heikin-Ashi when configured.
+func chartCandles(cfg *config.Config, strategyData *strategy.StrategyDataContext) market.CandleSticks {
+	if cfg.Chart.HeikinAshi {
+		return market.HeikinAshi(strategyData.Candles)
+	}
+	return strategyData.Candles
+}
+
+// chartSpec looks up the indicator series of the configured overlays and panels; series that
+// are not registered are skipped.
+func chartSpec(cfg *config.Config, strategyData *strategy.StrategyDataContext) reporting.ChartSpec {
+	lookup := func(name string, histogram bool) (reporting.ChartSeries, bool) {
+		series := strategyData.Series(name)
+		if series == nil {
+			log.Printf("Chart series %q is not a known indicator series; skipping it", name)
+			return reporting.ChartSeries{}, false
+		}
+		return reporting.ChartSeries{Name: name, Values: series, Histogram: histogram}, true
+	}
+
+	var spec reporting.ChartSpec
+	for _, name := range cfg.Chart.Overlays {
+		if series, ok := lookup(name, false); ok {
+			spec.Overlays = append(spec.Overlays, series)
+		}
+	}
+
+	panels := cfg.Chart.Panels
+	if len(panels) == 0 {
+		panels = []config.ChartPanelConfig{{
+			Series: []string{strategy.SeriesBoxFilter, strategy.SeriesVWZ},
+			Levels: []float64{0, cfg.ZScoreThreshold, -cfg.ZScoreThreshold},
+		}}
+	}
+	for _, p := range panels {
+		panel := reporting.ChartPanel{Title: p.Title, Levels: p.Levels}
+		for _, name := range p.Series {
+			if series, ok := lookup(name, false); ok {
+				panel.Series = append(panel.Series, series)
+			}
+		}
+		for _, name := range p.Histograms {
+			if series, ok := lookup(name, true); ok {
+				panel.Series = append(panel.Series, series)
+			}
+		}
+		if len(panel.Series) > 0 {
+			spec.Panels = append(spec.Panels, panel)
+		}
+	}
+
+	if cfg.Chart.BBWShading {
+		states := strategyData.BBWStatesFor(cfg)
+		spec.Shading = make([]string, len(states))
+		for i, state := range states {
+			switch state.Status {
+			case strategy.Squeeze, strategy.ExpandingBullish, strategy.ExpandingBearish, strategy.Volatile:
+				spec.Shading[i] = string(state.Status)
+			}
+		}
+	}
+	return spec
+}
+
+// runCacheCommand builds binary candle caches for every CSV file in the given directories,
+// using the configured source timezone and cache directory.
+// Usage: go-backtesting cache <dir> [dir...]
+func runCacheCommand(cfg *config.Config, dirs []string) {
+	if len(dirs) == 0 {
+		log.Fatalf("Usage: %s cache <dir> [dir...]", os.Args[0])
+	}
+	sourceLoc, err := cfg.SourceLocation()
+	if err != nil {
+		log.Fatalf("Invalid source timezone: %v", err)
+	}
+	for _, dir := range dirs {
+		built, err := market.BuildCacheDir(dir, cfg.CacheDir, sourceLoc)
+		for _, path := range built {
+			fmt.Println("Built", path)
+		}
+		if err != nil {
+			log.Fatalf("Failed to build caches in %s: %v", dir, err)
+		}
+	}
+}
+
+// runScoreCommand backtests the configured conditions, trains the signal score model on the
+// resulting trades and writes it to the configured model path. Train on conditions without
+// "score" filters, or the model only learns from the signals the previous model let through.
+// Usage: go-backtesting score
+func runScoreCommand(cfg *config.Config) {
+	if cfg.Scoring.Model == "" {
+		log.Fatalf("Set scoring.model to the path the score model should be written to")
+	}
+	longCondition, err := strategy.ResolveEntryCondition(cfg, "long")
+	if err != nil {
+		log.Fatalf("Failed to get long entry condition: %v", err)
+	}
+	shortCondition, err := strategy.ResolveEntryCondition(cfg, "short")
+	if err != nil {
+		log.Fatalf("Failed to get short entry condition: %v", err)
+	}
+	exits, err := strategy.ResolveExits(cfg, longCondition, shortCondition)
+	if err != nil {
+		log.Fatalf("Failed to get exit conditions: %v", err)
+	}
+	strategyData, err := strategy.InitializeStrategyDataContext(cfg)
+	if err != nil {
+		log.Fatalf("Failed to initialize strategy data: %v", err)
+	}
+
+	result := strategy.RunBacktest(strategyData, cfg, longCondition, shortCondition, exits)
+	model, report, err := strategy.TrainScoreModel(result.Trades, cfg.Scoring)
+	if err != nil {
+		log.Fatalf("Failed to train score model: %v", err)
+	}
+	if err := model.Save(cfg.Scoring.Model); err != nil {
+		log.Fatalf("Failed to save score model: %v", err)
+	}
+	reporting.PrintScoreReport(model, report)
+	fmt.Println("Wrote score model to", cfg.Scoring.Model)
+}
+
+// runCompareCommand compares backtest results saved as result.json, printing their metrics and
+// trade differences and writing compare.html. The first file is the baseline.
+// Usage: go-backtesting compare <result.json> <result.json> [result.json...]
+func runCompareCommand(paths []string) {
+	if len(paths) < 2 {
+		log.Fatalf("Usage: %s compare <result.json> <result.json> [result.json...]", os.Args[0])
+	}
+	runs := make([]reporting.ComparedRun, len(paths))
+	for k, path := range paths {
+		file, err := reporting.LoadResultFile(path)
+		if err != nil {
+			log.Fatalf("Failed to load result: %v", err)
+		}
+		result, err := file.BacktestResult()
+		if err != nil {
+			log.Fatalf("Failed to read result %s: %v", path, err)
+		}
+		runs[k] = reporting.ComparedRun{Name: path, Result: result}
+	}
+	reporting.PrintComparison(runs)
+	if err := reporting.GenerateComparisonHTML(runs); err != nil {
+		log.Printf("Failed to generate comparison: %v", err)
 	}
 }
@@ -0,0 +1,189 @@
+package market
+
+import (
+	"math"
+	"time"
+)
+
+// Bar builders aggregate trade prints into CandleSticks so that every indicator and
+// condition can run on alternative bar types. A bar's Time is the time of its first tick,
+// except for time bars, which use the calendar-aligned bucket start.
+
+// TimeBars groups ticks into fixed calendar-aligned time intervals.
+func TimeBars(ticks Ticks, interval time.Duration) CandleSticks {
+	if interval <= 0 {
+		return nil
+	}
+	var bars CandleSticks
+	for _, t := range ticks {
+		start := t.Time.Truncate(interval)
+		if n := len(bars); n > 0 && bars[n-1].Time.Equal(start) {
+			bars[n-1].add(t)
+			continue
+		}
+		bar := newBar(t)
+		bar.Time = start
+		bars = append(bars, bar)
+	}
+	return bars
+}
+
+// TickBars closes a bar after every count ticks.
+func TickBars(ticks Ticks, count int) CandleSticks {
+	if count <= 0 {
+		return nil
+	}
+	n := 0
+	return thresholdBars(ticks, func(Tick) bool {
+		n++
+		if n == count {
+			n = 0
+			return true
+		}
+		return false
+	})
+}
+
+// VolumeBars closes a bar once the traded quantity reaches volume.
+func VolumeBars(ticks Ticks, volume float64) CandleSticks {
+	if volume <= 0 {
+		return nil
+	}
+	sum := 0.0
+	return thresholdBars(ticks, func(t Tick) bool {
+		sum += t.Qty
+		if sum >= volume {
+			sum = 0
+			return true
+		}
+		return false
+	})
+}
+
+// DollarBars closes a bar once the traded notional (price * quantity) reaches value.
+func DollarBars(ticks Ticks, value float64) CandleSticks {
+	if value <= 0 {
+		return nil
+	}
+	sum := 0.0
+	return thresholdBars(ticks, func(t Tick) bool {
+		sum += t.Price * t.Qty
+		if sum >= value {
+			sum = 0
+			return true
+		}
+		return false
+	})
+}
+
+// thresholdBars accumulates ticks into a bar until closeAfter reports that the tick
+// just added completes it. A trailing partial bar is kept.
+func thresholdBars(ticks Ticks, closeAfter func(Tick) bool) CandleSticks {
+	var bars CandleSticks
+	open := false
+	for _, t := range ticks {
+		if !open {
+			bars = append(bars, newBar(t))
+			open = true
+		} else {
+			bars[len(bars)-1].add(t)
+		}
+		if closeAfter(t) {
+			open = false
+		}
+	}
+	return bars
+}
+
+// RangeBars starts a new bar whenever a tick would push the current bar's high-low range
+// beyond size price units, so every bar spans at most size.
+func RangeBars(ticks Ticks, size float64) CandleSticks {
+	if size <= 0 {
+		return nil
+	}
+	var bars CandleSticks
+	for _, t := range ticks {
+		n := len(bars)
+		if n == 0 {
+			bars = append(bars, newBar(t))
+			continue
+		}
+		bar := &bars[n-1]
+		if math.Max(bar.High, t.Price)-math.Min(bar.Low, t.Price) > size {
+			bars = append(bars, newBar(t))
+			continue
+		}
+		bar.add(t)
+	}
+	return bars
+}
+
+// RenkoBars builds classic Renko bricks of size price units. A brick in the direction of
+// the trend needs a move of one brick beyond the last close; a reversal needs two.
+// Bricks carry the time of the tick that completed them and the volume traded since the
+// previous brick; their High and Low are the brick bounds.
+func RenkoBars(ticks Ticks, size float64) CandleSticks {
+	if size <= 0 || len(ticks) == 0 {
+		return nil
+	}
+	var bricks CandleSticks
+	last := math.Floor(ticks[0].Price/size) * size
+	direction := 0
+	vol := 0.0
+	for _, t := range ticks {
+		vol += t.Qty
+		for {
+			open, close, ok := nextBrick(t.Price, last, size, direction)
+			if !ok {
+				break
+			}
+			bricks = append(bricks, Candle{
+				Time:  t.Time,
+				Open:  open,
+				High:  math.Max(open, close),
+				Low:   math.Min(open, close),
+				Close: close,
+				Vol:   vol,
+			})
+			vol = 0
+			last = close
+			if close > open {
+				direction = 1
+			} else {
+				direction = -1
+			}
+		}
+	}
+	return bricks
+}
+
+// nextBrick returns the bounds of the brick formed by price, if any, given the last brick
+// close and the current trend direction (1 up, -1 down, 0 none yet).
+func nextBrick(price, last, size float64, direction int) (open, close float64, ok bool) {
+	switch {
+	case direction >= 0 && price >= last+size:
+		return last, last + size, true
+	case direction < 0 && price >= last+2*size:
+		return last + size, last + 2*size, true
+	case direction <= 0 && price <= last-size:
+		return last, last - size, true
+	case direction > 0 && price <= last-2*size:
+		return last - size, last - 2*size, true
+	}
+	return 0, 0, false
+}
+
+func newBar(t Tick) Candle {
+	return Candle{Time: t.Time, Open: t.Price, High: t.Price, Low: t.Price, Close: t.Price, Vol: t.Qty}
+}
+
+func (c *Candle) add(t Tick) {
+	if t.Price > c.High {
+		c.High = t.Price
+	}
+	if t.Price < c.Low {
+		c.Low = t.Price
+	}
+	c.Close = t.Price
+	c.Vol += t.Qty
+}
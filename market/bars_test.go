@@ -0,0 +1,103 @@
+package market_test
+
+import (
+	"go-backtesting/market"
+	"os"
+	"testing"
+	"time"
+)
+
+func makeTicks(prices ...float64) market.Ticks {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	ticks := make(market.Ticks, len(prices))
+	for i, p := range prices {
+		ticks[i] = market.Tick{Time: start.Add(time.Duration(i) * 20 * time.Second), Price: p, Qty: 1}
+	}
+	return ticks
+}
+
+func TestReadTicksFromCSV(t *testing.T) {
+	content := `time,price,qty,side
+1672531200000,100.5,0.2,buy
+2023-01-01 00:00:01.250,101,0.3,sell`
+	tmpfile, err := os.CreateTemp("", "test_ticks.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	ticks, err := market.ReadTicksFromCSV(tmpfile.Name(), time.UTC)
+	if err != nil {
+		t.Fatalf("ReadTicksFromCSV failed: %v", err)
+	}
+	if len(ticks) != 2 {
+		t.Fatalf("Expected 2 ticks, but got %d", len(ticks))
+	}
+	if !ticks[0].Time.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) || ticks[0].Price != 100.5 || ticks[0].Qty != 0.2 {
+		t.Errorf("Unexpected first tick: %+v", ticks[0])
+	}
+	if !ticks[1].Time.Equal(time.Date(2023, 1, 1, 0, 0, 1, 250e6, time.UTC)) {
+		t.Errorf("Expected fractional seconds to be parsed, but got %v", ticks[1].Time)
+	}
+}
+
+func TestTimeBars(t *testing.T) {
+	bars := market.TimeBars(makeTicks(10, 12, 9, 11, 13), time.Minute)
+	if len(bars) != 2 {
+		t.Fatalf("Expected 2 bars, but got %d", len(bars))
+	}
+	if bars[0].Open != 10 || bars[0].High != 12 || bars[0].Low != 9 || bars[0].Close != 9 || bars[0].Vol != 3 {
+		t.Errorf("Unexpected first bar: %+v", bars[0])
+	}
+	if !bars[1].Time.Equal(time.Date(2023, 1, 1, 0, 1, 0, 0, time.UTC)) || bars[1].Close != 13 {
+		t.Errorf("Unexpected second bar: %+v", bars[1])
+	}
+}
+
+func TestTickVolumeAndDollarBars(t *testing.T) {
+	ticks := makeTicks(10, 11, 12, 13, 14)
+
+	if bars := market.TickBars(ticks, 2); len(bars) != 3 || bars[0].Close != 11 || bars[2].Open != 14 {
+		t.Errorf("Unexpected tick bars: %+v", bars)
+	}
+	if bars := market.VolumeBars(ticks, 3); len(bars) != 2 || bars[0].Vol != 3 || bars[1].Vol != 2 {
+		t.Errorf("Unexpected volume bars: %+v", bars)
+	}
+	// Notional: 10+11=21 closes the first bar, 12+13=25 the second.
+	if bars := market.DollarBars(ticks, 20); len(bars) != 3 || bars[0].Close != 11 || bars[1].Close != 13 {
+		t.Errorf("Unexpected dollar bars: %+v", bars)
+	}
+}
+
+func TestRangeBars(t *testing.T) {
+	bars := market.RangeBars(makeTicks(10, 11, 12, 13, 12, 10), 2)
+	if len(bars) != 3 {
+		t.Fatalf("Expected 3 bars, but got %d: %+v", len(bars), bars)
+	}
+	for i, b := range bars {
+		if b.High-b.Low > 2 {
+			t.Errorf("Bar %d spans %f, more than the range size", i, b.High-b.Low)
+		}
+	}
+}
+
+func TestRenkoBars(t *testing.T) {
+	// Up two bricks, then a reversal needs a two-brick move before a down brick forms.
+	bricks := market.RenkoBars(makeTicks(100, 102, 104, 103, 101, 100), 2)
+	if len(bricks) != 3 {
+		t.Fatalf("Expected 3 bricks, but got %d: %+v", len(bricks), bricks)
+	}
+	expected := [][2]float64{{100, 102}, {102, 104}, {102, 100}}
+	for i, b := range bricks {
+		if b.Open != expected[i][0] || b.Close != expected[i][1] {
+			t.Errorf("Brick %d: expected %v -> %v, but got %v -> %v", i, expected[i][0], expected[i][1], b.Open, b.Close)
+		}
+	}
+}
@@ -0,0 +1,144 @@
+package market_test
+
+import (
+	"errors"
+	"fmt"
+	"go-backtesting/market"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestCandleCacheRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "candles.csv")
+	content := `Time,Open,High,Low,Close,Volume
+2023-01-01 00:00:00,100,105,95,102,1000
+2023-01-01 00:05:00,102,106,101,105,1200.5`
+	if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+
+	expected, err := market.ReadCandlesFromCSV(csvPath)
+	if err != nil {
+		t.Fatalf("ReadCandlesFromCSV failed: %v", err)
+	}
+
+	// The first load parses the CSV and writes the cache; the second must read it back unchanged.
+	cacheDir := filepath.Join(dir, "cache")
+	for run := 0; run < 2; run++ {
+		candles, err := market.LoadCandlesCached(csvPath, cacheDir, time.UTC)
+		if err != nil {
+			t.Fatalf("LoadCandlesCached failed on run %d: %v", run, err)
+		}
+		if len(candles) != len(expected) {
+			t.Fatalf("Run %d: expected %d candles, but got %d", run, len(expected), len(candles))
+		}
+		for i := range candles {
+			if candles[i] != expected[i] {
+				t.Errorf("Run %d, candle %d: expected %+v, but got %+v", run, i, expected[i], candles[i])
+			}
+		}
+	}
+
+	cachePath := market.CachePath(csvPath, cacheDir)
+	sum, err := market.FileChecksum(csvPath)
+	if err != nil {
+		t.Fatalf("FileChecksum failed: %v", err)
+	}
+	if _, err := market.ReadCandleCache(cachePath, sum, time.UTC); err != nil {
+		t.Errorf("Expected the cache to be valid, but got %v", err)
+	}
+
+	seoul, _ := time.LoadLocation("Asia/Seoul")
+	if _, err := market.ReadCandleCache(cachePath, sum, seoul); !errors.Is(err, market.ErrCacheInvalid) {
+		t.Errorf("Expected a timezone mismatch to invalidate the cache, but got %v", err)
+	}
+
+	sum[0] ^= 0xff
+	if _, err := market.ReadCandleCache(cachePath, sum, time.UTC); !errors.Is(err, market.ErrCacheInvalid) {
+		t.Errorf("Expected a source checksum mismatch to invalidate the cache, but got %v", err)
+	}
+
+	data, _ := os.ReadFile(cachePath)
+	data[len(data)/2] ^= 0xff
+	os.WriteFile(cachePath, data, 0o644)
+	sum[0] ^= 0xff
+	if _, err := market.ReadCandleCache(cachePath, sum, time.UTC); !errors.Is(err, market.ErrCacheInvalid) {
+		t.Errorf("Expected a corrupt cache to be rejected, but got %v", err)
+	}
+}
+
+func TestBuildCacheDir(t *testing.T) {
+	dir := t.TempDir()
+	content := "Time,Open,High,Low,Close,Volume\n2023-01-01 00:00:00,1,2,0.5,1.5,10\n"
+	for _, name := range []string{"a.csv", "b.CSV", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+	}
+
+	built, err := market.BuildCacheDir(dir, "", time.UTC)
+	if err != nil {
+		t.Fatalf("BuildCacheDir failed: %v", err)
+	}
+	if len(built) != 2 {
+		t.Fatalf("Expected 2 caches, but got %v", built)
+	}
+	for _, path := range built {
+		if _, err := os.Stat(path); err != nil {
+			t.Errorf("Expected cache %s to exist: %v", path, err)
+		}
+	}
+}
+
+func TestCachePathSeparatesSameNamedSources(t *testing.T) {
+	dir := t.TempDir()
+	cacheDir := filepath.Join(dir, "cache")
+	var paths []string
+	for k, sub := range []string{"btc", "eth"} {
+		csvPath := filepath.Join(dir, sub, "1m.csv")
+		content := fmt.Sprintf("Time,Open,High,Low,Close,Volume\n2023-01-01 00:00:00,%d,2,0.5,1.5,10\n", k+1)
+		if err := os.MkdirAll(filepath.Dir(csvPath), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(csvPath, []byte(content), 0o644); err != nil {
+			t.Fatalf("Failed to write %s: %v", csvPath, err)
+		}
+		paths = append(paths, csvPath)
+	}
+	if a, b := market.CachePath(paths[0], cacheDir), market.CachePath(paths[1], cacheDir); a == b {
+		t.Fatalf("Expected different caches for %s and %s, but both use %s", paths[0], paths[1], a)
+	}
+
+	for run := 0; run < 2; run++ {
+		for k, csvPath := range paths {
+			candles, err := market.LoadCandlesCached(csvPath, cacheDir, time.UTC)
+			if err != nil {
+				t.Fatalf("LoadCandlesCached failed: %v", err)
+			}
+			if len(candles) != 1 || candles[0].Open != float64(k+1) {
+				t.Errorf("Run %d: expected the candles of %s, but got %+v", run, csvPath, candles)
+			}
+		}
+	}
+}
+
+func TestLoadCandlesCachedWithoutWritableCache(t *testing.T) {
+	dir := t.TempDir()
+	csvPath := filepath.Join(dir, "candles.csv")
+	if err := os.WriteFile(csvPath, []byte("Time,Open,High,Low,Close,Volume\n2023-01-01 00:00:00,1,2,0.5,1.5,10\n"), 0o644); err != nil {
+		t.Fatalf("Failed to write CSV: %v", err)
+	}
+	// A regular file where the cache directory should be makes every cache write fail.
+	cacheDir := filepath.Join(dir, "cache")
+	if err := os.WriteFile(cacheDir, nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	candles, err := market.LoadCandlesCached(csvPath, cacheDir, time.UTC)
+	if err != nil || len(candles) != 1 {
+		t.Errorf("Expected the candles despite the failed cache write, but got %v, %v", candles, err)
+	}
+}
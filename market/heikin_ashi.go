@@ -0,0 +1,26 @@
+package market
+
+import "math"
+
+// HeikinAshi returns the Heikin-Ashi transform of candles. Each bar's close is the average of
+// its OHLC prices and its open is the midpoint of the previous Heikin-Ashi bar, which smooths
+// the series; times and volumes are kept.
+func HeikinAshi(candles CandleSticks) CandleSticks {
+	ha := make(CandleSticks, len(candles))
+	for i, c := range candles {
+		close := (c.Open + c.High + c.Low + c.Close) / 4
+		open := (c.Open + c.Close) / 2
+		if i > 0 {
+			open = (ha[i-1].Open + ha[i-1].Close) / 2
+		}
+		ha[i] = Candle{
+			Time:  c.Time,
+			Open:  open,
+			High:  math.Max(c.High, math.Max(open, close)),
+			Low:   math.Min(c.Low, math.Min(open, close)),
+			Close: close,
+			Vol:   c.Vol,
+		}
+	}
+	return ha
+}
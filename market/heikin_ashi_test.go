@@ -0,0 +1,25 @@
+package market_test
+
+import (
+	"go-backtesting/market"
+	"testing"
+)
+
+func TestHeikinAshi(t *testing.T) {
+	candles := market.CandleSticks{
+		{Open: 10, High: 14, Low: 9, Close: 13, Vol: 5},
+		{Open: 13, High: 15, Low: 12, Close: 12, Vol: 7},
+	}
+	ha := market.HeikinAshi(candles)
+
+	if ha[0].Open != 11.5 || ha[0].Close != 11.5 || ha[0].High != 14 || ha[0].Low != 9 {
+		t.Errorf("Expected the first bar to be O 11.5 H 14 L 9 C 11.5, but got %+v", ha[0])
+	}
+	// The second open is the midpoint of the first Heikin-Ashi bar, its close the OHLC average.
+	if ha[1].Open != 11.5 || ha[1].Close != 13 || ha[1].High != 15 || ha[1].Low != 11.5 {
+		t.Errorf("Expected the second bar to be O 11.5 H 15 L 11.5 C 13, but got %+v", ha[1])
+	}
+	if ha[1].Vol != 7 {
+		t.Errorf("Expected volume to be kept, but got %f", ha[1].Vol)
+	}
+}
@@ -23,8 +23,18 @@ type Candle struct {
 // CandleSticks is a slice of Candles.
 type CandleSticks []Candle
 
-// ReadCandlesFromCSV reads a CSV file and returns a slice of CandleSticks.
+// ReadCandlesFromCSV reads a CSV file whose timestamps are in UTC and returns a slice of CandleSticks.
 func ReadCandlesFromCSV(filePath string) (CandleSticks, error) {
+	return ReadCandlesFromCSVInLocation(filePath, time.UTC)
+}
+
+// ReadCandlesFromCSVInLocation reads a CSV file whose timestamps are wall-clock times in loc
+// and returns a slice of CandleSticks with every time normalized to UTC.
+func ReadCandlesFromCSVInLocation(filePath string, loc *time.Location) (CandleSticks, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
 	file, err := os.Open(filePath)
 	if err != nil {
 		return nil, fmt.Errorf("error opening file: %w", err)
@@ -52,7 +62,7 @@ func ReadCandlesFromCSV(filePath string) (CandleSticks, error) {
 		}
 
 		// CSV format: YYYY-MM-DD HH:MM:SS, open, high, low, close, volume
-		t, err := time.Parse("2006-01-02 15:04:05", record[0])
+		t, err := time.ParseInLocation("2006-01-02 15:04:05", record[0], loc)
 		if err != nil {
 			log.Printf("Error parsing timestamp, skipping record: %v", err)
 			continue
@@ -85,7 +95,7 @@ func ReadCandlesFromCSV(filePath string) (CandleSticks, error) {
 		}
 
 		candles = append(candles, Candle{
-			Time:  t,
+			Time:  t.UTC(),
 			Open:  open,
 			High:  high,
 			Low:   low,
@@ -69,3 +69,37 @@ func TestReadCandlesFromCSV(t *testing.T) {
 		}
 	}
 }
+
+func TestReadCandlesFromCSVInLocation(t *testing.T) {
+	content := `Time,Open,High,Low,Close,Volume
+2023-01-01 09:00:00,100,105,95,102,1000`
+	tmpfile, err := os.CreateTemp("", "test_data.csv")
+	if err != nil {
+		t.Fatalf("Failed to create temp file: %v", err)
+	}
+	defer os.Remove(tmpfile.Name())
+
+	if _, err := tmpfile.Write([]byte(content)); err != nil {
+		t.Fatalf("Failed to write to temp file: %v", err)
+	}
+	if err := tmpfile.Close(); err != nil {
+		t.Fatalf("Failed to close temp file: %v", err)
+	}
+
+	seoul, err := time.LoadLocation("Asia/Seoul")
+	if err != nil {
+		t.Fatalf("Failed to load timezone: %v", err)
+	}
+	candles, err := market.ReadCandlesFromCSVInLocation(tmpfile.Name(), seoul)
+	if err != nil {
+		t.Fatalf("ReadCandlesFromCSVInLocation failed: %v", err)
+	}
+
+	expected := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	if !candles[0].Time.Equal(expected) {
+		t.Errorf("Expected Time to be %v, but got %v", expected, candles[0].Time)
+	}
+	if candles[0].Time.Location() != time.UTC {
+		t.Errorf("Expected Time to be normalized to UTC, but got %v", candles[0].Time.Location())
+	}
+}
@@ -0,0 +1,108 @@
+package market
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ParseTimeframe converts a timeframe string such as "5m", "1h", "4h", "1d" or "1w"
+// into a time.Duration.
+func ParseTimeframe(tf string) (time.Duration, error) {
+	tf = strings.TrimSpace(tf)
+	if len(tf) < 2 {
+		return 0, fmt.Errorf("invalid timeframe: %q", tf)
+	}
+
+	n, err := strconv.Atoi(tf[:len(tf)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid timeframe: %q", tf)
+	}
+
+	var unit time.Duration
+	switch tf[len(tf)-1] {
+	case 's':
+		unit = time.Second
+	case 'm':
+		unit = time.Minute
+	case 'h':
+		unit = time.Hour
+	case 'd':
+		unit = 24 * time.Hour
+	case 'w':
+		unit = 7 * 24 * time.Hour
+	default:
+		return 0, fmt.Errorf("invalid timeframe unit in %q (expected s, m, h, d or w)", tf)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// InferInterval returns the smallest positive spacing between consecutive candles,
+// which is the bar interval of a regularly sampled series with occasional gaps.
+func InferInterval(candles CandleSticks) time.Duration {
+	var interval time.Duration
+	for i := 1; i < len(candles); i++ {
+		d := candles[i].Time.Sub(candles[i-1].Time)
+		if d > 0 && (interval == 0 || d < interval) {
+			interval = d
+		}
+	}
+	return interval
+}
+
+// Resample aggregates candles into bars of the given timeframe.
+// Bars are aligned to the calendar: intraday timeframes start on multiples of the
+// timeframe from midnight UTC, daily bars start at midnight UTC and weekly bars on Monday.
+// Each bar opens at the first open, closes at the last close, spans the highest high
+// and lowest low of its members and sums their volume. Empty buckets produce no bar.
+// The candle time of a resampled bar is its bucket start, not the first member's time.
+func Resample(candles CandleSticks, timeframe time.Duration) CandleSticks {
+	if timeframe <= 0 || len(candles) == 0 {
+		return nil
+	}
+
+	var bars CandleSticks
+	for _, c := range candles {
+		start := c.Time.Truncate(timeframe)
+		if n := len(bars); n > 0 && bars[n-1].Time.Equal(start) {
+			bar := &bars[n-1]
+			if c.High > bar.High {
+				bar.High = c.High
+			}
+			if c.Low < bar.Low {
+				bar.Low = c.Low
+			}
+			bar.Close = c.Close
+			bar.Vol += c.Vol
+			continue
+		}
+		bars = append(bars, Candle{
+			Time:  start,
+			Open:  c.Open,
+			High:  c.High,
+			Low:   c.Low,
+			Close: c.Close,
+			Vol:   c.Vol,
+		})
+	}
+	return bars
+}
+
+// CompletedBarIndex maps every base candle to the index of the last higher-timeframe
+// bar that had fully closed by the end of that base candle, or -1 if none had.
+// Candle times are bar open times, so a base candle at t covers [t, t+baseInterval)
+// and a higher bar at T is complete once T+timeframe <= t+baseInterval.
+// Using only completed bars keeps higher-timeframe values free of look-ahead.
+func CompletedBarIndex(base CandleSticks, baseInterval time.Duration, higher CandleSticks, timeframe time.Duration) []int {
+	index := make([]int, len(base))
+	j := -1
+	for i, c := range base {
+		end := c.Time.Add(baseInterval)
+		for j+1 < len(higher) && !higher[j+1].Time.Add(timeframe).After(end) {
+			j++
+		}
+		index[i] = j
+	}
+	return index
+}
@@ -0,0 +1,91 @@
+package market_test
+
+import (
+	"go-backtesting/market"
+	"testing"
+	"time"
+)
+
+func TestParseTimeframe(t *testing.T) {
+	cases := map[string]time.Duration{
+		"5m": 5 * time.Minute,
+		"1h": time.Hour,
+		"4h": 4 * time.Hour,
+		"1d": 24 * time.Hour,
+		"1w": 7 * 24 * time.Hour,
+	}
+	for in, expected := range cases {
+		got, err := market.ParseTimeframe(in)
+		if err != nil {
+			t.Errorf("ParseTimeframe(%q) failed: %v", in, err)
+			continue
+		}
+		if got != expected {
+			t.Errorf("ParseTimeframe(%q): expected %v, but got %v", in, expected, got)
+		}
+	}
+
+	for _, in := range []string{"", "h", "0m", "5x", "-1h"} {
+		if _, err := market.ParseTimeframe(in); err == nil {
+			t.Errorf("Expected ParseTimeframe(%q) to fail", in)
+		}
+	}
+}
+
+func TestResample(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 50, 0, 0, time.UTC)
+	var candles market.CandleSticks
+	for i := 0; i < 6; i++ {
+		candles = append(candles, market.Candle{
+			Time:  start.Add(time.Duration(i) * 5 * time.Minute),
+			Open:  float64(100 + i),
+			High:  float64(110 + i),
+			Low:   float64(90 - i),
+			Close: float64(101 + i),
+			Vol:   10,
+		})
+	}
+
+	bars := market.Resample(candles, time.Hour)
+	if len(bars) != 2 {
+		t.Fatalf("Expected 2 hourly bars, but got %d", len(bars))
+	}
+
+	first, second := bars[0], bars[1]
+	if !first.Time.Equal(time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected first bar aligned to 00:00, but got %v", first.Time)
+	}
+	if first.Open != 100 || first.High != 111 || first.Low != 89 || first.Close != 102 || first.Vol != 20 {
+		t.Errorf("Unexpected first bar OHLCV: %+v", first)
+	}
+	if !second.Time.Equal(time.Date(2023, 1, 1, 1, 0, 0, 0, time.UTC)) {
+		t.Errorf("Expected second bar aligned to 01:00, but got %v", second.Time)
+	}
+	if second.Open != 102 || second.High != 115 || second.Low != 85 || second.Close != 106 || second.Vol != 40 {
+		t.Errorf("Unexpected second bar OHLCV: %+v", second)
+	}
+}
+
+func TestCompletedBarIndex(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var candles market.CandleSticks
+	for i := 0; i < 30; i++ {
+		candles = append(candles, market.Candle{Time: start.Add(time.Duration(i) * 5 * time.Minute)})
+	}
+	bars := market.Resample(candles, time.Hour)
+	index := market.CompletedBarIndex(candles, 5*time.Minute, bars, time.Hour)
+
+	// The 00:55 candle closes at 01:00, completing the first hourly bar.
+	for i, j := range index {
+		expected := -1
+		if i >= 11 {
+			expected = 0
+		}
+		if i >= 23 {
+			expected = 1
+		}
+		if j != expected {
+			t.Errorf("Candle %d (%s): expected higher bar %d, but got %d", i, candles[i].Time.Format("15:04"), expected, j)
+		}
+	}
+}
@@ -0,0 +1,257 @@
+package market
+
+import (
+	"math"
+	"math/rand/v2"
+	"time"
+)
+
+// Synthetic generates deterministic candle series from seeded stochastic processes,
+// so strategies and conditions can be tested against known market regimes.
+// The same Seed always produces the same series.
+type Synthetic struct {
+	Start      time.Time
+	Interval   time.Duration
+	StartPrice float64
+	Seed       uint64
+	// BaseVolume is the typical volume per bar (default 100); volume rises with the bar's move.
+	BaseVolume float64
+}
+
+// SyntheticRegime labels the process state that generated a bar.
+type SyntheticRegime string
+
+const (
+	RegimeTrendUp   SyntheticRegime = "trend_up"
+	RegimeTrendDown SyntheticRegime = "trend_down"
+	RegimeRange     SyntheticRegime = "range"
+)
+
+// RegimeSwitchingParams configures a two-state Markov chain alternating between a trending
+// random walk and a mean-reverting range.
+type RegimeSwitchingParams struct {
+	TrendDrift      float64 // per-bar log drift while trending; its sign is drawn on each switch
+	TrendVolatility float64 // per-bar log volatility while trending
+	RangeVolatility float64 // per-bar log volatility while ranging
+	RangeReversion  float64 // fraction of the distance to the range mean recovered per bar
+	SwitchProb      float64 // probability of switching state on each bar
+}
+
+// JumpDiffusionParams configures a Merton jump-diffusion.
+type JumpDiffusionParams struct {
+	Drift      float64 // per-bar log drift
+	Volatility float64 // per-bar diffusion volatility
+	Intensity  float64 // expected jumps per bar
+	JumpMean   float64 // mean log jump size
+	JumpStd    float64 // standard deviation of the log jump size
+}
+
+// SqueezeBreakout describes a volatility squeeze followed by a directional breakout.
+type SqueezeBreakout struct {
+	At           int     // first bar of the squeeze
+	SqueezeBars  int     // length of the squeeze
+	Compression  float64 // factor applied to bar moves during the squeeze, e.g. 0.1
+	BreakoutBars int     // length of the breakout
+	Move         float64 // total log move over the breakout; negative for a downside breakout
+}
+
+func (s Synthetic) rng() *rand.Rand {
+	return rand.New(rand.NewPCG(s.Seed, s.Seed^0x9e3779b97f4a7c15))
+}
+
+// GBM generates n bars of geometric Brownian motion with per-bar log drift and volatility.
+func (s Synthetic) GBM(n int, drift, volatility float64) CandleSticks {
+	r := s.rng()
+	returns := make([]float64, n)
+	vols := make([]float64, n)
+	for i := range returns {
+		returns[i] = drift - volatility*volatility/2 + volatility*r.NormFloat64()
+		vols[i] = volatility
+	}
+	return s.build(r, returns, vols)
+}
+
+// GARCH generates n bars whose volatility follows a GARCH(1,1) process, producing volatility
+// clustering: sigma²[t] = omega + alpha*r²[t-1] + beta*sigma²[t-1]. alpha+beta must be below 1.
+func (s Synthetic) GARCH(n int, omega, alpha, beta float64) CandleSticks {
+	r := s.rng()
+	returns := make([]float64, n)
+	vols := make([]float64, n)
+	variance := omega / (1 - alpha - beta) // start at the unconditional variance
+	prev := 0.0
+	for i := range returns {
+		variance = omega + alpha*prev*prev + beta*variance
+		vols[i] = math.Sqrt(variance)
+		returns[i] = vols[i] * r.NormFloat64()
+		prev = returns[i]
+	}
+	return s.build(r, returns, vols)
+}
+
+// RegimeSwitching generates n bars alternating between trending and ranging states and
+// returns the state of every bar alongside the candles.
+func (s Synthetic) RegimeSwitching(n int, p RegimeSwitchingParams) (CandleSticks, []SyntheticRegime) {
+	r := s.rng()
+	returns := make([]float64, n)
+	vols := make([]float64, n)
+	regimes := make([]SyntheticRegime, n)
+
+	regime := RegimeRange
+	level, mean := 0.0, 0.0 // log price relative to the start, and the current range mean
+	for i := range returns {
+		if i > 0 && r.Float64() < p.SwitchProb {
+			if regime == RegimeRange {
+				regime = RegimeTrendUp
+				if r.IntN(2) == 0 {
+					regime = RegimeTrendDown
+				}
+			} else {
+				regime = RegimeRange
+				mean = level
+			}
+		}
+
+		switch regime {
+		case RegimeTrendUp:
+			returns[i] = p.TrendDrift + p.TrendVolatility*r.NormFloat64()
+			vols[i] = p.TrendVolatility
+		case RegimeTrendDown:
+			returns[i] = -p.TrendDrift + p.TrendVolatility*r.NormFloat64()
+			vols[i] = p.TrendVolatility
+		default:
+			returns[i] = p.RangeReversion*(mean-level) + p.RangeVolatility*r.NormFloat64()
+			vols[i] = p.RangeVolatility
+		}
+		level += returns[i]
+		regimes[i] = regime
+	}
+	return s.build(r, returns, vols), regimes
+}
+
+// JumpDiffusion generates n bars of a diffusion with Poisson-distributed log-normal jumps.
+func (s Synthetic) JumpDiffusion(n int, p JumpDiffusionParams) CandleSticks {
+	r := s.rng()
+	returns := make([]float64, n)
+	vols := make([]float64, n)
+	for i := range returns {
+		ret := p.Drift - p.Volatility*p.Volatility/2 + p.Volatility*r.NormFloat64()
+		for jumps := poisson(r, p.Intensity); jumps > 0; jumps-- {
+			ret += p.JumpMean + p.JumpStd*r.NormFloat64()
+		}
+		returns[i] = ret
+		vols[i] = p.Volatility
+	}
+	return s.build(r, returns, vols)
+}
+
+// InjectSqueezeBreakout returns a copy of candles with a squeeze and breakout written over
+// the bars starting at sb.At. Bar moves and wicks are compressed during the squeeze, the
+// breakout adds sb.Move spread evenly over its bars, and later bars keep their own moves
+// continuing from the new price level.
+func InjectSqueezeBreakout(candles CandleSticks, sb SqueezeBreakout) CandleSticks {
+	out := make(CandleSticks, len(candles))
+	copy(out, candles)
+	if sb.At <= 0 || sb.At >= len(candles) {
+		return out
+	}
+
+	squeezeEnd := min(sb.At+sb.SqueezeBars, len(candles))
+	breakoutEnd := min(squeezeEnd+sb.BreakoutBars, len(candles))
+	for i := sb.At; i < len(candles); i++ {
+		orig := candles[i]
+		prevClose := out[i-1].Close
+		ref := candles[i-1].Close
+
+		// Express the original bar relative to its previous close, then rebuild it on the new path.
+		open := math.Log(orig.Open / ref)
+		high := math.Log(orig.High / ref)
+		low := math.Log(orig.Low / ref)
+		close := math.Log(orig.Close / ref)
+		vol := orig.Vol
+
+		switch {
+		case i < squeezeEnd:
+			open, high, low, close = open*sb.Compression, high*sb.Compression, low*sb.Compression, close*sb.Compression
+			vol *= 0.5
+		case i < breakoutEnd:
+			step := sb.Move / float64(breakoutEnd-squeezeEnd)
+			close += step
+			high = math.Max(high+step, math.Max(open, close))
+			low = math.Min(low, math.Min(open, close))
+			vol *= 3
+		}
+
+		out[i] = Candle{
+			Time:  orig.Time,
+			Open:  prevClose * math.Exp(open),
+			High:  prevClose * math.Exp(high),
+			Low:   prevClose * math.Exp(low),
+			Close: prevClose * math.Exp(close),
+			Vol:   vol,
+		}
+	}
+	return out
+}
+
+// build turns per-bar log returns into candles. Each bar walks four intrabar steps from the
+// previous close to its own close (a Brownian bridge) to derive a plausible high and low.
+func (s Synthetic) build(r *rand.Rand, returns, vols []float64) CandleSticks {
+	const steps = 4
+	startPrice := s.StartPrice
+	if startPrice <= 0 {
+		startPrice = 100
+	}
+	interval := s.Interval
+	if interval <= 0 {
+		interval = 5 * time.Minute
+	}
+	baseVolume := s.BaseVolume
+	if baseVolume <= 0 {
+		baseVolume = 100
+	}
+
+	candles := make(CandleSticks, len(returns))
+	price := startPrice
+	for i, ret := range returns {
+		open := price
+		high, low := open, open
+		level := 0.0
+		for k := 1; k < steps; k++ {
+			remaining := float64(steps - k + 1)
+			level += (ret-level)/remaining + vols[i]*math.Sqrt(1/float64(steps))*r.NormFloat64()
+			p := open * math.Exp(level)
+			high = math.Max(high, p)
+			low = math.Min(low, p)
+		}
+		price = open * math.Exp(ret)
+		high = math.Max(high, price)
+		low = math.Min(low, price)
+
+		surprise := 0.0
+		if vols[i] > 0 {
+			surprise = math.Abs(ret) / vols[i]
+		}
+		candles[i] = Candle{
+			Time:  s.Start.Add(time.Duration(i) * interval),
+			Open:  open,
+			High:  high,
+			Low:   low,
+			Close: price,
+			Vol:   baseVolume * (1 + surprise) * math.Exp(0.25*r.NormFloat64()),
+		}
+	}
+	return candles
+}
+
+// poisson draws from a Poisson distribution using Knuth's method, adequate for small means.
+func poisson(r *rand.Rand, mean float64) int {
+	if mean <= 0 {
+		return 0
+	}
+	limit := math.Exp(-mean)
+	k := 0
+	for p := r.Float64(); p > limit; p *= r.Float64() {
+		k++
+	}
+	return k
+}
@@ -0,0 +1,120 @@
+package market_test
+
+import (
+	"go-backtesting/market"
+	"math"
+	"testing"
+	"time"
+)
+
+func newSynthetic(seed uint64) market.Synthetic {
+	return market.Synthetic{
+		Start:      time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Interval:   5 * time.Minute,
+		StartPrice: 100,
+		Seed:       seed,
+	}
+}
+
+func TestSyntheticIsDeterministic(t *testing.T) {
+	a := newSynthetic(42).GBM(500, 0, 0.01)
+	b := newSynthetic(42).GBM(500, 0, 0.01)
+	c := newSynthetic(43).GBM(500, 0, 0.01)
+
+	for i := range a {
+		if a[i] != b[i] {
+			t.Fatalf("Bar %d differs between runs with the same seed", i)
+		}
+	}
+	if a[len(a)-1].Close == c[len(c)-1].Close {
+		t.Error("Expected different seeds to produce different series")
+	}
+}
+
+func TestSyntheticCandlesAreConsistent(t *testing.T) {
+	s := newSynthetic(7)
+	series := map[string]market.CandleSticks{
+		"gbm":   s.GBM(300, 0.0005, 0.01),
+		"garch": s.GARCH(300, 1e-6, 0.1, 0.85),
+		"jump":  s.JumpDiffusion(300, market.JumpDiffusionParams{Volatility: 0.01, Intensity: 0.05, JumpStd: 0.05}),
+	}
+	for name, candles := range series {
+		if len(candles) != 300 {
+			t.Fatalf("%s: expected 300 candles, but got %d", name, len(candles))
+		}
+		for i, c := range candles {
+			if c.High < math.Max(c.Open, c.Close) || c.Low > math.Min(c.Open, c.Close) || c.Low <= 0 || c.Vol <= 0 {
+				t.Fatalf("%s: inconsistent candle %d: %+v", name, i, c)
+			}
+			if i > 0 && c.Open != candles[i-1].Close {
+				t.Fatalf("%s: candle %d does not open at the previous close", name, i)
+			}
+			if !c.Time.Equal(s.Start.Add(time.Duration(i) * s.Interval)) {
+				t.Fatalf("%s: candle %d has unexpected time %v", name, i, c.Time)
+			}
+		}
+	}
+}
+
+func TestRegimeSwitchingLabelsTrends(t *testing.T) {
+	candles, regimes := newSynthetic(1).RegimeSwitching(2000, market.RegimeSwitchingParams{
+		TrendDrift:      0.003,
+		TrendVolatility: 0.002,
+		RangeVolatility: 0.002,
+		RangeReversion:  0.1,
+		SwitchProb:      0.01,
+	})
+	if len(regimes) != len(candles) {
+		t.Fatalf("Expected a regime per candle, but got %d for %d candles", len(regimes), len(candles))
+	}
+
+	var up, down, ranging float64
+	var nUp, nDown, nRange int
+	for i := 1; i < len(candles); i++ {
+		ret := math.Log(candles[i].Close / candles[i-1].Close)
+		switch regimes[i] {
+		case market.RegimeTrendUp:
+			up += ret
+			nUp++
+		case market.RegimeTrendDown:
+			down += ret
+			nDown++
+		default:
+			ranging += ret
+			nRange++
+		}
+	}
+	if nUp == 0 || nDown == 0 || nRange == 0 {
+		t.Fatalf("Expected every regime to occur, got up=%d down=%d range=%d", nUp, nDown, nRange)
+	}
+	if up/float64(nUp) <= 0.002 || down/float64(nDown) >= -0.002 {
+		t.Errorf("Expected trending regimes to drift, got mean up %f and down %f", up/float64(nUp), down/float64(nDown))
+	}
+}
+
+func TestInjectSqueezeBreakout(t *testing.T) {
+	base := newSynthetic(3).GBM(300, 0, 0.01)
+	sb := market.SqueezeBreakout{At: 100, SqueezeBars: 50, Compression: 0.1, BreakoutBars: 10, Move: 0.2}
+	candles := market.InjectSqueezeBreakout(base, sb)
+
+	rangeOf := func(c market.Candle) float64 { return (c.High - c.Low) / c.Open }
+	var before, during float64
+	for i := 50; i < 100; i++ {
+		before += rangeOf(candles[i])
+		during += rangeOf(candles[i+50])
+	}
+	if during > before*0.2 {
+		t.Errorf("Expected the squeeze to compress bar ranges, got %f before and %f during", before, during)
+	}
+
+	move := math.Log(candles[159].Close / candles[149].Close)
+	baseMove := math.Log(base[159].Close / base[149].Close)
+	if !(math.Abs(move-baseMove-0.2) < 1e-9) {
+		t.Errorf("Expected the breakout to add a 0.2 log move, got %f over the original %f", move, baseMove)
+	}
+	for i := 0; i < 100; i++ {
+		if candles[i] != base[i] {
+			t.Fatalf("Expected bars before the squeeze to be unchanged, bar %d differs", i)
+		}
+	}
+}
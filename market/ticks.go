@@ -0,0 +1,95 @@
+package market
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Tick represents a single trade print.
+type Tick struct {
+	Time  time.Time
+	Price float64
+	Qty   float64
+}
+
+// Ticks is a slice of Ticks in time order.
+type Ticks []Tick
+
+// ReadTicksFromCSV reads a CSV file of trade prints and returns them with times normalized to UTC.
+// CSV format: time, price, quantity[, ...]. The time column is either epoch milliseconds or
+// "YYYY-MM-DD HH:MM:SS[.fff]" wall-clock time in loc. Additional columns are ignored.
+func ReadTicksFromCSV(filePath string, loc *time.Location) (Ticks, error) {
+	if loc == nil {
+		loc = time.UTC
+	}
+
+	file, err := os.Open(filePath)
+	if err != nil {
+		return nil, fmt.Errorf("error opening file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.TrimLeadingSpace = true
+	reader.FieldsPerRecord = -1
+	_, err = reader.Read() // Skip header
+	if err != nil {
+		if err == io.EOF {
+			return nil, fmt.Errorf("error: file is empty or contains only a header")
+		}
+		return nil, fmt.Errorf("error reading header: %w", err)
+	}
+
+	var ticks Ticks
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading record: %w", err)
+		}
+		if len(record) < 3 {
+			log.Printf("Too few columns, skipping record: %v", record)
+			continue
+		}
+
+		t, err := parseTickTime(record[0], loc)
+		if err != nil {
+			log.Printf("Error parsing timestamp, skipping record: %v", err)
+			continue
+		}
+		price, err := strconv.ParseFloat(record[1], 64)
+		if err != nil {
+			log.Printf("Error parsing price, skipping record: %v", err)
+			continue
+		}
+		qty, err := strconv.ParseFloat(record[2], 64)
+		if err != nil {
+			log.Printf("Error parsing quantity, skipping record: %v", err)
+			continue
+		}
+
+		ticks = append(ticks, Tick{Time: t, Price: price, Qty: qty})
+	}
+	return ticks, nil
+}
+
+func parseTickTime(value string, loc *time.Location) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if ms, err := strconv.ParseInt(value, 10, 64); err == nil {
+		return time.UnixMilli(ms).UTC(), nil
+	}
+	// Fractional seconds are accepted after the seconds field even though the layout omits them.
+	t, err := time.ParseInLocation("2006-01-02 15:04:05", value, loc)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return t.UTC(), nil
+}
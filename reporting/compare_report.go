@@ -0,0 +1,210 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-backtesting/strategy"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+)
+
+// consoleDiffLimit caps the trades PrintComparison lists per kind of difference; compare.html
+// lists them all.
+const consoleDiffLimit = 20
+
+// ComparedRun is a backtest result to compare, named after where it was loaded from.
+type ComparedRun struct {
+	Name   string
+	Result strategy.BacktestResult
+}
+
+// tradeDiffRow is one added, removed or changed trade. Before is nil for added trades and After
+// for removed ones.
+type tradeDiffRow struct {
+	Kind      string
+	Direction string
+	EntryTime string
+	Before    *tradeOutcome
+	After     *tradeOutcome
+}
+
+type tradeOutcome struct {
+	ExitTime  string
+	ExitPrice float64
+	Reason    string
+	Pnl       float64
+}
+
+func outcomeOf(t strategy.Trade) *tradeOutcome {
+	return &tradeOutcome{ExitTime: displayTime(t.ExitTime).Format("2006-01-02 15:04"), ExitPrice: t.ExitPrice, Reason: t.ExitReason, Pnl: t.Pnl}
+}
+
+func (o *tradeOutcome) String() string {
+	if o == nil {
+		return "-"
+	}
+	return fmt.Sprintf("%s @ %.4f (%s) PnL %.4f", o.ExitTime, o.ExitPrice, o.Reason, o.Pnl)
+}
+
+func diffRow(kind string, t strategy.Trade) tradeDiffRow {
+	return tradeDiffRow{Kind: kind, Direction: t.Direction, EntryTime: displayTime(t.EntryTime).Format("2006-01-02 15:04")}
+}
+
+// runDiff is how a run's trades differ from the baseline's.
+type runDiff struct {
+	Added     int
+	Removed   int
+	Changed   int
+	Unchanged int
+	Rows      []tradeDiffRow
+}
+
+func diffRuns(baseline, run ComparedRun) runDiff {
+	diff := strategy.DiffTrades(baseline.Result.Trades, run.Result.Trades)
+	d := runDiff{
+		Added: len(diff.Added), Removed: len(diff.Removed), Changed: len(diff.Changed), Unchanged: diff.Unchanged,
+	}
+	for _, t := range diff.Added {
+		row := diffRow("added", t)
+		row.After = outcomeOf(t)
+		d.Rows = append(d.Rows, row)
+	}
+	for _, t := range diff.Removed {
+		row := diffRow("removed", t)
+		row.Before = outcomeOf(t)
+		d.Rows = append(d.Rows, row)
+	}
+	for _, c := range diff.Changed {
+		row := diffRow("changed", c.After)
+		row.Before, row.After = outcomeOf(c.Before), outcomeOf(c.After)
+		d.Rows = append(d.Rows, row)
+	}
+	return d
+}
+
+// comparisonTable returns the metric names and, per metric, the formatted value of each run.
+func comparisonTable(runs []ComparedRun) (names []string, values [][]string) {
+	for k, run := range runs {
+		for m, row := range metricRows(strategy.ComputeMetrics(run.Result), run.Result.Trades) {
+			if k == 0 {
+				names = append(names, row.Name)
+				values = append(values, make([]string, len(runs)))
+			}
+			values[m][k] = row.Value
+		}
+	}
+	return names, values
+}
+
+// PrintComparison prints the summary metrics of the runs side by side and how the trades of
+// every run differ from those of the first.
+func PrintComparison(runs []ComparedRun) {
+	if len(runs) == 0 {
+		return
+	}
+
+	fmt.Println("\n--- Run Comparison ---")
+	for k, run := range runs {
+		fmt.Printf("[%d] %s\n", k+1, run.Name)
+	}
+	names, values := comparisonTable(runs)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	header := []string{"Metric"}
+	for k := range runs {
+		header = append(header, fmt.Sprintf("[%d]", k+1))
+	}
+	fmt.Fprintln(w, strings.Join(header, "\t")+"\t")
+	for m, name := range names {
+		fmt.Fprintln(w, name+"\t"+strings.Join(values[m], "\t")+"\t")
+	}
+	w.Flush()
+
+	for k, run := range runs[1:] {
+		d := diffRuns(runs[0], run)
+		fmt.Printf("\n--- Trades: [%d] vs [1] ---\n", k+2)
+		fmt.Printf("Added: %d, Removed: %d, Changed: %d, Unchanged: %d\n", d.Added, d.Removed, d.Changed, d.Unchanged)
+		for _, kind := range []string{"added", "removed", "changed"} {
+			shown := 0
+			for _, row := range d.Rows {
+				if row.Kind != kind {
+					continue
+				}
+				if shown == consoleDiffLimit {
+					fmt.Printf("  ... more %s trades in compare.html\n", kind)
+					break
+				}
+				shown++
+				fmt.Printf("  %-7s %-5s %s  %s -> %s\n", kind, row.Direction, row.EntryTime, row.Before, row.After)
+			}
+		}
+	}
+}
+
+// comparisonPage is the data of compare.html.template.
+type comparisonPage struct {
+	Timezone string
+	Runs     []string
+	Metrics  []comparisonMetric
+	Diffs    []runDiff
+	Equity   template.JS
+}
+
+type comparisonMetric struct {
+	Name   string
+	Values []string
+}
+
+// equitySeries is a run's equity curve as [time, equity] points in chart milliseconds.
+type equitySeries struct {
+	Name   string       `json:"name"`
+	Points [][2]float64 `json:"points"`
+}
+
+// GenerateComparisonHTML writes compare.html to OutputDir: the runs' summary metrics side by
+// side, their equity curves overlaid and how the trades of every run differ from those of the
+// first. Like the tearsheet, the page needs no network access.
+func GenerateComparisonHTML(runs []ComparedRun) error {
+	tmpl, err := template.New("compare.html.template").Funcs(reportFuncs).ParseFS(templates, "templates/compare.html.template")
+	if err != nil {
+		return fmt.Errorf("error parsing comparison template: %w", err)
+	}
+
+	page := comparisonPage{Timezone: DisplayLocation.String()}
+	curves := make([]equitySeries, len(runs))
+	for k, run := range runs {
+		page.Runs = append(page.Runs, run.Name)
+		curves[k] = equitySeries{Name: run.Name, Points: [][2]float64{}}
+		for _, p := range equityPoints(run.Result.Trades) {
+			curves[k].Points = append(curves[k].Points, [2]float64{p[0], p[1]})
+		}
+		if k > 0 {
+			page.Diffs = append(page.Diffs, diffRuns(runs[0], run))
+		}
+	}
+	names, values := comparisonTable(runs)
+	for m, name := range names {
+		page.Metrics = append(page.Metrics, comparisonMetric{Name: name, Values: values[m]})
+	}
+	encoded, err := json.Marshal(curves)
+	if err != nil {
+		return fmt.Errorf("error encoding equity curves: %w", err)
+	}
+	page.Equity = template.JS(encoded)
+
+	file, err := os.Create(filepath.Join(OutputDir, "compare.html"))
+	if err != nil {
+		return fmt.Errorf("error creating compare.html: %w", err)
+	}
+	err = tmpl.Execute(file, page)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing compare.html: %w", err)
+	}
+	fmt.Println("Generated compare.html")
+	return nil
+}
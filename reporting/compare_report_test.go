@@ -0,0 +1,82 @@
+package reporting_test
+
+import (
+	"go-backtesting/reporting"
+	"go-backtesting/strategy"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadResultFileRoundTrip(t *testing.T) {
+	dir := exportTo(t)
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	result := strategy.BacktestResult{
+		Trades: []strategy.Trade{
+			{EntryTime: start, ExitTime: start.Add(time.Hour), Direction: "long", EntryPrice: 100, ExitPrice: 103, Size: 1, Pnl: 3, PnlPercentage: 3, ExitReason: strategy.ExitTakeProfit, Regime: strategy.RegimeTrendingUp},
+		},
+		TotalPnl: 3, WinCount: 1, TotalTrades: 1, WinRate: 100,
+	}
+	if err := reporting.ExportBacktestResult(result); err != nil {
+		t.Fatal(err)
+	}
+
+	file, err := reporting.LoadResultFile(filepath.Join(dir, "result.json"))
+	if err != nil {
+		t.Fatalf("LoadResultFile failed: %v", err)
+	}
+	loaded, err := file.BacktestResult()
+	if err != nil {
+		t.Fatalf("BacktestResult failed: %v", err)
+	}
+	got, want := loaded.Trades[0], result.Trades[0]
+	if !got.EntryTime.Equal(want.EntryTime) || !got.ExitTime.Equal(want.ExitTime) || got.ExitReason != want.ExitReason ||
+		got.Regime != want.Regime || got.Pnl != want.Pnl || loaded.TotalPnl != result.TotalPnl {
+		t.Errorf("Expected the loaded trade to match %+v, but got %+v", want, got)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "old.json"), []byte(`{"schema_version": 99}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := reporting.LoadResultFile(filepath.Join(dir, "old.json")); err == nil {
+		t.Errorf("Expected an error for an unknown schema version")
+	}
+}
+
+func TestGenerateComparisonHTML(t *testing.T) {
+	dir := exportTo(t)
+	start := time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC)
+	trade := func(hour int, exitPrice float64) strategy.Trade {
+		entry := start.Add(time.Duration(hour) * time.Hour)
+		return strategy.Trade{EntryTime: entry, ExitTime: entry.Add(time.Hour), Direction: "long", EntryPrice: 100, ExitPrice: exitPrice,
+			Size: 1, Pnl: exitPrice - 100, PnlPercentage: exitPrice - 100, ExitReason: "signal_stop"}
+	}
+	runs := []reporting.ComparedRun{
+		{Name: "before/result.json", Result: strategy.BacktestResult{Trades: []strategy.Trade{trade(0, 101), trade(2, 99)}, TotalTrades: 2}},
+		{Name: "after/result.json", Result: strategy.BacktestResult{Trades: []strategy.Trade{trade(0, 101), trade(2, 102), trade(4, 101)}, TotalTrades: 3}},
+	}
+	reporting.PrintComparison(runs)
+	if err := reporting.GenerateComparisonHTML(runs); err != nil {
+		t.Fatalf("GenerateComparisonHTML failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "compare.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"<td>[2]</td><td style=\"text-align:left\">after/result.json</td>",
+		"<tr><td>Total trades</td><td>2</td><td>3</td></tr>",
+		"Added 1, removed 0, changed 1, unchanged 1.",
+		"<td>added</td><td>long</td><td>2024-03-01 04:00</td><td>-</td><td>2024-03-01 05:00 @ 101.0000 (signal_stop) PnL 1.0000</td>",
+		"<td>changed</td><td>long</td><td>2024-03-01 02:00</td><td>2024-03-01 03:00 @ 99.0000",
+		`{"name":"after/result.json","points":[[`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the comparison to contain %q", want)
+		}
+	}
+}
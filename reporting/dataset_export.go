@@ -0,0 +1,118 @@
+package reporting
+
+import (
+	"encoding/csv"
+	"fmt"
+	"go-backtesting/strategy"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+// ExportDataset writes the dataset to path, as Parquet when the path ends in ".parquet" and as
+// CSV otherwise. Missing values are written as empty CSV fields and as Parquet nulls.
+func ExportDataset(path string, d *strategy.Dataset) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("error creating dataset: %w", err)
+	}
+	if strings.EqualFold(filepath.Ext(path), ".parquet") {
+		err = writeDatasetParquet(file, d)
+	} else {
+		err = writeDatasetCSV(file, d)
+	}
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing dataset %s: %w", path, err)
+	}
+	return nil
+}
+
+func writeDatasetCSV(file *os.File, d *strategy.Dataset) error {
+	w := csv.NewWriter(file)
+	header := []string{"time"}
+	for _, c := range d.Strings {
+		header = append(header, c.Name)
+	}
+	for _, c := range d.Floats {
+		header = append(header, c.Name)
+	}
+	if err := w.Write(header); err != nil {
+		return err
+	}
+
+	record := make([]string, len(header))
+	for r := range d.Len() {
+		record[0] = displayTime(d.Time[r]).Format(time.RFC3339)
+		k := 1
+		for _, c := range d.Strings {
+			record[k] = c.Values[r]
+			k++
+		}
+		for _, c := range d.Floats {
+			record[k] = ""
+			if v := c.Values[r]; !math.IsNaN(v) {
+				record[k] = strconv.FormatFloat(v, 'g', -1, 64)
+			}
+			k++
+		}
+		if err := w.Write(record); err != nil {
+			return err
+		}
+	}
+	w.Flush()
+	return w.Error()
+}
+
+func writeDatasetParquet(file *os.File, d *strategy.Dataset) error {
+	group := parquet.Group{"time": parquet.Timestamp(parquet.Millisecond)}
+	for _, c := range d.Strings {
+		group[c.Name] = parquet.String()
+	}
+	for _, c := range d.Floats {
+		group[c.Name] = parquet.Optional(parquet.Leaf(parquet.DoubleType))
+	}
+	schema := parquet.NewSchema("dataset", group)
+	column := func(name string) int {
+		leaf, _ := schema.Lookup(name)
+		return leaf.ColumnIndex
+	}
+
+	// Rows hold their values in column order, which parquet sorts by name.
+	timeColumn := column("time")
+	stringColumns := make([]int, len(d.Strings))
+	for k, c := range d.Strings {
+		stringColumns[k] = column(c.Name)
+	}
+	floatColumns := make([]int, len(d.Floats))
+	for k, c := range d.Floats {
+		floatColumns[k] = column(c.Name)
+	}
+
+	w := parquet.NewWriter(file, schema)
+	row := make(parquet.Row, len(group))
+	for r := range d.Len() {
+		row[timeColumn] = parquet.Int64Value(d.Time[r].UnixMilli()).Level(0, 0, timeColumn)
+		for k, c := range d.Strings {
+			row[stringColumns[k]] = parquet.ByteArrayValue([]byte(c.Values[r])).Level(0, 0, stringColumns[k])
+		}
+		for k, c := range d.Floats {
+			v := parquet.NullValue().Level(0, 0, floatColumns[k])
+			if !math.IsNaN(c.Values[r]) {
+				v = parquet.DoubleValue(c.Values[r]).Level(0, 1, floatColumns[k])
+			}
+			row[floatColumns[k]] = v
+		}
+		if _, err := w.WriteRows([]parquet.Row{row}); err != nil {
+			return err
+		}
+	}
+	return w.Close()
+}
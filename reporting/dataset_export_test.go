@@ -0,0 +1,72 @@
+package reporting_test
+
+import (
+	"go-backtesting/reporting"
+	"go-backtesting/strategy"
+	"math"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/parquet-go/parquet-go"
+)
+
+func testDataset() *strategy.Dataset {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	return &strategy.Dataset{
+		Time:    []time.Time{start, start.Add(5 * time.Minute)},
+		Strings: []strategy.StringColumn{{Name: "regime", Values: []string{"ranging", "trending_up"}}},
+		Floats: []strategy.FloatColumn{
+			{Name: "close", Values: []float64{100, 101.5}},
+			{Name: "adx", Values: []float64{math.NaN(), 22}},
+		},
+	}
+}
+
+func TestExportDatasetCSV(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.csv")
+	if err := reporting.ExportDataset(path, testDataset()); err != nil {
+		t.Fatalf("ExportDataset failed: %v", err)
+	}
+	records := readCSV(t, path)
+
+	want := [][]string{
+		{"time", "regime", "close", "adx"},
+		{"2024-01-01T00:00:00Z", "ranging", "100", ""},
+		{"2024-01-01T00:05:00Z", "trending_up", "101.5", "22"},
+	}
+	for r := range want {
+		for k := range want[r] {
+			if records[r][k] != want[r][k] {
+				t.Errorf("Expected row %d column %d to be %q, but got %q", r, k, want[r][k], records[r][k])
+			}
+		}
+	}
+}
+
+func TestExportDatasetParquet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "dataset.parquet")
+	if err := reporting.ExportDataset(path, testDataset()); err != nil {
+		t.Fatalf("ExportDataset failed: %v", err)
+	}
+
+	type row struct {
+		Time   time.Time `parquet:"time,timestamp(millisecond)"`
+		Regime string    `parquet:"regime"`
+		Close  float64   `parquet:"close"`
+		ADX    *float64  `parquet:"adx,optional"`
+	}
+	rows, err := parquet.ReadFile[row](path)
+	if err != nil {
+		t.Fatalf("ReadFile failed: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("Expected 2 rows, but got %d", len(rows))
+	}
+	if !rows[1].Time.Equal(time.Date(2024, 1, 1, 0, 5, 0, 0, time.UTC)) || rows[1].Regime != "trending_up" || rows[1].Close != 101.5 {
+		t.Errorf("Expected the second row to round-trip, but got %+v", rows[1])
+	}
+	if rows[0].ADX != nil || rows[1].ADX == nil || *rows[1].ADX != 22 {
+		t.Errorf("Expected a missing ADX to be null and the present one 22, but got %v and %v", rows[0].ADX, rows[1].ADX)
+	}
+}
@@ -0,0 +1,48 @@
+package reporting
+
+import (
+	"fmt"
+	"go-backtesting/strategy"
+	"os"
+	"text/tabwriter"
+)
+
+// PrintRegimeSummary prints, for every market regime, the share of candles classified in it
+// and the trades entered in it with their win rate and PnL.
+func PrintRegimeSummary(result strategy.BacktestResult, regimes []strategy.Regime) {
+	if len(regimes) == 0 {
+		return
+	}
+
+	bars := make(map[strategy.Regime]int)
+	for _, r := range regimes {
+		bars[r]++
+	}
+	trades := make(map[strategy.Regime]int)
+	wins := make(map[strategy.Regime]int)
+	pnl := make(map[strategy.Regime]float64)
+	for _, t := range result.Trades {
+		trades[t.Regime]++
+		pnl[t.Regime] += t.Pnl
+		if t.Pnl > 0 {
+			wins[t.Regime]++
+		}
+	}
+
+	fmt.Println("\n--- Market Regimes ---")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Regime\tBars(%)\tTrades\tWin Rate\tPnL\t")
+	fmt.Fprintln(w, "------\t-------\t------\t--------\t---\t")
+
+	for _, r := range strategy.Regimes {
+		if bars[r] == 0 && trades[r] == 0 {
+			continue
+		}
+		winRate := 0.0
+		if trades[r] > 0 {
+			winRate = float64(wins[r]) / float64(trades[r]) * 100
+		}
+		fmt.Fprintf(w, "%s\t%.1f%%\t%d\t%.2f%%\t%.2f\t\n", r, float64(bars[r])/float64(len(regimes))*100, trades[r], winRate, pnl[r])
+	}
+	w.Flush()
+}
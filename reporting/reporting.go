@@ -1,16 +1,44 @@
 package reporting
 
 import (
+	"embed"
+	"encoding/json"
 	"fmt"
 	"go-backtesting/market"
 	"go-backtesting/strategy"
 	"math"
 	"os"
+	"path/filepath"
 	"strings"
 	"text/template"
 	"time"
 )
 
+// templates holds the HTML templates and scripts of the chart and the tearsheet, so reports
+// can be generated from any working directory.
+//
+//go:embed templates
+var templates embed.FS
+
+// DisplayLocation is the timezone used to format times in reports and the chart.
+// Times are stored in UTC internally; set this from the configured display timezone.
+var DisplayLocation = time.UTC
+
+// OutputDir is the directory the chart and result files are written to.
+var OutputDir = "."
+
+// displayTime converts t to the display timezone.
+func displayTime(t time.Time) time.Time {
+	return t.In(DisplayLocation)
+}
+
+// chartMillis returns t as epoch milliseconds shifted by the display timezone offset,
+// so the chart, which renders timestamps as UTC wall-clock, shows display-local times.
+func chartMillis(t time.Time) int64 {
+	_, offset := displayTime(t).Zone()
+	return t.UnixMilli() + int64(offset)*1000
+}
+
 // PrintTradeAnalysis prints a detailed analysis of the trades.
 func PrintTradeAnalysis(result strategy.BacktestResult, strategyData *strategy.StrategyDataContext) {
 	fmt.Printf("\n--- Trade Entry Analysis ---\n")
@@ -39,13 +67,15 @@ func PrintTradeAnalysis(result strategy.BacktestResult, strategyData *strategy.S
 			}
 		}
 
+		bbwzScores := strategyData.Series(strategy.SeriesBBWZ)
 		bbwStr := "NaN"
-		if entryIndex != -1 && entryIndex < len(strategyData.BbwzScores) && !math.IsNaN(strategyData.BbwzScores[entryIndex]) {
-			bbwStr = fmt.Sprintf("%.4f", strategyData.BbwzScores[entryIndex])
+		if entryIndex != -1 && entryIndex < len(bbwzScores) && !math.IsNaN(bbwzScores[entryIndex]) {
+			bbwStr = fmt.Sprintf("%.4f", bbwzScores[entryIndex])
 		}
+		dx := strategyData.Series(strategy.SeriesDX)
 		dxStr := "NaN"
-		if entryIndex != -1 && entryIndex < len(strategyData.DX) && !math.IsNaN(strategyData.DX[entryIndex]) {
-			dxStr = fmt.Sprintf("%.2f", strategyData.DX[entryIndex])
+		if entryIndex != -1 && entryIndex < len(dx) && !math.IsNaN(dx[entryIndex]) {
+			dxStr = fmt.Sprintf("%.2f", dx[entryIndex])
 		}
 
 		volStr := "NaN"
@@ -58,7 +88,7 @@ func PrintTradeAnalysis(result strategy.BacktestResult, strategyData *strategy.S
 		fmt.Printf("%-5d %-5s %-20s %-10s %-10s %-10s %-10s %-10s %-10s %-10s %-10s %-10.2f %-10s\n",
 			i,
 			trade.Direction,
-			trade.EntryTime.Format("01-02 15:04"),
+			displayTime(trade.EntryTime).Format("01-02 15:04"),
 			zStr,
 			vwzStr,
 			bbwStr,
@@ -74,73 +104,223 @@ func PrintTradeAnalysis(result strategy.BacktestResult, strategyData *strategy.S
 	fmt.Println("----------------------------------------------------------------------------------------------------------------------------------")
 }
 
-// ChartData contains the data for the HTML chart.
+// ChartData contains the data for the HTML chart, each field a JavaScript value.
 type ChartData struct {
 	CandleData   string
-	ZData        string
-	VWZData      string
 	EntrySignals string
 	VolumeData   string
+	Overlays     string
+	Panels       string
+	Shading      string
+	Trades       string
+	Timezone     string
 }
 
-// GenerateHTMLChart generates an HTML chart of the backtest results.
-func GenerateHTMLChart(candles market.CandleSticks, zScores []float64, vwzScores []float64, entrySignals []strategy.EntrySignal) {
-	var candleData []string
-	var zData []string
-	var vwzData []string
+// ChartSeries is a named indicator series aligned to the candles, drawn as a line or, when
+// Histogram is set, as bars.
+type ChartSeries struct {
+	Name      string
+	Values    []float64
+	Histogram bool
+}
+
+// ChartPanel is a panel under the price chart with its own value axis. Levels are drawn as
+// horizontal reference lines.
+type ChartPanel struct {
+	Title  string
+	Series []ChartSeries
+	Levels []float64
+}
+
+// ChartSpec lists what the chart draws besides the candles, the volume and the entry markers.
+type ChartSpec struct {
+	// Overlays are drawn on the price axis.
+	Overlays []ChartSeries
+	Panels   []ChartPanel
+	// Shading labels the background of each candle on the price chart, e.g. with its BBW
+	// state; candles with an empty label are not shaded.
+	Shading []string
+	// Trades are drawn from entry to exit and colored by PnL, together with their take-profit
+	// and stop-loss levels at TakeProfitRate and StopLossRate; a zero rate is not drawn.
+	Trades         []strategy.Trade
+	TakeProfitRate float64
+	StopLossRate   float64
+}
 
+// chartPoint is a point of a line or bar series; a nil Y leaves a gap.
+type chartPoint struct {
+	X int64    `json:"x"`
+	Y *float64 `json:"y"`
+}
+
+type chartSeriesData struct {
+	Label     string       `json:"label"`
+	Histogram bool         `json:"histogram"`
+	Data      []chartPoint `json:"data"`
+}
+
+type chartPanelData struct {
+	Title  string            `json:"title"`
+	Series []chartSeriesData `json:"series"`
+	Levels []float64         `json:"levels"`
+}
+
+// shadingSpan is a run of candles with the same shading label, from the first candle's time up
+// to the time of the candle after the run.
+type shadingSpan struct {
+	From  int64  `json:"from"`
+	To    int64  `json:"to"`
+	Label string `json:"label"`
+}
+
+func chartSeriesPoints(candles market.CandleSticks, o ChartSeries) chartSeriesData {
+	series := chartSeriesData{Label: o.Name, Histogram: o.Histogram, Data: make([]chartPoint, len(candles))}
 	for i, c := range candles {
-		ms := c.Time.UnixNano() / int64(time.Millisecond)
-		candlePoint := fmt.Sprintf("{x: %d, o: %.4f, h: %.4f, l: %.4f, c: %.4f}", ms, c.Open, c.High, c.Low, c.Close)
-		candleData = append(candleData, candlePoint)
-
-		if math.IsNaN(zScores[i]) {
-			zData = append(zData, fmt.Sprintf("{x: %d, y: null}", ms))
-		} else {
-			zData = append(zData, fmt.Sprintf("{x: %d, y: %.4f}", ms, zScores[i]))
+		series.Data[i].X = chartMillis(c.Time)
+		if i < len(o.Values) && !math.IsNaN(o.Values[i]) {
+			series.Data[i].Y = &o.Values[i]
+		}
+	}
+	return series
+}
+
+func shadingSpans(candles market.CandleSticks, labels []string) []shadingSpan {
+	spans := []shadingSpan{}
+	for i := 0; i < len(candles) && i < len(labels); {
+		j := i
+		for j < len(candles) && j < len(labels) && labels[j] == labels[i] {
+			j++
+		}
+		if labels[i] != "" {
+			to := chartMillis(candles[j-1].Time)
+			if j < len(candles) {
+				to = chartMillis(candles[j].Time)
+			}
+			spans = append(spans, shadingSpan{From: chartMillis(candles[i].Time), To: to, Label: labels[i]})
 		}
-		if math.IsNaN(vwzScores[i]) {
-			vwzData = append(vwzData, fmt.Sprintf("{x: %d, y: null}", ms))
-		} else {
-			vwzData = append(vwzData, fmt.Sprintf("{x: %d, y: %.4f}", ms, vwzScores[i]))
+		i = j
+	}
+	return spans
+}
+
+// chartTrade is a trade as drawn on the chart and the tearsheet. Times are chart milliseconds;
+// a zero TakeProfit or StopLoss means the level is disabled. The fields without JSON keys are
+// for the tearsheet's trade table.
+type chartTrade struct {
+	Entry      int64   `json:"entry"`
+	Exit       int64   `json:"exit"`
+	Direction  string  `json:"direction"`
+	EntryPrice float64 `json:"entryPrice"`
+	ExitPrice  float64 `json:"exitPrice"`
+	TakeProfit float64 `json:"tp"`
+	StopLoss   float64 `json:"sl"`
+	Size       float64 `json:"size"`
+	Pnl        float64 `json:"pnl"`
+	Return     float64 `json:"return"`
+	Reason     string  `json:"reason"`
+	Regime     string  `json:"regime"`
+
+	Number    int           `json:"-"`
+	EntryTime string        `json:"-"`
+	ExitTime  string        `json:"-"`
+	Holding   time.Duration `json:"-"`
+}
+
+// chartTrades converts trades for drawing, with their levels at the given take-profit and
+// stop-loss rates.
+func chartTrades(trades []strategy.Trade, takeProfitRate, stopLossRate float64) []chartTrade {
+	records := make([]chartTrade, len(trades))
+	for k, t := range trades {
+		takeProfit, stopLoss := t.Levels(takeProfitRate, stopLossRate)
+		if takeProfitRate <= 0 {
+			takeProfit = 0
+		}
+		if stopLossRate <= 0 {
+			stopLoss = 0
+		}
+		records[k] = chartTrade{
+			Entry:      chartMillis(t.EntryTime),
+			Exit:       chartMillis(t.ExitTime),
+			Direction:  t.Direction,
+			EntryPrice: t.EntryPrice,
+			ExitPrice:  t.ExitPrice,
+			TakeProfit: takeProfit,
+			StopLoss:   stopLoss,
+			Size:       t.Size,
+			Pnl:        t.Pnl,
+			Return:     t.PnlPercentage,
+			Reason:     t.ExitReason,
+			Regime:     string(t.Regime),
+			Number:     k + 1,
+			EntryTime:  displayTime(t.EntryTime).Format("2006-01-02 15:04"),
+			ExitTime:   displayTime(t.ExitTime).Format("2006-01-02 15:04"),
+			Holding:    t.ExitTime.Sub(t.EntryTime),
 		}
 	}
+	return records
+}
 
-	candleDataJS := "[" + strings.Join(candleData, ",") + "]"
-	zDataJS := "[" + strings.Join(zData, ",") + "]"
-	vwzDataJS := "[" + strings.Join(vwzData, ",") + "]"
+// GenerateHTMLChart generates an HTML chart of the backtest results: the candles with volume,
+// entry markers and spec's overlays, shading and trades, and spec's panels below them sharing
+// the time axis. Series values must be aligned to candles.
+func GenerateHTMLChart(candles market.CandleSticks, entrySignals []strategy.EntrySignal, spec ChartSpec) {
+	var candleData []string
+	var volumeData []string
+	for _, c := range candles {
+		ms := chartMillis(c.Time)
+		candleData = append(candleData, fmt.Sprintf("{x: %d, o: %.4f, h: %.4f, l: %.4f, c: %.4f}", ms, c.Open, c.High, c.Low, c.Close))
+		volumeData = append(volumeData, fmt.Sprintf("{x: %d, y: %.4f}", ms, c.Vol))
+	}
 
 	var entrySignalData []string
 	for _, s := range entrySignals {
-		ms := s.Time.UnixNano() / int64(time.Millisecond)
+		ms := chartMillis(s.Time)
 		signalPoint := fmt.Sprintf("{x: %d, y: %.4f, direction: '%s'}", ms, s.Price, s.Direction)
 		entrySignalData = append(entrySignalData, signalPoint)
 	}
-	entrySignalsJS := "[" + strings.Join(entrySignalData, ",") + "]"
 
-	var volumeData []string
-	for _, c := range candles {
-		ms := c.Time.UnixNano() / int64(time.Millisecond)
-		volumePoint := fmt.Sprintf("{x: %d, y: %.4f}", ms, c.Vol)
-		volumeData = append(volumeData, volumePoint)
+	overlays := make([]chartSeriesData, len(spec.Overlays))
+	for k, o := range spec.Overlays {
+		overlays[k] = chartSeriesPoints(candles, o)
+	}
+	panels := make([]chartPanelData, len(spec.Panels))
+	for k, p := range spec.Panels {
+		panels[k] = chartPanelData{Title: p.Title, Series: make([]chartSeriesData, len(p.Series)), Levels: append([]float64{}, p.Levels...)}
+		for n, o := range p.Series {
+			panels[k].Series[n] = chartSeriesPoints(candles, o)
+		}
 	}
-	volumeDataJS := "[" + strings.Join(volumeData, ",") + "]"
 
-	tmpl, err := template.ParseFiles("chart.html.template")
+	data := ChartData{
+		CandleData:   "[" + strings.Join(candleData, ",") + "]",
+		EntrySignals: "[" + strings.Join(entrySignalData, ",") + "]",
+		VolumeData:   "[" + strings.Join(volumeData, ",") + "]",
+		Timezone:     DisplayLocation.String(),
+	}
+	for _, field := range []struct {
+		dst   *string
+		value any
+	}{
+		{&data.Overlays, overlays},
+		{&data.Panels, panels},
+		{&data.Shading, shadingSpans(candles, spec.Shading)},
+		{&data.Trades, chartTrades(spec.Trades, spec.TakeProfitRate, spec.StopLossRate)},
+	} {
+		encoded, err := json.Marshal(field.value)
+		if err != nil {
+			fmt.Println("Error encoding chart data:", err)
+			return
+		}
+		*field.dst = string(encoded)
+	}
+
+	tmpl, err := template.ParseFS(templates, "templates/chart.html.template")
 	if err != nil {
 		fmt.Println("Error parsing template:", err)
 		return
 	}
 
-	data := ChartData{
-		CandleData:   candleDataJS,
-		ZData:        zDataJS,
-		VWZData:      vwzDataJS,
-		EntrySignals: entrySignalsJS,
-		VolumeData:   volumeDataJS,
-	}
-
-	file, err := os.Create("chart.html")
+	file, err := os.Create(filepath.Join(OutputDir, "chart.html"))
 	if err != nil {
 		fmt.Println("Error creating chart.html:", err)
 		return
@@ -159,8 +339,8 @@ func GenerateHTMLChart(candles market.CandleSticks, zScores []float64, vwzScores
 func PrintDetailedTradeRecords(result strategy.BacktestResult) {
 	fmt.Printf("\n--- Detailed Trade Records ---\n")
 	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------------")
-	fmt.Printf("%-5s %-5s %-20s %-15s %-20s %-15s %-10s %-10s %-10s\n",
-		"Idx", "Type", "Entry Time", "Entry Price", "Exit Time", "Exit Price", "Pnl", "Pnl(%)", "Status")
+	fmt.Printf("%-5s %-5s %-20s %-15s %-20s %-15s %-10s %-10s %-10s %-15s\n",
+		"Idx", "Type", "Entry Time", "Entry Price", "Exit Time", "Exit Price", "Pnl", "Pnl(%)", "Status", "Exit Reason")
 	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------------")
 
 	for i, trade := range result.Trades {
@@ -169,16 +349,17 @@ func PrintDetailedTradeRecords(result strategy.BacktestResult) {
 			status = "Win"
 		}
 
-		fmt.Printf("%-5d %-5s %-20s %-15.2f %-20s %-15.2f %-10.2f %-9.2f%% %-10s\n",
+		fmt.Printf("%-5d %-5s %-20s %-15.2f %-20s %-15.2f %-10.2f %-9.2f%% %-10s %-15s\n",
 			i,
 			trade.Direction,
-			trade.EntryTime.Format("01-02 15:04:05"),
+			displayTime(trade.EntryTime).Format("01-02 15:04:05"),
 			trade.EntryPrice,
-			trade.ExitTime.Format("01-02 15:04:05"),
+			displayTime(trade.ExitTime).Format("01-02 15:04:05"),
 			trade.ExitPrice,
 			trade.Pnl,
 			trade.PnlPercentage,
 			status,
+			trade.ExitReason,
 		)
 	}
 	fmt.Println("-----------------------------------------------------------------------------------------------------------------------------------------")
@@ -1,8 +1,14 @@
 package reporting_test
 
 import (
+	"go-backtesting/market"
 	"go-backtesting/reporting"
 	"go-backtesting/strategy"
+	"math"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 	"time"
 )
@@ -35,3 +41,52 @@ func TestPrintTradeAnalysis(t *testing.T) {
 	// A more thorough test would capture the output and verify it.
 	reporting.PrintTradeAnalysis(result, strategyData)
 }
+
+func TestGenerateHTMLChart(t *testing.T) {
+	dir := exportTo(t)
+	t.Chdir(t.TempDir())
+
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	var candles market.CandleSticks
+	for k := range 4 {
+		candles = append(candles, market.Candle{Time: start.Add(time.Duration(k) * time.Hour), Open: 100, High: 101, Low: 99, Close: 100})
+	}
+	spec := reporting.ChartSpec{
+		Overlays: []reporting.ChartSeries{{Name: "ema_short", Values: []float64{math.NaN(), 100, 100.5, 101}}},
+		Panels: []reporting.ChartPanel{{
+			Title:  "MACD",
+			Series: []reporting.ChartSeries{{Name: "macd.histogram", Values: []float64{0.5, -0.5, 1, 2}, Histogram: true}},
+		}},
+		Shading: []string{"", "Squeeze", "Squeeze", ""},
+		Trades: []strategy.Trade{
+			{EntryTime: start, ExitTime: start.Add(2 * time.Hour), Direction: "short", EntryPrice: 100, ExitPrice: 101, Pnl: -1, PnlPercentage: -1, ExitReason: strategy.ExitStopLoss},
+		},
+		TakeProfitRate: 0.02,
+	}
+	reporting.GenerateHTMLChart(candles, nil, spec)
+
+	data, err := os.ReadFile(filepath.Join(dir, "chart.html"))
+	if err != nil {
+		t.Fatalf("Expected chart.html to be written: %v", err)
+	}
+	page := string(data)
+	hour := int64(time.Hour / time.Millisecond)
+	startMs := start.UnixMilli()
+	for _, want := range []string{
+		`{"label":"ema_short","histogram":false,"data":[{"x":` + itoa(startMs) + `,"y":null}`,
+		`{"title":"MACD","series":[{"label":"macd.histogram","histogram":true`,
+		`"levels":[]`,
+		`[{"from":` + itoa(startMs+hour) + `,"to":` + itoa(startMs+3*hour) + `,"label":"Squeeze"}]`,
+		// The stop-loss rate is zero, so only the take-profit level is drawn.
+		`{"entry":` + itoa(startMs) + `,"exit":` + itoa(startMs+2*hour) + `,"direction":"short","entryPrice":100,"exitPrice":101,"tp":98,"sl":0`,
+		`"reason":"stop_loss"`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the chart to contain %s", want)
+		}
+	}
+}
+
+func itoa(v int64) string {
+	return strconv.FormatInt(v, 10)
+}
@@ -0,0 +1,243 @@
+package reporting
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"go-backtesting/strategy"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// ResultSchemaVersion versions the layout of the JSON and CSV result files. It is bumped
+// whenever a field is renamed, removed or changes meaning; adding fields keeps the version.
+const ResultSchemaVersion = 1
+
+// The result files use UTC RFC 3339 times and snake_case keys, and every CSV row starts with
+// the schema version.
+
+// ResultFile is the content of result.json.
+type ResultFile struct {
+	SchemaVersion int            `json:"schema_version"`
+	Summary       ResultSummary  `json:"summary"`
+	Trades        []TradeRecord  `json:"trades"`
+	Equity        []EquityRecord `json:"equity"`
+}
+
+// ResultSummary mirrors the totals of a strategy.BacktestResult.
+type ResultSummary struct {
+	TotalTrades int     `json:"total_trades"`
+	WinCount    int     `json:"win_count"`
+	LossCount   int     `json:"loss_count"`
+	WinRate     float64 `json:"win_rate"`
+	TotalPnl    float64 `json:"total_pnl"`
+}
+
+// TradeRecord is one closed trade.
+type TradeRecord struct {
+	EntryTime     string  `json:"entry_time"`
+	ExitTime      string  `json:"exit_time"`
+	Direction     string  `json:"direction"`
+	EntryPrice    float64 `json:"entry_price"`
+	ExitPrice     float64 `json:"exit_price"`
+	Size          float64 `json:"size"`
+	Pnl           float64 `json:"pnl"`
+	PnlPercentage float64 `json:"pnl_percentage"`
+	ExitReason    string  `json:"exit_reason"`
+	Regime        string  `json:"regime"`
+}
+
+// EquityRecord is the equity after one closed trade.
+type EquityRecord struct {
+	Time     string  `json:"time"`
+	Equity   float64 `json:"equity"`
+	Drawdown float64 `json:"drawdown"`
+}
+
+// SignalFile is the content of signals.json.
+type SignalFile struct {
+	SchemaVersion int            `json:"schema_version"`
+	Signals       []SignalRecord `json:"signals"`
+}
+
+// SignalRecord is one entry signal.
+type SignalRecord struct {
+	Time      string  `json:"time"`
+	Price     float64 `json:"price"`
+	Direction string  `json:"direction"`
+}
+
+func resultTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// NewResultFile converts a backtest result to its file layout.
+func NewResultFile(result strategy.BacktestResult) ResultFile {
+	file := ResultFile{
+		SchemaVersion: ResultSchemaVersion,
+		Summary: ResultSummary{
+			TotalTrades: result.TotalTrades,
+			WinCount:    result.WinCount,
+			LossCount:   result.LossCount,
+			WinRate:     result.WinRate,
+			TotalPnl:    result.TotalPnl,
+		},
+		Trades: make([]TradeRecord, len(result.Trades)),
+	}
+	for k, t := range result.Trades {
+		file.Trades[k] = TradeRecord{
+			EntryTime:     resultTime(t.EntryTime),
+			ExitTime:      resultTime(t.ExitTime),
+			Direction:     t.Direction,
+			EntryPrice:    t.EntryPrice,
+			ExitPrice:     t.ExitPrice,
+			Size:          t.Size,
+			Pnl:           t.Pnl,
+			PnlPercentage: t.PnlPercentage,
+			ExitReason:    t.ExitReason,
+			Regime:        string(t.Regime),
+		}
+	}
+	curve := strategy.EquityCurve(result.Trades)
+	file.Equity = make([]EquityRecord, len(curve))
+	for k, p := range curve {
+		file.Equity[k] = EquityRecord{Time: resultTime(p.Time), Equity: p.Equity, Drawdown: p.Drawdown}
+	}
+	return file
+}
+
+// LoadResultFile reads a result.json written by ExportBacktestResult.
+func LoadResultFile(path string) (ResultFile, error) {
+	var file ResultFile
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return file, fmt.Errorf("error reading result file: %w", err)
+	}
+	if err := json.Unmarshal(data, &file); err != nil {
+		return file, fmt.Errorf("error decoding result file %s: %w", path, err)
+	}
+	if file.SchemaVersion != ResultSchemaVersion {
+		return file, fmt.Errorf("result file %s has schema version %d, expected %d", path, file.SchemaVersion, ResultSchemaVersion)
+	}
+	return file, nil
+}
+
+// BacktestResult converts the file back to the backtest result it was written from, without
+// the trades' entry indicators.
+func (f ResultFile) BacktestResult() (strategy.BacktestResult, error) {
+	result := strategy.BacktestResult{
+		Trades:      make([]strategy.Trade, len(f.Trades)),
+		TotalPnl:    f.Summary.TotalPnl,
+		WinCount:    f.Summary.WinCount,
+		LossCount:   f.Summary.LossCount,
+		TotalTrades: f.Summary.TotalTrades,
+		WinRate:     f.Summary.WinRate,
+	}
+	for k, t := range f.Trades {
+		entry, err := time.Parse(time.RFC3339, t.EntryTime)
+		if err != nil {
+			return result, fmt.Errorf("trade %d: %w", k+1, err)
+		}
+		exit, err := time.Parse(time.RFC3339, t.ExitTime)
+		if err != nil {
+			return result, fmt.Errorf("trade %d: %w", k+1, err)
+		}
+		result.Trades[k] = strategy.Trade{
+			EntryTime:     entry,
+			EntryPrice:    t.EntryPrice,
+			ExitTime:      exit,
+			ExitPrice:     t.ExitPrice,
+			Direction:     t.Direction,
+			Pnl:           t.Pnl,
+			PnlPercentage: t.PnlPercentage,
+			ExitReason:    t.ExitReason,
+			Regime:        strategy.Regime(t.Regime),
+			Size:          t.Size,
+		}
+	}
+	return result, nil
+}
+
+func signalRecords(signals []strategy.EntrySignal) []SignalRecord {
+	records := make([]SignalRecord, len(signals))
+	for k, s := range signals {
+		records[k] = SignalRecord{Time: resultTime(s.Time), Price: s.Price, Direction: s.Direction}
+	}
+	return records
+}
+
+// ExportBacktestResult writes result.json, trades.csv and equity.csv to OutputDir.
+func ExportBacktestResult(result strategy.BacktestResult) error {
+	file := NewResultFile(result)
+	if err := writeJSONFile("result.json", file); err != nil {
+		return err
+	}
+
+	trades := [][]string{{"schema_version", "entry_time", "exit_time", "direction", "entry_price", "exit_price", "size", "pnl", "pnl_percentage", "exit_reason", "regime"}}
+	for _, t := range file.Trades {
+		trades = append(trades, []string{
+			schemaField(), t.EntryTime, t.ExitTime, t.Direction, formatFloat(t.EntryPrice), formatFloat(t.ExitPrice),
+			formatFloat(t.Size), formatFloat(t.Pnl), formatFloat(t.PnlPercentage), t.ExitReason, t.Regime,
+		})
+	}
+	if err := writeCSVFile("trades.csv", trades); err != nil {
+		return err
+	}
+
+	equity := [][]string{{"schema_version", "time", "equity", "drawdown"}}
+	for _, p := range file.Equity {
+		equity = append(equity, []string{schemaField(), p.Time, formatFloat(p.Equity), formatFloat(p.Drawdown)})
+	}
+	return writeCSVFile("equity.csv", equity)
+}
+
+// ExportSignals writes signals.json and signals.csv to OutputDir.
+func ExportSignals(signals []strategy.EntrySignal) error {
+	records := signalRecords(signals)
+	if err := writeJSONFile("signals.json", SignalFile{SchemaVersion: ResultSchemaVersion, Signals: records}); err != nil {
+		return err
+	}
+
+	rows := [][]string{{"schema_version", "time", "price", "direction"}}
+	for _, s := range records {
+		rows = append(rows, []string{schemaField(), s.Time, formatFloat(s.Price), s.Direction})
+	}
+	return writeCSVFile("signals.csv", rows)
+}
+
+func schemaField() string {
+	return strconv.Itoa(ResultSchemaVersion)
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'f', -1, 64)
+}
+
+func writeJSONFile(name string, v any) error {
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return fmt.Errorf("error encoding %s: %w", name, err)
+	}
+	if err := os.WriteFile(filepath.Join(OutputDir, name), append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+	return nil
+}
+
+func writeCSVFile(name string, rows [][]string) error {
+	file, err := os.Create(filepath.Join(OutputDir, name))
+	if err != nil {
+		return fmt.Errorf("error creating %s: %w", name, err)
+	}
+	w := csv.NewWriter(file)
+	err = w.WriteAll(rows)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing %s: %w", name, err)
+	}
+	return nil
+}
@@ -0,0 +1,101 @@
+package reporting_test
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"go-backtesting/reporting"
+	"go-backtesting/strategy"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func exportTo(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	previous := reporting.OutputDir
+	reporting.OutputDir = dir
+	t.Cleanup(func() { reporting.OutputDir = previous })
+	return dir
+}
+
+func TestExportBacktestResult(t *testing.T) {
+	dir := exportTo(t)
+	start := time.Date(2024, 3, 1, 9, 0, 0, 0, time.FixedZone("KST", 9*3600))
+	result := strategy.BacktestResult{
+		Trades: []strategy.Trade{
+			{EntryTime: start, ExitTime: start.Add(time.Hour), Direction: "long", EntryPrice: 100, ExitPrice: 103, Size: 1, Pnl: 3, PnlPercentage: 3, ExitReason: strategy.ExitTakeProfit, Regime: strategy.RegimeTrendingUp},
+			{EntryTime: start.Add(2 * time.Hour), ExitTime: start.Add(3 * time.Hour), Direction: "short", EntryPrice: 103, ExitPrice: 105, Size: 1, Pnl: -2, PnlPercentage: -1.94, ExitReason: "signal_stop", Regime: strategy.RegimeRanging},
+		},
+		TotalPnl: 1, WinCount: 1, LossCount: 1, TotalTrades: 2, WinRate: 50,
+	}
+	if err := reporting.ExportBacktestResult(result); err != nil {
+		t.Fatalf("ExportBacktestResult failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "result.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file reporting.ResultFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		t.Fatalf("result.json is not valid JSON: %v", err)
+	}
+	if file.SchemaVersion != reporting.ResultSchemaVersion || file.Summary.TotalTrades != 2 || len(file.Trades) != 2 {
+		t.Errorf("Expected a versioned result with 2 trades, but got %+v", file)
+	}
+	if got := file.Trades[0].EntryTime; got != "2024-03-01T00:00:00Z" {
+		t.Errorf("Expected times in UTC, but got %s", got)
+	}
+	if last := file.Equity[1]; last.Equity != 1 || last.Drawdown != 2 {
+		t.Errorf("Expected equity 1 with drawdown 2 after the second trade, but got %+v", last)
+	}
+
+	for name, header := range map[string]string{
+		"trades.csv": "schema_version,entry_time,exit_time,direction,entry_price,exit_price,size,pnl,pnl_percentage,exit_reason,regime",
+		"equity.csv": "schema_version,time,equity,drawdown",
+	} {
+		records := readCSV(t, filepath.Join(dir, name))
+		if strings.Join(records[0], ",") != header || len(records) != 3 {
+			t.Errorf("Expected %s to have header %q and 2 rows, but got %v", name, header, records)
+		}
+	}
+	if row := readCSV(t, filepath.Join(dir, "trades.csv"))[2]; row[0] != "1" || row[9] != "signal_stop" || row[8] != "-1.94" {
+		t.Errorf("Unexpected trade row %v", row)
+	}
+}
+
+func TestExportSignals(t *testing.T) {
+	dir := exportTo(t)
+	signals := []strategy.EntrySignal{{Time: time.Date(2024, 3, 1, 0, 0, 0, 0, time.UTC), Price: 100.5, Direction: "short"}}
+	if err := reporting.ExportSignals(signals); err != nil {
+		t.Fatalf("ExportSignals failed: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "signals.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var file reporting.SignalFile
+	if err := json.Unmarshal(data, &file); err != nil || file.SchemaVersion != reporting.ResultSchemaVersion || len(file.Signals) != 1 {
+		t.Errorf("Expected a versioned signal file with one signal, but got %+v (%v)", file, err)
+	}
+	if row := readCSV(t, filepath.Join(dir, "signals.csv"))[1]; strings.Join(row, ",") != "1,2024-03-01T00:00:00Z,100.5,short" {
+		t.Errorf("Unexpected signal row %v", row)
+	}
+}
+
+func readCSV(t *testing.T, path string) [][]string {
+	t.Helper()
+	file, err := os.Open(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatalf("%s is not valid CSV: %v", path, err)
+	}
+	return records
+}
@@ -0,0 +1,34 @@
+package reporting
+
+import (
+	"fmt"
+	"go-backtesting/strategy"
+	"os"
+	"text/tabwriter"
+)
+
+// PrintScoreReport prints a trained score model's weights and how it does on the held-out trades.
+func PrintScoreReport(model *strategy.ScoreModel, report strategy.ScoreReport) {
+	fmt.Println("\n--- Signal Score Model ---")
+	fmt.Println("-----------------------------------------------------------------")
+	fmt.Printf("Training Trades: %d\n", report.TrainTrades)
+	fmt.Printf("Test Trades: %d\n", report.TestTrades)
+	if report.Skipped > 0 {
+		fmt.Printf("Skipped Trades (missing features): %d\n", report.Skipped)
+	}
+	fmt.Printf("Training Accuracy: %.2f%%\n", report.TrainAccuracy*100)
+	if report.TestTrades > 0 {
+		fmt.Printf("Test Accuracy: %.2f%% (always predicting a win: %.2f%%)\n", report.TestAccuracy*100, report.TestBaseRate*100)
+		fmt.Printf("Test Log Loss: %.4f\n", report.TestLogLoss)
+	}
+	fmt.Println("-----------------------------------------------------------------")
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Feature\tWeight\t")
+	fmt.Fprintln(w, "-------\t------\t")
+	for k, name := range model.Features {
+		fmt.Fprintf(w, "%s\t%.4f\t\n", name, model.Weights[k])
+	}
+	fmt.Fprintf(w, "%s\t%.4f\t\n", "(bias)", model.Bias)
+	w.Flush()
+}
@@ -21,7 +21,24 @@ func PrintAllSignals(signals []strategy.EntrySignal) {
 	fmt.Fprintln(w, "----\t-----\t---------\t")
 
 	for _, s := range signals {
-		fmt.Fprintf(w, "%s\t%.2f\t%s\t\n", s.Time.Format(time.RFC3339), s.Price, s.Direction)
+		fmt.Fprintf(w, "%s\t%.2f\t%s\t\n", displayTime(s.Time).Format(time.RFC3339), s.Price, s.Direction)
+	}
+	w.Flush()
+}
+
+// PrintRejectionSummary prints how often each check rejected an entry, most frequent first.
+func PrintRejectionSummary(rejections []strategy.Rejection) {
+	if len(rejections) == 0 {
+		return
+	}
+
+	fmt.Println("\n--- Entry Rejections ---")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', tabwriter.AlignRight)
+	fmt.Fprintln(w, "Check\tCount\t")
+	fmt.Fprintln(w, "-----\t-----\t")
+
+	for _, r := range rejections {
+		fmt.Fprintf(w, "%s\t%d\t\n", r.Check, r.Count)
 	}
 	w.Flush()
 }
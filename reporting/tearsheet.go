@@ -0,0 +1,212 @@
+package reporting
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-backtesting/market"
+	"go-backtesting/strategy"
+	"html/template"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// histogramBins is the number of bins of the tearsheet's return histogram.
+const histogramBins = 20
+
+// tearsheetPage is the data of tearsheet.html.template. Tables are rendered by the template;
+// Data holds the JSON the embedded script draws the charts from.
+type tearsheetPage struct {
+	Title    string
+	Timezone string
+	Metrics  []metricRow
+	Years    []monthlyReturnRow
+	Trades   []chartTrade
+	Data     template.JS
+	Script   template.JS
+}
+
+// metricRow is a formatted summary metric.
+type metricRow struct {
+	Name  string
+	Value string
+}
+
+// monthlyReturnRow sums the returns of the trades closed in each month of Year, in percent.
+type monthlyReturnRow struct {
+	Year   int
+	Months [12]*float64
+	Total  float64
+}
+
+type histogramBin struct {
+	From  float64 `json:"from"`
+	To    float64 `json:"to"`
+	Count int     `json:"count"`
+}
+
+// tearsheetData is drawn by tearsheet.js. Candles are [time, open, high, low, close] and
+// equity points [time, equity, drawdown].
+type tearsheetData struct {
+	Candles   [][5]float64   `json:"candles"`
+	Equity    [][3]float64   `json:"equity"`
+	Trades    []chartTrade   `json:"trades"`
+	Histogram []histogramBin `json:"histogram"`
+}
+
+// GenerateTearsheet writes tearsheet.html to OutputDir, a single-file report of the backtest:
+// summary metrics, equity and drawdown curves, monthly returns, a histogram of trade returns
+// and a sortable trade list, with every trade drawn on the price chart together with its
+// take-profit and stop-loss levels. takeProfit and stopLoss are the TPRate and SLRate the
+// trades ran with; a zero rate is not drawn. The page needs no network access.
+func GenerateTearsheet(candles market.CandleSticks, result strategy.BacktestResult, takeProfit, stopLoss float64) error {
+	tmpl, err := template.New("tearsheet.html.template").Funcs(reportFuncs).ParseFS(templates, "templates/tearsheet.html.template")
+	if err != nil {
+		return fmt.Errorf("error parsing tearsheet template: %w", err)
+	}
+	script, err := templates.ReadFile("templates/tearsheet.js")
+	if err != nil {
+		return fmt.Errorf("error reading tearsheet script: %w", err)
+	}
+
+	trades := chartTrades(result.Trades, takeProfit, stopLoss)
+	data := tearsheetData{
+		Candles:   make([][5]float64, len(candles)),
+		Equity:    equityPoints(result.Trades),
+		Trades:    trades,
+		Histogram: returnHistogram(result.Trades, histogramBins),
+	}
+	for k, c := range candles {
+		data.Candles[k] = [5]float64{float64(chartMillis(c.Time)), c.Open, c.High, c.Low, c.Close}
+	}
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return fmt.Errorf("error encoding tearsheet data: %w", err)
+	}
+
+	page := tearsheetPage{
+		Title:    "Backtest tearsheet",
+		Timezone: DisplayLocation.String(),
+		Metrics:  metricRows(strategy.ComputeMetrics(result), result.Trades),
+		Years:    monthlyReturns(result.Trades),
+		Trades:   trades,
+		Data:     template.JS(encoded),
+		Script:   template.JS(script),
+	}
+
+	file, err := os.Create(filepath.Join(OutputDir, "tearsheet.html"))
+	if err != nil {
+		return fmt.Errorf("error creating tearsheet.html: %w", err)
+	}
+	err = tmpl.Execute(file, page)
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("error writing tearsheet.html: %w", err)
+	}
+	fmt.Println("Generated tearsheet.html")
+	return nil
+}
+
+var reportFuncs = template.FuncMap{
+	"inc":      func(k int) int { return k + 1 },
+	"number":   formatFloat,
+	"percent":  func(v float64) string { return fmt.Sprintf("%.2f%%", v) },
+	"price":    func(v float64) string { return fmt.Sprintf("%.4f", v) },
+	"seconds":  func(d time.Duration) string { return formatFloat(d.Seconds()) },
+	"duration": func(d time.Duration) string { return d.Round(time.Minute).String() },
+	"tone": func(v float64) string {
+		if v < 0 {
+			return "negative"
+		}
+		return "positive"
+	},
+}
+
+// equityPoints returns the equity curve starting from zero at the first entry.
+func equityPoints(trades []strategy.Trade) [][3]float64 {
+	if len(trades) == 0 {
+		return nil
+	}
+	points := [][3]float64{{float64(chartMillis(trades[0].EntryTime)), 0, 0}}
+	for _, p := range strategy.EquityCurve(trades) {
+		points = append(points, [3]float64{float64(chartMillis(p.Time)), p.Equity, p.Drawdown})
+	}
+	return points
+}
+
+// monthlyReturns sums the trade returns by the month, in the display timezone, the trades
+// closed in.
+func monthlyReturns(trades []strategy.Trade) []monthlyReturnRow {
+	var rows []monthlyReturnRow
+	for _, t := range trades {
+		exit := displayTime(t.ExitTime)
+		k := slices.IndexFunc(rows, func(r monthlyReturnRow) bool { return r.Year == exit.Year() })
+		if k < 0 {
+			rows = append(rows, monthlyReturnRow{Year: exit.Year()})
+			k = len(rows) - 1
+		}
+		month := &rows[k].Months[exit.Month()-1]
+		if *month == nil {
+			*month = new(float64)
+		}
+		**month += t.PnlPercentage
+		rows[k].Total += t.PnlPercentage
+	}
+	slices.SortFunc(rows, func(a, b monthlyReturnRow) int { return a.Year - b.Year })
+	return rows
+}
+
+// returnHistogram counts the trade returns in bins equal-width bins spanning them.
+func returnHistogram(trades []strategy.Trade, bins int) []histogramBin {
+	if len(trades) == 0 {
+		return nil
+	}
+	low, high := math.Inf(1), math.Inf(-1)
+	for _, t := range trades {
+		low, high = math.Min(low, t.PnlPercentage), math.Max(high, t.PnlPercentage)
+	}
+	if low == high {
+		low, high = low-0.5, high+0.5
+	}
+	width := (high - low) / float64(bins)
+	histogram := make([]histogramBin, bins)
+	for k := range histogram {
+		histogram[k] = histogramBin{From: low + float64(k)*width, To: low + float64(k+1)*width}
+	}
+	for _, t := range trades {
+		k := min(int((t.PnlPercentage-low)/width), bins-1)
+		histogram[k].Count++
+	}
+	return histogram
+}
+
+func metricRows(m strategy.Metrics, trades []strategy.Trade) []metricRow {
+	profitFactor := "n/a"
+	if !math.IsInf(m.ProfitFactor, 0) {
+		profitFactor = fmt.Sprintf("%.2f", m.ProfitFactor)
+	}
+	period := "n/a"
+	if len(trades) > 0 {
+		period = fmt.Sprintf("%s to %s",
+			displayTime(trades[0].EntryTime).Format("2006-01-02"), displayTime(trades[len(trades)-1].ExitTime).Format("2006-01-02"))
+	}
+	return []metricRow{
+		{"Period", period},
+		{"Total trades", fmt.Sprintf("%d", m.TotalTrades)},
+		{"Win rate", fmt.Sprintf("%.2f%%", m.WinRate)},
+		{"Total PnL", fmt.Sprintf("%.4f", m.TotalPnl)},
+		{"Total return", fmt.Sprintf("%.2f%%", m.TotalReturn)},
+		{"Expectancy", fmt.Sprintf("%.3f%%", m.Expectancy)},
+		{"Average win", fmt.Sprintf("%.3f%%", m.AverageWin)},
+		{"Average loss", fmt.Sprintf("%.3f%%", m.AverageLoss)},
+		{"Profit factor", profitFactor},
+		{"Max drawdown", fmt.Sprintf("%.4f", m.MaxDrawdown)},
+		{"Sharpe (per trade)", fmt.Sprintf("%.3f", m.SharpeRatio)},
+		{"Longest losing streak", fmt.Sprintf("%d", m.LongestLosingStreak)},
+		{"Average holding", m.AverageHolding.Round(time.Minute).String()},
+	}
+}
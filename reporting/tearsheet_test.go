@@ -0,0 +1,57 @@
+package reporting_test
+
+import (
+	"go-backtesting/market"
+	"go-backtesting/reporting"
+	"go-backtesting/strategy"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestGenerateTearsheet(t *testing.T) {
+	dir := exportTo(t)
+	// Generating from another working directory must not depend on files next to the binary.
+	t.Chdir(t.TempDir())
+
+	start := time.Date(2024, 1, 31, 0, 0, 0, 0, time.UTC)
+	var candles market.CandleSticks
+	for k := range 48 {
+		price := 100 + float64(k%5)
+		candles = append(candles, market.Candle{Time: start.Add(time.Duration(k) * time.Hour), Open: price, High: price + 1, Low: price - 1, Close: price})
+	}
+	result := strategy.BacktestResult{
+		Trades: []strategy.Trade{
+			{EntryTime: start, ExitTime: start.Add(3 * time.Hour), Direction: "long", EntryPrice: 100, ExitPrice: 103, Size: 1, Pnl: 3, PnlPercentage: 3, ExitReason: strategy.ExitTakeProfit},
+			{EntryTime: start.Add(25 * time.Hour), ExitTime: start.Add(26 * time.Hour), Direction: "short", EntryPrice: 101, ExitPrice: 102, Size: 1, Pnl: -1, PnlPercentage: -0.99, ExitReason: "<stop>"},
+		},
+		TotalPnl: 2, WinCount: 1, LossCount: 1, TotalTrades: 2, WinRate: 50,
+	}
+	if err := reporting.GenerateTearsheet(candles, result, 0.03, 0.01); err != nil {
+		t.Fatalf("GenerateTearsheet failed: %v", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, "tearsheet.html"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	page := string(data)
+	for _, want := range []string{
+		"Profit factor", "3.00",
+		`id="equity"`, `id="drawdown"`, `id="histogram"`, `id="price"`, `class="sortable"`,
+		// January and February rows of the monthly table.
+		`<td>2024</td><td class="positive">3.00%</td><td class="negative">-0.99%</td>`,
+		`"tp":103`, `"sl":99`,
+		"&lt;stop&gt;",
+		"function drawPrice",
+	} {
+		if !strings.Contains(page, want) {
+			t.Errorf("Expected the tearsheet to contain %q", want)
+		}
+	}
+	if strings.Contains(page, "<script src=") {
+		t.Errorf("Expected the tearsheet to need no external scripts")
+	}
+}
@@ -0,0 +1,295 @@
+// Draws the tearsheet charts as plain SVG so the page works offline without chart libraries.
+// Times are milliseconds already shifted to the display timezone, so they are formatted as UTC.
+(function () {
+  "use strict";
+
+  const data = window.tearsheet;
+  const NS = "http://www.w3.org/2000/svg";
+  const WIDTH = 1000;
+  const PAD = { left: 70, right: 20, top: 10, bottom: 28 };
+  const MAX_POINTS = 1000;
+  const CANDLE_LIMIT = 200;
+
+  function node(name, attrs, parent) {
+    const e = document.createElementNS(NS, name);
+    for (const k in attrs) {
+      e.setAttribute(k, attrs[k]);
+    }
+    if (parent) {
+      parent.appendChild(e);
+    }
+    return e;
+  }
+
+  function tooltip(e, text) {
+    node("title", {}, e).textContent = text;
+    return e;
+  }
+
+  function formatTime(ms) {
+    return new Date(ms).toISOString().slice(0, 16).replace("T", " ");
+  }
+
+  function formatNumber(v) {
+    return Number(v.toPrecision(6)).toString();
+  }
+
+  function extent(values) {
+    let low = Infinity, high = -Infinity;
+    for (const v of values) {
+      if (v < low) low = v;
+      if (v > high) high = v;
+    }
+    return [low, high];
+  }
+
+  // frame clears svg and draws a grid and axis labels for the given ranges. It returns the
+  // functions mapping data coordinates to the svg.
+  function frame(svg, height, x0, x1, y0, y1, formatX) {
+    while (svg.firstChild) svg.removeChild(svg.firstChild);
+    svg.setAttribute("viewBox", "0 0 " + WIDTH + " " + height);
+    if (x0 === x1) { x0 -= 1; x1 += 1; }
+    if (y0 === y1) { y0 -= 1; y1 += 1; }
+    const x = v => PAD.left + (v - x0) / (x1 - x0) * (WIDTH - PAD.left - PAD.right);
+    const y = v => height - PAD.bottom - (v - y0) / (y1 - y0) * (height - PAD.top - PAD.bottom);
+    for (let k = 0; k <= 4; k++) {
+      const yv = y0 + (y1 - y0) * k / 4;
+      node("line", { x1: PAD.left, x2: WIDTH - PAD.right, y1: y(yv), y2: y(yv), class: "grid" }, svg);
+      node("text", { x: PAD.left - 6, y: y(yv) + 4, "text-anchor": "end" }, svg).textContent = formatNumber(yv);
+      const anchor = k === 0 ? "start" : k === 4 ? "end" : "middle";
+      node("text", { x: x(x0 + (x1 - x0) * k / 4), y: height - 8, "text-anchor": anchor }, svg).textContent = formatX(x0 + (x1 - x0) * k / 4);
+    }
+    return { x, y, x0, x1, height };
+  }
+
+  function empty(svg) {
+    svg.setAttribute("viewBox", "0 0 " + WIDTH + " 40");
+    node("text", { x: PAD.left, y: 24 }, svg).textContent = "No trades.";
+  }
+
+  // stepPoints traces a value that changes at each point and holds until the next one.
+  function stepPoints(f, points, value) {
+    const out = [];
+    points.forEach((p, k) => {
+      if (k > 0) out.push(f.x(p[0]) + "," + f.y(value(points[k - 1])));
+      out.push(f.x(p[0]) + "," + f.y(value(p)));
+    });
+    return out.join(" ");
+  }
+
+  function drawEquity() {
+    const svg = document.getElementById("equity");
+    const points = data.equity || [];
+    if (points.length === 0) return empty(svg);
+    const [low, high] = extent(points.map(p => p[1]));
+    const f = frame(svg, 260, points[0][0], points[points.length - 1][0], Math.min(0, low), Math.max(0, high), formatTime);
+    node("line", { x1: PAD.left, x2: WIDTH - PAD.right, y1: f.y(0), y2: f.y(0), class: "zero" }, svg);
+    node("polyline", { points: stepPoints(f, points, p => p[1]), class: "equity" }, svg);
+  }
+
+  function drawDrawdown() {
+    const svg = document.getElementById("drawdown");
+    const points = data.equity || [];
+    if (points.length === 0) return empty(svg);
+    const [, deepest] = extent(points.map(p => p[2]));
+    const f = frame(svg, 160, points[0][0], points[points.length - 1][0], -deepest, 0, formatTime);
+    const line = stepPoints(f, points, p => -p[2]);
+    const last = points[points.length - 1][0];
+    node("polygon", { points: f.x(points[0][0]) + "," + f.y(0) + " " + line + " " + f.x(last) + "," + f.y(0), class: "drawdown" }, svg);
+  }
+
+  function drawHistogram() {
+    const svg = document.getElementById("histogram");
+    const bins = data.histogram || [];
+    if (bins.length === 0) return empty(svg);
+    const [, most] = extent(bins.map(b => b.count));
+    const f = frame(svg, 220, bins[0].from, bins[bins.length - 1].to, 0, most, v => formatNumber(v) + "%");
+    for (const b of bins) {
+      const mid = (b.from + b.to) / 2;
+      const bar = node("rect", {
+        x: f.x(b.from) + 1,
+        y: f.y(b.count),
+        width: Math.max(f.x(b.to) - f.x(b.from) - 2, 1),
+        height: f.y(0) - f.y(b.count),
+        class: mid < 0 ? "loss" : "win",
+      }, svg);
+      tooltip(bar, formatNumber(b.from) + "% to " + formatNumber(b.to) + "%: " + b.count + " trades");
+    }
+    if (bins[0].from < 0 && bins[bins.length - 1].to > 0) {
+      node("line", { x1: f.x(0), x2: f.x(0), y1: PAD.top, y2: f.y(0), class: "zero" }, svg);
+    }
+  }
+
+  // --- Price chart with the trades ---
+
+  const candles = data.candles || [];
+  const trades = data.trades || [];
+  const price = document.getElementById("price");
+  const fullView = candles.length > 0 ? [candles[0][0], candles[candles.length - 1][0]] : [0, 1];
+  let view = fullView.slice();
+  let selected = -1;
+
+  function firstAtOrAfter(t) {
+    let lo = 0, hi = candles.length;
+    while (lo < hi) {
+      const mid = (lo + hi) >> 1;
+      if (candles[mid][0] < t) lo = mid + 1; else hi = mid;
+    }
+    return lo;
+  }
+
+  // visibleCandles merges the candles in view into at most MAX_POINTS buckets of
+  // [time, open, high, low, close].
+  function visibleCandles() {
+    const from = firstAtOrAfter(view[0]);
+    const to = firstAtOrAfter(view[1] + 1);
+    const size = Math.max(1, Math.ceil((to - from) / MAX_POINTS));
+    const out = [];
+    for (let i = from; i < to; i += size) {
+      const end = Math.min(i + size, to);
+      let high = -Infinity, low = Infinity;
+      for (let j = i; j < end; j++) {
+        high = Math.max(high, candles[j][2]);
+        low = Math.min(low, candles[j][3]);
+      }
+      out.push([candles[i][0], candles[i][1], high, low, candles[end - 1][4]]);
+    }
+    return out;
+  }
+
+  function tradeLabel(t, k) {
+    return "#" + (k + 1) + " " + t.direction + " " + formatTime(t.entry) + " → " + formatTime(t.exit) +
+      "\nentry " + formatNumber(t.entryPrice) + ", exit " + formatNumber(t.exitPrice) +
+      (t.tp ? "\ntake profit " + formatNumber(t.tp) : "") + (t.sl ? ", stop loss " + formatNumber(t.sl) : "") +
+      "\n" + t.reason + ", PnL " + formatNumber(t.pnl) + " (" + t.return.toFixed(2) + "%)";
+  }
+
+  function drawPrice() {
+    if (candles.length === 0) return empty(price);
+    const bars = visibleCandles();
+    const shown = trades.map((t, k) => [t, k]).filter(([t]) => t.exit >= view[0] && t.entry <= view[1]);
+    let [low, high] = extent(bars.map(b => b[3]).concat(bars.map(b => b[2])));
+    // Keep the levels of a focused trade in view; others may run off the chart.
+    if (selected >= 0) {
+      const t = trades[selected];
+      [low, high] = extent([low, high, t.tp || low, t.sl || low]);
+    }
+    const f = frame(price, 380, view[0], view[1], low, high, formatTime);
+    const clip = v => Math.min(Math.max(v, view[0]), view[1]);
+
+    if (bars.length <= CANDLE_LIMIT) {
+      const step = bars.length > 1 ? (f.x(bars[1][0]) - f.x(bars[0][0])) * 0.6 : 6;
+      for (const b of bars) {
+        const tone = b[4] >= b[1] ? "up" : "down";
+        node("line", { x1: f.x(b[0]), x2: f.x(b[0]), y1: f.y(b[2]), y2: f.y(b[3]), class: tone }, price);
+        node("rect", {
+          x: f.x(b[0]) - step / 2, width: step,
+          y: f.y(Math.max(b[1], b[4])), height: Math.max(Math.abs(f.y(b[1]) - f.y(b[4])), 1),
+          class: tone,
+        }, price);
+      }
+    } else {
+      const band = bars.map(b => f.x(b[0]) + "," + f.y(b[2])).concat(bars.slice().reverse().map(b => f.x(b[0]) + "," + f.y(b[3])));
+      node("polygon", { points: band.join(" "), class: "range" }, price);
+      node("polyline", { points: bars.map(b => f.x(b[0]) + "," + f.y(b[4])).join(" "), class: "close" }, price);
+    }
+
+    for (const [t, k] of shown) {
+      const x1 = f.x(clip(t.entry)), x2 = f.x(clip(t.exit));
+      const group = tooltip(node("g", { "data-trade": k }, price), tradeLabel(t, k));
+      const outcome = t.pnl > 0 ? "win" : "loss";
+      const extra = k === selected ? " selected" : "";
+      if (t.tp) node("line", { x1: x1, x2: x2, y1: f.y(t.tp), y2: f.y(t.tp), class: "tp" + extra }, group);
+      if (t.sl) node("line", { x1: x1, x2: x2, y1: f.y(t.sl), y2: f.y(t.sl), class: "sl" + extra }, group);
+      node("line", { x1: f.x(t.entry), y1: f.y(t.entryPrice), x2: f.x(t.exit), y2: f.y(t.exitPrice), class: "path " + outcome + extra }, group);
+      if (t.entry >= view[0]) {
+        const ex = f.x(t.entry), ey = f.y(t.entryPrice);
+        const d = t.direction === "long" ? [ey + 9, ey + 1] : [ey - 9, ey - 1];
+        node("polygon", { points: (ex - 5) + "," + d[0] + " " + (ex + 5) + "," + d[0] + " " + ex + "," + d[1], class: t.direction === "long" ? "up" : "down" }, group);
+      }
+      if (t.exit <= view[1]) {
+        node("circle", { cx: f.x(t.exit), cy: f.y(t.exitPrice), r: 4, class: outcome }, group);
+      }
+    }
+    price.querySelectorAll("g[data-trade]").forEach(g => g.addEventListener("click", () => focusTrade(Number(g.dataset.trade))));
+  }
+
+  function toData(event) {
+    const rect = price.getBoundingClientRect();
+    const sx = (event.clientX - rect.left) / rect.width * WIDTH;
+    return view[0] + (sx - PAD.left) / (WIDTH - PAD.left - PAD.right) * (view[1] - view[0]);
+  }
+
+  function setView(x0, x1) {
+    const span = Math.max(x1 - x0, 60000);
+    x0 = Math.max(fullView[0], Math.min(x0, fullView[1] - span));
+    view = [x0, Math.min(x0 + span, fullView[1])];
+    drawPrice();
+  }
+
+  function focusTrade(k) {
+    const t = trades[k];
+    const bar = candles.length > 1 ? candles[1][0] - candles[0][0] : 60000;
+    const pad = Math.max(t.exit - t.entry, 20 * bar);
+    selected = k;
+    document.querySelectorAll("#trades tbody tr").forEach(r => r.classList.toggle("selected", Number(r.dataset.trade) === k));
+    setView(t.entry - pad, t.exit + pad);
+  }
+
+  price.addEventListener("wheel", event => {
+    event.preventDefault();
+    const at = toData(event);
+    const scale = event.deltaY < 0 ? 0.8 : 1.25;
+    setView(at - (at - view[0]) * scale, at + (view[1] - at) * scale);
+  }, { passive: false });
+
+  let dragFrom = null;
+  price.addEventListener("mousedown", event => { dragFrom = [toData(event), view.slice()]; });
+  window.addEventListener("mouseup", () => { dragFrom = null; });
+  price.addEventListener("mousemove", event => {
+    if (!dragFrom) return;
+    const shift = dragFrom[0] - toData(event);
+    view = dragFrom[1].slice();
+    setView(view[0] + shift, view[1] + shift);
+  });
+  document.getElementById("reset").addEventListener("click", () => {
+    selected = -1;
+    document.querySelectorAll("#trades tbody tr.selected").forEach(r => r.classList.remove("selected"));
+    view = fullView.slice();
+    drawPrice();
+  });
+
+  // --- Sortable tables ---
+
+  document.querySelectorAll("table.sortable").forEach(table => {
+    const headers = table.querySelectorAll("th");
+    headers.forEach((th, column) => {
+      th.addEventListener("click", () => {
+        const ascending = th.dataset.order !== "asc";
+        headers.forEach(h => delete h.dataset.order);
+        th.dataset.order = ascending ? "asc" : "desc";
+        const body = table.tBodies[0];
+        const value = row => {
+          const cell = row.cells[column];
+          return cell.dataset.v !== undefined ? cell.dataset.v : cell.textContent;
+        };
+        const rows = Array.from(body.rows).sort((a, b) => {
+          const va = value(a), vb = value(b);
+          const na = parseFloat(va), nb = parseFloat(vb);
+          const order = isNaN(na) || isNaN(nb) ? va.localeCompare(vb) : na - nb;
+          return ascending ? order : -order;
+        });
+        rows.forEach(r => body.appendChild(r));
+      });
+    });
+  });
+  document.querySelectorAll("#trades tbody tr").forEach(r => r.addEventListener("click", () => {
+    focusTrade(Number(r.dataset.trade));
+    price.scrollIntoView({ behavior: "smooth", block: "center" });
+  }));
+
+  drawEquity();
+  drawDrawdown();
+  drawHistogram();
+  drawPrice();
+})();
@@ -5,7 +5,6 @@ import (
 	"math"
 
 	"github.com/markcheno/go-talib"
-	"gonum.org/v1/gonum/stat"
 )
 
 type BBWState struct {
@@ -16,41 +15,98 @@ type BBWState struct {
 	DurationSide int
 }
 
+// DetectBBWState classifies the Bollinger Band width regime at the last candle.
 func DetectBBWState(
 	candles market.CandleSticks,
 	period int,
 	multiplier float64,
 	bbwThreshold float64,
+	atrPeriod int,
 ) BBWState {
 	if len(candles) < period*2 {
 		return BBWState{Status: "InsufficientData"}
 	}
+	inputs := computeBBWInputs(candles, period, multiplier, atrPeriod)
+	return inputs.stateAt(len(candles)-1, period, bbwThreshold, atrPeriod)
+}
+
+// BBWStateSeries returns the BBW regime of every candle, identical to calling DetectBBWState
+// on each prefix candles[:i+1] but computing the underlying series once. All of them are
+// causal, so the value at i only depends on the candles up to i.
+func BBWStateSeries(
+	candles market.CandleSticks,
+	period int,
+	multiplier float64,
+	bbwThreshold float64,
+	atrPeriod int,
+) []BBWState {
+	states := make([]BBWState, len(candles))
+	if len(candles) < period*2 {
+		for i := range states {
+			states[i] = BBWState{Status: "InsufficientData"}
+		}
+		return states
+	}
+
+	inputs := computeBBWInputs(candles, period, multiplier, atrPeriod)
+	for i := range states {
+		if i+1 < period*2 {
+			states[i] = BBWState{Status: "InsufficientData"}
+			continue
+		}
+		states[i] = inputs.stateAt(i, period, bbwThreshold, atrPeriod)
+	}
+	return states
+}
+
+// bbwInputs holds the series the BBW regime is derived from.
+type bbwInputs struct {
+	candles    market.CandleSticks
+	bbw        []float64
+	normalized []float64
+	middle     []float64
+	atr        []float64
+	bbwEMA     []float64
+}
 
+func computeBBWInputs(candles market.CandleSticks, period int, multiplier float64, atrPeriod int) bbwInputs {
 	bbwSeries, _, middle, _ := BBW(candles, period, multiplier)
-	normalizedBBW := NormalizeBBW(bbwSeries, period)
+	return bbwInputs{
+		candles:    candles,
+		bbw:        bbwSeries,
+		normalized: NormalizeBBW(bbwSeries, period),
+		middle:     middle,
+		atr:        ATR(candles, atrPeriod),
+		bbwEMA:     talib.Ema(bbwSeries, 5),
+	}
+}
 
-	if math.Abs(normalizedBBW[len(normalizedBBW)-1]) < bbwThreshold {
+// stateAt classifies candle i from series computed over at least candles[:i+1].
+func (in bbwInputs) stateAt(i, period int, bbwThreshold float64, atrPeriod int) BBWState {
+	n := i + 1 // the length of the prefix DetectBBWState would see
+
+	if math.Abs(in.normalized[i]) < bbwThreshold {
 		return BBWState{Status: Neutral}
 	}
 
-	atr := ATR(candles, 14)
-	if len(atr) < 3 {
+	// ATR returns no values for a prefix of at most atrPeriod candles.
+	if n <= atrPeriod || n < 3 {
 		return BBWState{Status: "InsufficientATR"}
 	}
-	atrUp := atr[len(atr)-1] > atr[len(atr)-2] && atr[len(atr)-2] > atr[len(atr)-3]
+	atrUp := in.atr[i] > in.atr[i-1] && in.atr[i-1] > in.atr[i-2]
 
-	bbw := bbwSeries[len(bbwSeries)-1]
-	bbwEMA := talib.Ema(bbwSeries, 5)
-	if len(bbwEMA) < period {
+	bbw := in.bbw[i]
+	if n < period {
 		return BBWState{Status: "InsufficientBBWSeries"}
 	}
-	bbwAvg := bbwEMA[len(bbwEMA)-1]
-	bbwPrev := bbwEMA[len(bbwEMA)-2]
-	bbwPPrev := bbwEMA[len(bbwEMA)-3]
+	bbwAvg := in.bbwEMA[i]
+	bbwPrev := in.bbwEMA[i-1]
+	bbwPPrev := in.bbwEMA[i-2]
 	bbwUp := bbwAvg > bbwPrev && bbwPrev > bbwPPrev && bbw > bbwAvg
 	bbwDown := bbwAvg < bbwPrev && bbwPrev < bbwPPrev && bbw < bbwAvg
 
-	close := candles[len(candles)-1].Close
+	close := in.candles[i].Close
+	middle := in.middle
 
 	var status MarketState
 	var sidewaysDuration int
@@ -58,13 +114,13 @@ func DetectBBWState(
 	if bbwDown {
 		status = Squeeze
 	} else if bbwUp {
-		if close > middle[len(middle)-1] && middle[len(middle)-1] > middle[len(middle)-2] {
+		if close > middle[i] && middle[i] > middle[i-1] {
 			if atrUp {
 				status = ExpandingBullish
 			} else {
 				status = Neutral
 			}
-		} else if close < middle[len(middle)-1] && middle[len(middle)-1] < middle[len(middle)-2] {
+		} else if close < middle[i] && middle[i] < middle[i-1] {
 			if atrUp {
 				status = ExpandingBearish
 			} else {
@@ -72,8 +128,8 @@ func DetectBBWState(
 			}
 		}
 	} else {
-		m1 := middle[len(middle)-1]
-		m2 := middle[len(middle)-2]
+		m1 := middle[i]
+		m2 := middle[i-1]
 
 		isCenterLineUp := close > m1 && m1 > m2
 		isCenterLineDown := close < m1 && m1 < m2
@@ -105,20 +161,15 @@ func NormalizeBBW(bbwSeries []float64, window int) []float64 {
 	}
 
 	normalized := make([]float64, len(bbwSeries))
+	windowData := make([]float64, window)
 	for i := range bbwSeries {
 		if i < window {
 			normalized[i] = 0 // initial value
 			continue
 		}
 
-		windowData := bbwSeries[i-window : i]
-		mean := stat.Mean(windowData, nil)
-		std := stat.StdDev(windowData, nil)
-		if std == 0 {
-			normalized[i] = 0
-		} else {
-			normalized[i] = (bbwSeries[i] - mean) / std
-		}
+		copy(windowData, bbwSeries[i-window:i])
+		normalized[i] = windowZScore(windowData, bbwSeries[i])
 	}
 	return normalized
 }
@@ -154,7 +205,10 @@ func ATR(candles market.CandleSticks, period int) (atr []float64) {
 		lows = append(lows, c.Low)
 	}
 
-	atr = talib.Atr(highs, lows, closes, 14)
+	if len(candles) <= period {
+		return []float64{}
+	}
+	atr = talib.Atr(highs, lows, closes, period)
 	if len(atr) < 3 {
 		return []float64{}
 	}
@@ -0,0 +1,122 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/market"
+	"math"
+	"testing"
+
+	"github.com/markcheno/go-talib"
+)
+
+// The per-bar benchmark recomputes every series over the growing prefix, so its cost grows
+// quadratically; it is limited to smaller inputs to keep the run short.
+func BenchmarkDetectBBWStatePerBar(b *testing.B) {
+	for _, n := range []int{1_000, 5_000} {
+		candles := syntheticSource(1).GBM(n, 0, 0.004)
+		b.Run(fmt.Sprintf("candles=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				for i := range candles {
+					DetectBBWState(candles[:i+1], 20, 2.0, 0.5, 14)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkBBWStateSeries(b *testing.B) {
+	for _, n := range []int{1_000, 5_000, 100_000, 500_000} {
+		candles := syntheticSource(1).GBM(n, 0, 0.004)
+		b.Run(fmt.Sprintf("candles=%d", n), func(b *testing.B) {
+			for b.Loop() {
+				BBWStateSeries(candles, 20, 2.0, 0.5, 14)
+			}
+		})
+	}
+}
+
+// referenceBBWState is a frozen copy of the original DetectBBWState, which recomputes every
+// series over the prefix it is given. It is the oracle for the shared per-series code.
+func referenceBBWState(
+	candles market.CandleSticks,
+	period int,
+	multiplier float64,
+	bbwThreshold float64,
+	atrPeriod int,
+) BBWState {
+	if len(candles) < period*2 {
+		return BBWState{Status: "InsufficientData"}
+	}
+
+	bbwSeries, _, middle, _ := BBW(candles, period, multiplier)
+	normalizedBBW := NormalizeBBW(bbwSeries, period)
+
+	if math.Abs(normalizedBBW[len(normalizedBBW)-1]) < bbwThreshold {
+		return BBWState{Status: Neutral}
+	}
+
+	atr := ATR(candles, atrPeriod)
+	if len(atr) < 3 {
+		return BBWState{Status: "InsufficientATR"}
+	}
+	atrUp := atr[len(atr)-1] > atr[len(atr)-2] && atr[len(atr)-2] > atr[len(atr)-3]
+
+	bbw := bbwSeries[len(bbwSeries)-1]
+	bbwEMA := talib.Ema(bbwSeries, 5)
+	if len(bbwEMA) < period {
+		return BBWState{Status: "InsufficientBBWSeries"}
+	}
+	bbwAvg := bbwEMA[len(bbwEMA)-1]
+	bbwPrev := bbwEMA[len(bbwEMA)-2]
+	bbwPPrev := bbwEMA[len(bbwEMA)-3]
+	bbwUp := bbwAvg > bbwPrev && bbwPrev > bbwPPrev && bbw > bbwAvg
+	bbwDown := bbwAvg < bbwPrev && bbwPrev < bbwPPrev && bbw < bbwAvg
+
+	close := candles[len(candles)-1].Close
+
+	var status MarketState
+	var sidewaysDuration int
+
+	if bbwDown {
+		status = Squeeze
+	} else if bbwUp {
+		if close > middle[len(middle)-1] && middle[len(middle)-1] > middle[len(middle)-2] {
+			if atrUp {
+				status = ExpandingBullish
+			} else {
+				status = Neutral
+			}
+		} else if close < middle[len(middle)-1] && middle[len(middle)-1] < middle[len(middle)-2] {
+			if atrUp {
+				status = ExpandingBearish
+			} else {
+				status = Neutral
+			}
+		}
+	} else {
+		m1 := middle[len(middle)-1]
+		m2 := middle[len(middle)-2]
+
+		isCenterLineUp := close > m1 && m1 > m2
+		isCenterLineDown := close < m1 && m1 < m2
+
+		if isCenterLineUp && atrUp {
+			status = ExpandingBullish
+		} else if isCenterLineDown && atrUp {
+			status = ExpandingBearish
+		} else {
+			status = Neutral
+		}
+	}
+	// if atrUp {
+	// 	status = Volatile
+	// }
+
+	return BBWState{
+		Status:       status,
+		BBW:          bbw,
+		BBWAvg:       bbwAvg,
+		BBWTrendUp:   bbwUp,
+		DurationSide: sidewaysDuration,
+	}
+}
@@ -0,0 +1,59 @@
+package strategy
+
+// TradeChange is a trade both runs entered at the same time and in the same direction but
+// closed differently.
+type TradeChange struct {
+	Before Trade
+	After  Trade
+}
+
+// TradeDiff lists how the trades of a run differ from those of a baseline run.
+type TradeDiff struct {
+	// Added holds the trades only the run took and Removed those only the baseline took.
+	Added     []Trade
+	Removed   []Trade
+	Changed   []TradeChange
+	Unchanged int
+}
+
+type tradeKey struct {
+	entry     int64
+	direction string
+}
+
+func keyOf(t Trade) tradeKey {
+	return tradeKey{entry: t.EntryTime.UnixNano(), direction: t.Direction}
+}
+
+// DiffTrades compares the trades of run with those of baseline, matching them by entry time and
+// direction. A matched trade has changed when its entry or exit price, exit time, exit reason or
+// size differ.
+func DiffTrades(baseline, run []Trade) TradeDiff {
+	before := make(map[tradeKey]Trade, len(baseline))
+	for _, t := range baseline {
+		before[keyOf(t)] = t
+	}
+	matched := make(map[tradeKey]bool, len(run))
+
+	var diff TradeDiff
+	for _, t := range run {
+		b, ok := before[keyOf(t)]
+		if !ok {
+			diff.Added = append(diff.Added, t)
+			continue
+		}
+		matched[keyOf(t)] = true
+		if b.EntryPrice != t.EntryPrice || b.ExitPrice != t.ExitPrice || !b.ExitTime.Equal(t.ExitTime) ||
+			b.ExitReason != t.ExitReason || b.Size != t.Size {
+			diff.Changed = append(diff.Changed, TradeChange{Before: b, After: t})
+		} else {
+			diff.Unchanged++
+		}
+	}
+	for _, t := range baseline {
+		if !matched[keyOf(t)] {
+			diff.Removed = append(diff.Removed, t)
+		}
+	}
+	return diff
+}
@@ -0,0 +1,31 @@
+package strategy
+
+import (
+	"testing"
+	"time"
+)
+
+func TestDiffTrades(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trade := func(hour int, direction string, exitPrice float64) Trade {
+		entry := start.Add(time.Duration(hour) * time.Hour)
+		return Trade{EntryTime: entry, ExitTime: entry.Add(time.Hour), Direction: direction, EntryPrice: 100, ExitPrice: exitPrice, Size: 1}
+	}
+	baseline := []Trade{trade(0, "long", 101), trade(2, "long", 101), trade(4, "short", 99)}
+	// The same entry in the opposite direction is a different trade.
+	run := []Trade{trade(0, "long", 101), trade(2, "long", 102), trade(4, "long", 101), trade(6, "short", 99)}
+
+	diff := DiffTrades(baseline, run)
+	if diff.Unchanged != 1 {
+		t.Errorf("Expected 1 unchanged trade, but got %d", diff.Unchanged)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].Before.ExitPrice != 101 || diff.Changed[0].After.ExitPrice != 102 {
+		t.Errorf("Expected the second trade to change its exit from 101 to 102, but got %+v", diff.Changed)
+	}
+	if len(diff.Added) != 2 || diff.Added[0].Direction != "long" || !diff.Added[1].EntryTime.Equal(start.Add(6*time.Hour)) {
+		t.Errorf("Expected the long at 04:00 and the short at 06:00 to be added, but got %+v", diff.Added)
+	}
+	if len(diff.Removed) != 1 || diff.Removed[0].Direction != "short" {
+		t.Errorf("Expected the short at 04:00 to be removed, but got %+v", diff.Removed)
+	}
+}
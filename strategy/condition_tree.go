@@ -0,0 +1,186 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"log"
+	"math"
+	"strings"
+)
+
+// BuildConditionTree combines registered conditions and rules as described by node:
+//
+//   - a leaf with Name uses the registered condition for direction; a leaf with Rule uses
+//     the compiled entry rule, which never stops.
+//   - "all" enters when every child enters, "any" when at least one does.
+//   - "not" enters when its single child does not; it never stops.
+//   - "vote" enters when at least K children enter.
+//   - "confirm" enters when its single child has entered on each of the last Bars candles.
+//   - "cooldown" passes its single child through, except that it does not enter on the exit
+//     candle of the last trade or the Bars candles after it.
+//
+// Except for "not", a node stops when any of its children stops, so a combined condition
+// never holds a trade longer than one of its parts would.
+func BuildConditionTree(node config.ConditionConfig, direction string, cfg *config.Config) (EntryCondition, error) {
+	return buildConditionNode(node, direction, cfg, direction, node.Trace)
+}
+
+func buildConditionNode(node config.ConditionConfig, direction string, cfg *config.Config, path string, trace bool) (EntryCondition, error) {
+	trace = trace || node.Trace
+	path += " > " + conditionLabel(node)
+
+	condition, err := combineConditionNode(node, direction, cfg, path, trace)
+	if err != nil {
+		return nil, err
+	}
+	if len(node.Regime) > 0 {
+		if condition, err = withRegime(condition, node.Regime, direction, cfg); err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+	}
+	// Leaves record their own checks; operators record their result under their path.
+	isOperator := node.Op != ""
+	if !isOperator && !trace {
+		return condition, nil
+	}
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		entry, stop := condition(indicators)
+		if isOperator {
+			indicators.Check(path, math.NaN(), math.NaN(), entry)
+		}
+		if trace {
+			when := ""
+			if indicators.index < len(indicators.candles) {
+				when = indicators.candles[indicators.index].Time.Format("2006-01-02 15:04:05") + " "
+			}
+			log.Printf("%s%s: entry=%v stop=%v", when, path, entry, stop)
+		}
+		return entry, stop
+	}, nil
+}
+
+func combineConditionNode(node config.ConditionConfig, direction string, cfg *config.Config, path string, trace bool) (EntryCondition, error) {
+	if node.Op == "" {
+		switch {
+		case node.Name != "" && node.Rule != "":
+			return nil, fmt.Errorf("%s: a leaf has either a name or a rule, not both", path)
+		case node.Name != "":
+			condition, err := GetEntryCondition(node.Name, direction, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			return condition, nil
+		case node.Rule != "":
+			condition, err := CompileRuleCondition(config.RuleConfig{Entry: node.Rule}, cfg)
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", path, err)
+			}
+			return condition, nil
+		}
+		return nil, fmt.Errorf("%s: a leaf needs a name or a rule", path)
+	}
+	if node.Name != "" || node.Rule != "" {
+		return nil, fmt.Errorf("%s: operator %q cannot also have a name or a rule", path, node.Op)
+	}
+
+	children := make([]EntryCondition, len(node.Children))
+	for k, child := range node.Children {
+		condition, err := buildConditionNode(child, direction, cfg, path, trace)
+		if err != nil {
+			return nil, err
+		}
+		children[k] = condition
+	}
+
+	switch node.Op {
+	case "all", "any":
+		if len(children) == 0 {
+			return nil, fmt.Errorf("%s: %q needs at least one child", path, node.Op)
+		}
+		need := len(children)
+		if node.Op == "any" {
+			need = 1
+		}
+		return voteCondition(children, need), nil
+	case "vote":
+		if node.K < 1 || node.K > len(children) {
+			return nil, fmt.Errorf("%s: vote needs k between 1 and %d, got %d", path, len(children), node.K)
+		}
+		return voteCondition(children, node.K), nil
+	}
+
+	if len(children) != 1 {
+		return nil, fmt.Errorf("%s: %q takes exactly one child, got %d", path, node.Op, len(children))
+	}
+	child := children[0]
+	switch node.Op {
+	case "not":
+		return func(indicators TechnicalIndicators) (bool, bool) {
+			entry, _ := child(indicators)
+			return !entry, false
+		}, nil
+	case "confirm":
+		if node.Bars < 1 {
+			return nil, fmt.Errorf("%s: confirm needs bars of at least 1, got %d", path, node.Bars)
+		}
+		return confirmCondition(child, node.Bars), nil
+	case "cooldown":
+		if node.Bars < 0 {
+			return nil, fmt.Errorf("%s: cooldown needs non-negative bars, got %d", path, node.Bars)
+		}
+		return func(indicators TechnicalIndicators) (bool, bool) {
+			entry, stop := child(indicators)
+			if bars, ok := indicators.BarsSinceExit(); ok && bars <= node.Bars {
+				entry = false
+			}
+			return entry, stop
+		}, nil
+	}
+	return nil, fmt.Errorf("%s: unknown operator %q", path, node.Op)
+}
+
+// voteCondition enters when at least need children enter and stops when any child stops.
+func voteCondition(children []EntryCondition, need int) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		votes, stop := 0, false
+		for _, child := range children {
+			entry, childStop := child(indicators)
+			if entry {
+				votes++
+			}
+			stop = stop || childStop
+		}
+		return votes >= need, stop
+	}
+}
+
+// confirmCondition enters when child entered on each of the last bars candles, evaluating
+// earlier candles from the indicators' data context. The stop flag is the current one.
+func confirmCondition(child EntryCondition, bars int) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		entry, stop := child(indicators)
+		for k := 1; entry && k < bars; k++ {
+			past, ok := indicators.at(k)
+			if !ok {
+				return false, stop
+			}
+			entry, _ = child(past)
+		}
+		return entry, stop
+	}
+}
+
+// conditionLabel names a node in trace output, e.g. "vote(2)" or "dmi".
+func conditionLabel(node config.ConditionConfig) string {
+	switch {
+	case node.Op == "vote":
+		return fmt.Sprintf("vote(%d)", node.K)
+	case node.Op == "confirm" || node.Op == "cooldown":
+		return fmt.Sprintf("%s(%d)", node.Op, node.Bars)
+	case node.Op != "":
+		return node.Op
+	case node.Rule != "":
+		return "rule(" + strings.TrimSpace(node.Rule) + ")"
+	}
+	return node.Name
+}
@@ -0,0 +1,131 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"strings"
+	"testing"
+)
+
+// ruleSeriesConfig declares the series used by ruleIndicators, so rule leaves compile.
+func ruleSeriesConfig() *config.Config {
+	return &config.Config{Indicators: []config.IndicatorConfig{
+		{Name: "fast", Type: "ema", Params: map[string]float64{"period": 3}},
+		{Name: "slow", Type: "ema", Params: map[string]float64{"period": 8}},
+	}}
+}
+
+func TestConditionTreeCombinators(t *testing.T) {
+	indicators := ruleIndicators()
+	leaf := func(rule string) config.ConditionConfig { return config.ConditionConfig{Rule: rule} }
+	yes, no := leaf("fast > slow"), leaf("fast < slow")
+
+	tests := []struct {
+		name string
+		node config.ConditionConfig
+		want bool
+	}{
+		{"all true", config.ConditionConfig{Op: "all", Children: []config.ConditionConfig{yes, yes}}, true},
+		{"all mixed", config.ConditionConfig{Op: "all", Children: []config.ConditionConfig{yes, no}}, false},
+		{"any mixed", config.ConditionConfig{Op: "any", Children: []config.ConditionConfig{no, yes}}, true},
+		{"not", config.ConditionConfig{Op: "not", Children: []config.ConditionConfig{no}}, true},
+		{"vote 2 of 3", config.ConditionConfig{Op: "vote", K: 2, Children: []config.ConditionConfig{yes, no, yes}}, true},
+		{"vote 3 of 3", config.ConditionConfig{Op: "vote", K: 3, Children: []config.ConditionConfig{yes, no, yes}}, false},
+		{"nested", config.ConditionConfig{Op: "all", Children: []config.ConditionConfig{
+			yes, {Op: "not", Children: []config.ConditionConfig{{Op: "any", Children: []config.ConditionConfig{no, no}}}},
+		}}, true},
+	}
+	for _, tt := range tests {
+		condition, err := BuildConditionTree(tt.node, "long", ruleSeriesConfig())
+		if err != nil {
+			t.Errorf("%s: BuildConditionTree failed: %v", tt.name, err)
+			continue
+		}
+		if entry, _ := condition(indicators); entry != tt.want {
+			t.Errorf("%s: Expected entry to be %v, but got %v", tt.name, tt.want, entry)
+		}
+	}
+}
+
+func TestConditionTreeStopPropagation(t *testing.T) {
+	longEntryConditions["test_stop"] = func(*config.Config) (EntryCondition, error) {
+		return func(TechnicalIndicators) (bool, bool) { return false, true }, nil
+	}
+	defer delete(longEntryConditions, "test_stop")
+
+	node := config.ConditionConfig{Op: "any", Children: []config.ConditionConfig{{Rule: "fast > slow"}, {Name: "test_stop"}}}
+	condition, err := BuildConditionTree(node, "long", ruleSeriesConfig())
+	if err != nil {
+		t.Fatalf("BuildConditionTree failed: %v", err)
+	}
+	if entry, stop := condition(ruleIndicators()); !entry || !stop {
+		t.Errorf("Expected any-of to enter and pass the child's stop through, but got %v, %v", entry, stop)
+	}
+}
+
+func TestConditionTreeConfirmAndCooldown(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:      "test_data.csv",
+		EmaPeriod:     5,
+		ADXPeriod:     5,
+		VWZPeriod:     5,
+		BBWPeriod:     20,
+		BBWMultiplier: 2.0,
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+	rising := config.ConditionConfig{Rule: "close > close[-1]"}
+	confirm, err := BuildConditionTree(config.ConditionConfig{Op: "confirm", Bars: 3, Children: []config.ConditionConfig{rising}}, "long", cfg)
+	if err != nil {
+		t.Fatalf("BuildConditionTree failed: %v", err)
+	}
+
+	candles := strategyData.Candles
+	confirmed := 0
+	for i := 3; i < len(candles); i++ {
+		want := candles[i].Close > candles[i-1].Close && candles[i-1].Close > candles[i-2].Close && candles[i-2].Close > candles[i-3].Close
+		if entry, _ := confirm(strategyData.createTechnicalIndicators(i, cfg)); entry != want {
+			t.Fatalf("Expected confirm(3) at candle %d to be %v, but got %v", i, want, entry)
+		}
+		if want {
+			confirmed++
+		}
+	}
+	if confirmed == 0 {
+		t.Error("Expected the test data to contain three rising closes in a row")
+	}
+
+	cooldown, err := BuildConditionTree(config.ConditionConfig{Op: "cooldown", Bars: 2, Children: []config.ConditionConfig{{Rule: "true"}}}, "long", cfg)
+	if err != nil {
+		t.Fatalf("BuildConditionTree failed: %v", err)
+	}
+	indicators := strategyData.createTechnicalIndicators(10, cfg)
+	for exit, want := range map[int]bool{-1: true, 10: false, 8: false, 7: true} {
+		if entry, _ := cooldown(indicators.withLastExit(exit)); entry != want {
+			t.Errorf("Expected cooldown(2) with the last exit at %d to be %v, but got %v", exit, want, entry)
+		}
+	}
+}
+
+func TestConditionTreeValidation(t *testing.T) {
+	tests := []struct {
+		node config.ConditionConfig
+		want string
+	}{
+		{config.ConditionConfig{}, "needs a name or a rule"},
+		{config.ConditionConfig{Name: "missing"}, "no long entry condition found"},
+		{config.ConditionConfig{Op: "all"}, "at least one child"},
+		{config.ConditionConfig{Op: "vote", K: 3, Children: []config.ConditionConfig{{Name: "dmi"}}}, "vote needs k"},
+		{config.ConditionConfig{Op: "not", Children: []config.ConditionConfig{{Name: "dmi"}, {Name: "bbw"}}}, "exactly one child"},
+		{config.ConditionConfig{Op: "confirm", Children: []config.ConditionConfig{{Name: "dmi"}}}, "confirm needs bars"},
+		{config.ConditionConfig{Op: "xor", Children: []config.ConditionConfig{{Name: "dmi"}}}, `unknown operator "xor"`},
+		{config.ConditionConfig{Op: "all", Children: []config.ConditionConfig{{Op: "not", Children: []config.ConditionConfig{{Rule: "fast >"}}}}}, "long > all > not > rule(fast >)"},
+	}
+	for _, tt := range tests {
+		_, err := BuildConditionTree(tt.node, "long", ruleSeriesConfig())
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected building %+v to fail with %q, but got %v", tt.node, tt.want, err)
+		}
+	}
+}
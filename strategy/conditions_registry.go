@@ -1,31 +1,70 @@
 package strategy
 
-import "fmt"
-
-// longEntryConditions holds the registry for long entry condition functions.
-var longEntryConditions = map[string]EntryCondition{
-	"default":  DefaultLongCondition,
-	"macd":     MACDLongCondition,
-	"bbw":      BBWLongCondition,
-	"combined": CombinedLongCondition,
-	"inverse":  InverseLongCondition,
-	"dmi":      DMILongCondition,
-}
-
-// shortEntryConditions holds the registry for short entry condition functions.
-var shortEntryConditions = map[string]EntryCondition{
-	"default":  DefaultShortCondition,
-	"macd":     MACDShortCondition,
-	"bbw":      BBWShortCondition,
-	"combined": CombinedShortCondition,
-	"inverse":  InverseShortCondition,
-	"dmi":      DMIShortCondition,
-}
-
-// GetEntryCondition retrieves a long or short entry condition function from the registry.
-// direction must be "long" or "short".
-func GetEntryCondition(name string, direction string) (EntryCondition, error) {
-	var registry map[string]EntryCondition
+import (
+	"fmt"
+	"go-backtesting/config"
+)
+
+// conditionFactory builds a registered condition from the configuration, applying the
+// defaults of its parameter block and rejecting invalid parameters.
+type conditionFactory func(cfg *config.Config) (EntryCondition, error)
+
+// longEntryConditions holds the registry for long entry conditions.
+var longEntryConditions = map[string]conditionFactory{
+	"default": func(cfg *config.Config) (EntryCondition, error) {
+		return NewDefaultLongCondition(cfg.ConditionParams.Default), nil
+	},
+	"macd": func(cfg *config.Config) (EntryCondition, error) {
+		return NewMACDLongCondition(cfg.ConditionParams.MACD), nil
+	},
+	"bbw": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := bbwConditionParams(cfg)
+		return NewBBWLongCondition(p), err
+	},
+	"combined": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := combinedConditionParams(cfg)
+		return NewCombinedLongCondition(p), err
+	},
+	"inverse": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := inverseConditionParams(cfg)
+		return NewInverseLongCondition(p), err
+	},
+	"dmi": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := dmiConditionParams(cfg)
+		return NewDMILongCondition(p), err
+	},
+}
+
+// shortEntryConditions holds the registry for short entry conditions.
+var shortEntryConditions = map[string]conditionFactory{
+	"default": func(cfg *config.Config) (EntryCondition, error) {
+		return NewDefaultShortCondition(cfg.ConditionParams.Default), nil
+	},
+	"macd": func(cfg *config.Config) (EntryCondition, error) {
+		return NewMACDShortCondition(cfg.ConditionParams.MACD), nil
+	},
+	"bbw": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := bbwConditionParams(cfg)
+		return NewBBWShortCondition(p), err
+	},
+	"combined": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := combinedConditionParams(cfg)
+		return NewCombinedShortCondition(p), err
+	},
+	"inverse": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := inverseConditionParams(cfg)
+		return NewInverseShortCondition(p), err
+	},
+	"dmi": func(cfg *config.Config) (EntryCondition, error) {
+		p, err := dmiConditionParams(cfg)
+		return NewDMIShortCondition(p), err
+	},
+}
+
+// GetEntryCondition builds a long or short entry condition from the registry, with the
+// parameters from its block in cfg.ConditionParams. direction must be "long" or "short".
+func GetEntryCondition(name string, direction string, cfg *config.Config) (EntryCondition, error) {
+	var registry map[string]conditionFactory
 	switch direction {
 	case "long":
 		registry = longEntryConditions
@@ -35,9 +74,105 @@ func GetEntryCondition(name string, direction string) (EntryCondition, error) {
 		return nil, fmt.Errorf("invalid direction for entry condition: %s", direction)
 	}
 
-	condition, ok := registry[name]
+	factory, ok := registry[name]
 	if !ok {
 		return nil, fmt.Errorf("no %s entry condition found for name: %s", direction, name)
 	}
+	condition, err := factory(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid parameters for %s entry condition %s: %w", direction, name, err)
+	}
+	return condition, nil
+}
+
+func bbwConditionParams(cfg *config.Config) (config.BBWConditionParams, error) {
+	p := cfg.ConditionParams.BBW
+	if p.MinBoxFilter == 0 {
+		p.MinBoxFilter = 2.0
+	}
+	if p.MinBoxFilter < 0 {
+		return p, fmt.Errorf("minBoxFilter must not be negative, got %v", p.MinBoxFilter)
+	}
+	return p, nil
+}
+
+func combinedConditionParams(cfg *config.Config) (config.CombinedConditionParams, error) {
+	p := cfg.ConditionParams.Combined
+	if p.VolatilityRatio == 0 {
+		p.VolatilityRatio = 1.5
+	}
+	if p.ZScoreSpike == 0 {
+		p.ZScoreSpike = 1.2
+	}
+	if p.VWZSpike == 0 {
+		p.VWZSpike = 1.0
+	}
+	if p.VolatilityRatio <= 1 {
+		return p, fmt.Errorf("volatilityRatio must be greater than 1, got %v", p.VolatilityRatio)
+	}
+	if p.ZScoreSpike < 0 || p.VWZSpike < 0 {
+		return p, fmt.Errorf("spike thresholds must not be negative, got zscoreSpike %v and vwzSpike %v", p.ZScoreSpike, p.VWZSpike)
+	}
+	return p, nil
+}
+
+func inverseConditionParams(cfg *config.Config) (config.InverseConditionParams, error) {
+	p := cfg.ConditionParams.Inverse
+	if p.BBWZExtreme == 0 {
+		p.BBWZExtreme = 2.5
+	}
+	if p.BBWZExtreme < 0 {
+		return p, fmt.Errorf("bbwzExtreme must not be negative, got %v", p.BBWZExtreme)
+	}
+	return p, nil
+}
+
+func dmiConditionParams(cfg *config.Config) (config.DMIConditionParams, error) {
+	p := cfg.ConditionParams.DMI
+	if p.ADXThreshold == 0 {
+		p.ADXThreshold = cfg.ADXThreshold
+	}
+	if p.DXThreshold == 0 {
+		p.DXThreshold = cfg.ADXThreshold
+	}
+	if p.ADXThreshold < 0 || p.DXThreshold < 0 {
+		return p, fmt.Errorf("thresholds must not be negative, got adxThreshold %v and dxThreshold %v", p.ADXThreshold, p.DXThreshold)
+	}
+	return p, nil
+}
+
+// ResolveEntryCondition returns the condition configured for direction: the compiled rule
+// when one is set, then the condition tree, otherwise the registered condition named by
+// LongCondition or ShortCondition. The condition is gated by the direction's regime filters.
+func ResolveEntryCondition(cfg *config.Config, direction string) (EntryCondition, error) {
+	if !conflictPolicies[cfg.ConflictPolicy] {
+		return nil, fmt.Errorf("unknown conflict policy %q", cfg.ConflictPolicy)
+	}
+	rule, tree, name, regime := cfg.LongRule, cfg.LongConditions, cfg.LongCondition, cfg.LongRegime
+	if direction == "short" {
+		rule, tree, name, regime = cfg.ShortRule, cfg.ShortConditions, cfg.ShortCondition, cfg.ShortRegime
+	}
+
+	var condition EntryCondition
+	var err error
+	switch {
+	case rule.Entry != "":
+		if condition, err = CompileRuleCondition(rule, cfg); err != nil {
+			return nil, fmt.Errorf("invalid %s rule: %w", direction, err)
+		}
+	case tree != nil:
+		if condition, err = BuildConditionTree(*tree, direction, cfg); err != nil {
+			return nil, fmt.Errorf("invalid %s condition tree: %w", direction, err)
+		}
+	default:
+		if condition, err = GetEntryCondition(name, direction, cfg); err != nil {
+			return nil, err
+		}
+	}
+
+	condition, err = withRegime(condition, regime, direction, cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid %s regime: %w", direction, err)
+	}
 	return condition, nil
 }
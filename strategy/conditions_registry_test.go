@@ -0,0 +1,65 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"strings"
+	"testing"
+)
+
+func TestGetEntryConditionUsesParameterBlocks(t *testing.T) {
+	indicators := TechnicalIndicators{
+		ADX:     []float64{20, 22, 24},
+		PlusDI:  []float64{20, 25, 30},
+		MinusDI: []float64{15, 14, 12},
+	}
+
+	// The DMI thresholds default to the top-level ADX threshold.
+	cfg := &config.Config{ADXThreshold: 25}
+	dmi, err := GetEntryCondition("dmi", "long", cfg)
+	if err != nil {
+		t.Fatalf("GetEntryCondition failed: %v", err)
+	}
+	if entry, _ := dmi(indicators); entry {
+		t.Error("Expected no entry with ADX 24 below the default threshold 25")
+	}
+
+	cfg.ConditionParams.DMI = config.DMIConditionParams{ADXThreshold: 20, DXThreshold: 15}
+	dmi, err = GetEntryCondition("dmi", "long", cfg)
+	if err != nil {
+		t.Fatalf("GetEntryCondition failed: %v", err)
+	}
+	if entry, stop := dmi(indicators); !entry || stop {
+		t.Errorf("Expected an entry with the configured thresholds, but got %v, %v", entry, stop)
+	}
+
+	cfg.ConditionParams.Default.ZScoreLevel = 1
+	def, err := GetEntryCondition("default", "long", cfg)
+	if err != nil {
+		t.Fatalf("GetEntryCondition failed: %v", err)
+	}
+	ema := TechnicalIndicators{EmaShort: []float64{2}, EmaLong: []float64{1}, ZScore: []float64{0.5}}
+	if entry, _ := def(ema); !entry {
+		t.Error("Expected the default condition to enter below the configured z-score level 1")
+	}
+}
+
+func TestGetEntryConditionValidatesParameters(t *testing.T) {
+	tests := []struct {
+		name   string
+		params config.ConditionParams
+		want   string
+	}{
+		{"dmi", config.ConditionParams{DMI: config.DMIConditionParams{DXThreshold: -1}}, "thresholds must not be negative"},
+		{"bbw", config.ConditionParams{BBW: config.BBWConditionParams{MinBoxFilter: -2}}, "minBoxFilter"},
+		{"combined", config.ConditionParams{Combined: config.CombinedConditionParams{VolatilityRatio: 0.5}}, "volatilityRatio"},
+		{"inverse", config.ConditionParams{Inverse: config.InverseConditionParams{BBWZExtreme: -1}}, "bbwzExtreme"},
+	}
+	for _, tt := range tests {
+		for _, direction := range []string{"long", "short"} {
+			_, err := GetEntryCondition(tt.name, direction, &config.Config{ConditionParams: tt.params})
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Expected %s %s to fail with %q, but got %v", direction, tt.name, tt.want, err)
+			}
+		}
+	}
+}
@@ -4,78 +4,142 @@ import (
 	"fmt"
 	"go-backtesting/config"
 	"go-backtesting/market"
-
-	"github.com/markcheno/go-talib"
+	"math"
+	"sort"
+	"time"
 )
 
 // initializeStrategyDataContext initializes the strategy data context.
 func InitializeStrategyDataContext(config *config.Config) (*StrategyDataContext, error) {
-	// 1. Read Candles from CSV
-	candles, err := market.ReadCandlesFromCSV(config.FilePath)
+	if config.LookbackDepth != 0 && config.LookbackDepth < minLookbackDepth {
+		return nil, fmt.Errorf("lookbackDepth must be at least %d, got %d", minLookbackDepth, config.LookbackDepth)
+	}
+
+	// 1. Read Candles from CSV, normalizing the source timezone to UTC
+	sourceLoc, err := config.SourceLocation()
+	if err != nil {
+		return nil, fmt.Errorf("invalid source timezone: %w", err)
+	}
+	candles, err := loadCandles(config, sourceLoc)
+	if err != nil {
+		return nil, err
+	}
+
+	// Keep the trading window plus the warmup bars before it
+	start, end, err := config.TimeRange()
 	if err != nil {
-		return nil, fmt.Errorf("failed to read candle data: %w", err)
+		return nil, fmt.Errorf("invalid date range: %w", err)
+	}
+	candles, startIndex := windowCandles(candles, start, end, config.WarmupBars)
+
+	var session *Session
+	if config.Session != nil {
+		session, err = NewSession(*config.Session)
+		if err != nil {
+			return nil, fmt.Errorf("invalid session: %w", err)
+		}
 	}
 
 	if len(candles) == 0 {
-		return &StrategyDataContext{Candles: candles}, nil // Return empty context if no candles
+		return &StrategyDataContext{Candles: candles, Session: session}, nil // Return empty context if no candles
 	}
 
-	// 2. Prepare data for TALib
-	highs := make([]float64, len(candles))
-	lows := make([]float64, len(candles))
-	closes := make([]float64, len(candles))
-	for i, c := range candles {
-		highs[i] = c.High
-		lows[i] = c.Low
-		closes[i] = c.Close
+	// 2. Calculate all declared indicator series
+	indicators, err := ComputeIndicators(candles, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to compute indicators: %w", err)
 	}
 
-	// 3. Calculate all indicator series
-	emaShort := talib.Ema(closes, config.EmaPeriod)
-	emaLong := talib.Ema(closes, config.EmaPeriod*10)
-	zScores := ZScores(candles, config.VWZPeriod)
-	vwzScores := VWZScores(candles, config.VWZPeriod, config.VWZScore.MinStdDev)
-	bbw, _, _, _ := BBW(candles, config.BBWPeriod, config.BBWMultiplier)
-	bbwzScores := NormalizeBBW(bbw, 48)
-	boxFilter := BoxFilterNormalized(candles, 12, 48)
+	regimes, err := ClassifyRegimes(candles, indicators, startIndex, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to classify market regimes: %w", err)
+	}
 
-	adxSeries := talib.Adx(highs, lows, closes, config.ADXPeriod)
-	plusDI := talib.PlusDI(highs, lows, closes, config.ADXPeriod)
-	minusDI := talib.MinusDI(highs, lows, closes, config.ADXPeriod)
-	dx := talib.Dx(highs, lows, closes, config.ADXPeriod)
+	// 3. Create and return the context
+	return &StrategyDataContext{
+		Candles:    candles,
+		Indicators: indicators,
+		Session:    session,
+		StartIndex: startIndex,
+		BBWStates:  BBWStateSeries(candles, config.BBWPeriod, config.BBWMultiplier, config.BBWThreshold, atrPeriod(config)),
+		Regimes:    regimes,
+	}, nil
+}
 
-	// Calculate MACD
-	macd, macdSignal, macdHistogram := CalculateMACD(closes, 12, 26, 9)
+// windowCandles trims candles to [start, end) plus up to warmup bars before start and returns
+// the index of the first candle inside the window. If the window itself is empty, no candles are
+// returned so callers can report that no data is available for the range.
+func windowCandles(candles market.CandleSticks, start, end time.Time, warmup int) (market.CandleSticks, int) {
+	first := 0
+	if !start.IsZero() {
+		first = sort.Search(len(candles), func(i int) bool { return !candles[i].Time.Before(start) })
+	}
+	last := len(candles)
+	if !end.IsZero() {
+		last = sort.Search(len(candles), func(i int) bool { return !candles[i].Time.Before(end) })
+	}
+	if first >= last {
+		return nil, 0
+	}
+	from := max(first-warmup, 0)
+	return candles[from:last], first - from
+}
 
-	// Pad the MACD results to align with the candle data.
-	macdOffset := len(closes) - len(macd)
-	signalOffset := len(closes) - len(macdSignal)
-	histogramOffset := len(closes) - len(macdHistogram)
+// loadCandles reads the configured input, either a candle CSV or trade prints aggregated into bars.
+func loadCandles(config *config.Config, loc *time.Location) (market.CandleSticks, error) {
+	if config.TickFilePath == "" {
+		read := market.ReadCandlesFromCSVInLocation
+		if config.CandleCache {
+			read = func(path string, loc *time.Location) (market.CandleSticks, error) {
+				return market.LoadCandlesCached(path, config.CacheDir, loc)
+			}
+		}
+		candles, err := read(config.FilePath, loc)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read candle data: %w", err)
+		}
+		return candles, nil
+	}
 
-	finalMacd := make([]float64, len(closes))
-	finalSignal := make([]float64, len(closes))
-	finalHistogram := make([]float64, len(closes))
+	ticks, err := market.ReadTicksFromCSV(config.TickFilePath, loc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read tick data: %w", err)
+	}
+	candles, err := buildBars(ticks, config.Bars)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build bars: %w", err)
+	}
+	return candles, nil
+}
 
-	copy(finalMacd[macdOffset:], macd)
-	copy(finalSignal[signalOffset:], macdSignal)
-	copy(finalHistogram[histogramOffset:], macdHistogram)
+// buildBars aggregates trade prints into candles of the configured bar type.
+func buildBars(ticks market.Ticks, bars config.BarConfig) (market.CandleSticks, error) {
+	if bars.Type == "time" {
+		interval, err := market.ParseTimeframe(bars.Timeframe)
+		if err != nil {
+			return nil, err
+		}
+		return market.TimeBars(ticks, interval), nil
+	}
 
-	// 4. Create and return the context
-	return &StrategyDataContext{
-		Candles:       candles,
-		EmaShort:      emaShort,
-		EmaLong:       emaLong,
-		ZScores:       zScores,
-		VwzScores:     vwzScores,
-		PlusDI:        plusDI,
-		MinusDI:       minusDI,
-		AdxSeries:     adxSeries,
-		BbwzScores:    bbwzScores,
-		Bbw:           bbw,
-		DX:            dx,
-		MACD:          finalMacd,
-		MACDSignal:    finalSignal,
-		MACDHistogram: finalHistogram,
-		BoxFilter:     boxFilter,
-	}, nil
+	if bars.Size <= 0 {
+		return nil, fmt.Errorf("bar size must be positive for %q bars", bars.Type)
+	}
+	switch bars.Type {
+	case "tick":
+		if bars.Size < 1 || bars.Size != math.Trunc(bars.Size) {
+			return nil, fmt.Errorf("tick bar size must be a whole number of ticks, got %v", bars.Size)
+		}
+		return market.TickBars(ticks, int(bars.Size)), nil
+	case "volume":
+		return market.VolumeBars(ticks, bars.Size), nil
+	case "dollar":
+		return market.DollarBars(ticks, bars.Size), nil
+	case "range":
+		return market.RangeBars(ticks, bars.Size), nil
+	case "renko":
+		return market.RenkoBars(ticks, bars.Size), nil
+	default:
+		return nil, fmt.Errorf("unknown bar type: %q", bars.Type)
+	}
 }
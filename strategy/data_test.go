@@ -0,0 +1,91 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"testing"
+	"time"
+)
+
+func TestWindowCandles(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var candles market.CandleSticks
+	for i := 0; i < 10; i++ {
+		candles = append(candles, market.Candle{Time: start.Add(time.Duration(i) * time.Hour)})
+	}
+
+	windowed, startIndex := windowCandles(candles, start.Add(5*time.Hour), start.Add(8*time.Hour), 3)
+	if len(windowed) != 6 || startIndex != 3 {
+		t.Fatalf("Expected 6 candles starting trading at 3, but got %d starting at %d", len(windowed), startIndex)
+	}
+	if !windowed[startIndex].Time.Equal(start.Add(5 * time.Hour)) {
+		t.Errorf("Expected the first tradable candle at 05:00, but got %v", windowed[startIndex].Time)
+	}
+
+	// Warmup is limited by the data available before the start.
+	if windowed, startIndex := windowCandles(candles, start.Add(time.Hour), time.Time{}, 5); len(windowed) != 10 || startIndex != 1 {
+		t.Errorf("Expected 10 candles starting trading at 1, but got %d starting at %d", len(windowed), startIndex)
+	}
+
+	if windowed, _ := windowCandles(candles, start.Add(20*time.Hour), time.Time{}, 5); len(windowed) != 0 {
+		t.Errorf("Expected no candles for a range after the data, but got %d", len(windowed))
+	}
+}
+
+func TestBuildBarsRejectsFractionalTickSizes(t *testing.T) {
+	start := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	var ticks market.Ticks
+	for i := range 6 {
+		ticks = append(ticks, market.Tick{Time: start.Add(time.Duration(i) * time.Second), Price: 100 + float64(i), Qty: 1})
+	}
+
+	candles, err := buildBars(ticks, config.BarConfig{Type: "tick", Size: 2})
+	if err != nil || len(candles) != 3 {
+		t.Fatalf("Expected 3 bars of 2 ticks, but got %d (%v)", len(candles), err)
+	}
+	for _, size := range []float64{0.5, 2.5, -1} {
+		if _, err := buildBars(ticks, config.BarConfig{Type: "tick", Size: size}); err == nil {
+			t.Errorf("Expected tick bar size %v to be rejected", size)
+		}
+	}
+	// Fractional sizes stay valid for the bar types measured in quantity or price.
+	if _, err := buildBars(ticks, config.BarConfig{Type: "volume", Size: 2.5}); err != nil {
+		t.Errorf("Expected a fractional volume bar size to be accepted, but got %v", err)
+	}
+}
+
+func TestSignalsRespectStartTime(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:          "test_data.csv",
+		VWZPeriod:         5,
+		EmaPeriod:         5,
+		ADXPeriod:         5,
+		ADXThreshold:      0,
+		AdxUpperThreshold: 100,
+		BBWPeriod:         20,
+		BBWMultiplier:     2.0,
+		VWZScore:          config.VWZScoreConfig{MinStdDev: 1e-5},
+		StartTime:         "2025-09-01 04:00:00",
+		WarmupBars:        30,
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+	windowStart := strategyData.Candles[strategyData.StartIndex].Time
+	if !windowStart.Equal(time.Date(2025, 9, 1, 4, 0, 0, 0, time.UTC)) {
+		t.Fatalf("Expected the window to start at 04:00, but got %v", windowStart)
+	}
+
+	always := func(indicators TechnicalIndicators) (bool, bool) { return true, false }
+	never := func(indicators TechnicalIndicators) (bool, bool) { return false, false }
+	signals := GenerateAllSignals(strategyData, cfg, always, never)
+	if len(signals) == 0 {
+		t.Fatal("Expected signals inside the window")
+	}
+	for _, s := range signals {
+		if s.Time.Before(windowStart) {
+			t.Errorf("Signal at %v precedes the start of the window", s.Time)
+		}
+	}
+}
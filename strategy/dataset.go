@@ -0,0 +1,248 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"time"
+)
+
+// Dataset is a table for offline research, one row per candle or per entry signal. Every
+// column has one value per row; missing numbers are NaN.
+type Dataset struct {
+	Time    []time.Time
+	Strings []StringColumn
+	Floats  []FloatColumn
+}
+
+// StringColumn is a named text column of a Dataset.
+type StringColumn struct {
+	Name   string
+	Values []string
+}
+
+// FloatColumn is a named numeric column of a Dataset.
+type FloatColumn struct {
+	Name   string
+	Values []float64
+}
+
+// Len returns the number of rows.
+func (d *Dataset) Len() int {
+	return len(d.Time)
+}
+
+// exportSettings is an ExportConfig with its defaults applied.
+type exportSettings struct {
+	signalsOnly bool
+	horizons    []int
+	maxHolding  int
+}
+
+func exportSettingsFor(cfg *config.Config) (exportSettings, error) {
+	e := cfg.Export
+	s := exportSettings{horizons: e.Horizons, maxHolding: e.MaxHolding}
+	switch e.Rows {
+	case "", "candles":
+	case "signals":
+		s.signalsOnly = true
+	default:
+		return s, fmt.Errorf("unknown export rows %q (available: candles, signals)", e.Rows)
+	}
+	if len(s.horizons) == 0 {
+		s.horizons = []int{1, 5, 20}
+	}
+	for _, h := range s.horizons {
+		if h < 1 {
+			return s, fmt.Errorf("export horizons must be positive, got %d", h)
+		}
+	}
+	if s.maxHolding == 0 {
+		s.maxHolding = 20
+	}
+	if s.maxHolding < 1 {
+		return s, fmt.Errorf("export maxHolding must be positive, got %d", s.maxHolding)
+	}
+	return s, nil
+}
+
+// The dataset columns besides the indicator series, in the order BuildDataset adds them.
+var (
+	candleColumns  = []string{"open", "high", "low", "close", "volume"}
+	verdictColumns = []string{"long_entry", "long_stop", "short_entry", "short_stop"}
+)
+
+func forwardReturnColumn(horizon int) string {
+	return fmt.Sprintf("fwd_return_%d", horizon)
+}
+
+// checkIndicatorNames rejects indicator series named like one of the dataset's own columns,
+// which would otherwise appear twice in the export.
+func (e exportSettings) checkIndicatorNames(names []string) error {
+	reserved := map[string]bool{"time": true, "regime": true, "bbw_state": true, "signal": true}
+	for _, name := range append(append([]string{}, candleColumns...), verdictColumns...) {
+		reserved[name] = true
+	}
+	for _, h := range e.horizons {
+		reserved[forwardReturnColumn(h)] = true
+	}
+	for _, direction := range []string{"long", "short"} {
+		reserved["tb_"+direction], reserved["tb_"+direction+"_bars"] = true, true
+	}
+	for _, name := range names {
+		if reserved[name] {
+			return fmt.Errorf("indicator %q has the name of a dataset column; rename it to export the dataset", name)
+		}
+	}
+	return nil
+}
+
+// BuildDataset builds the research dataset configured by cfg.Export. Each row holds:
+//   - time, the candle's open, high, low, close and volume, and every indicator series;
+//   - "regime" and "bbw_state";
+//   - the long and short conditions' verdicts as 0 or 1 ("long_entry", "long_stop",
+//     "short_entry", "short_stop"), NaN where the runners would not look for an entry, and
+//     "signal", the direction the runners would enter in given those verdicts and the regime
+//     sizing, if any;
+//   - "fwd_return_<h>", the return from the close to the close h candles later;
+//   - "tb_long", "tb_short": triple-barrier labels of a trade entered at the close, 1 when
+//     the take-profit level (TPRate) is reached first, -1 when the stop-loss level (SLRate) is,
+//     and 0 when neither is within MaxHolding candles; "tb_long_bars" and "tb_short_bars"
+//     count the candles until then. A candle reaching both levels counts as a take profit, as
+//     in RunBacktest.
+//
+// Labels look into the future and are NaN where the candles run out before they are decided.
+// Indicators named like one of these columns are rejected.
+func BuildDataset(s *StrategyDataContext, cfg *config.Config, longCondition, shortCondition EntryCondition) (*Dataset, error) {
+	settings, err := exportSettingsFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	if s.Indicators != nil {
+		if err := settings.checkIndicatorNames(s.Indicators.Names()); err != nil {
+			return nil, err
+		}
+	}
+
+	var rows []int
+	verdicts := make(map[int][4]float64)
+	signals := make(map[int]string)
+	for i := range s.Candles {
+		if !s.canSignal(i, cfg) {
+			if !settings.signalsOnly {
+				rows = append(rows, i)
+			}
+			continue
+		}
+		indicators := s.createTechnicalIndicators(i, cfg)
+		longEntry, longStop := longCondition(indicators)
+		shortEntry, shortStop := shortCondition(indicators)
+		verdicts[i] = [4]float64{boolFloat(longEntry), boolFloat(longStop), boolFloat(shortEntry), boolFloat(shortStop)}
+		direction, entry, _ := applyConflictPolicy(cfg.ConflictPolicy,
+			func() (bool, bool) { return longEntry, longStop },
+			func() (bool, bool) { return shortEntry, shortStop })
+		// As in traceDecision, a signal in a regime sized 0 is not taken.
+		if entry && RegimeSize(cfg, indicators.Regime) > 0 {
+			signals[i] = direction
+		}
+		if !settings.signalsOnly || signals[i] != "" {
+			rows = append(rows, i)
+		}
+	}
+
+	d := &Dataset{Time: make([]time.Time, len(rows))}
+	for r, i := range rows {
+		d.Time[r] = s.Candles[i].Time
+	}
+	float := func(name string, value func(i int) float64) {
+		column := FloatColumn{Name: name, Values: make([]float64, len(rows))}
+		for r, i := range rows {
+			column.Values[r] = value(i)
+		}
+		d.Floats = append(d.Floats, column)
+	}
+	text := func(name string, value func(i int) string) {
+		column := StringColumn{Name: name, Values: make([]string, len(rows))}
+		for r, i := range rows {
+			column.Values[r] = value(i)
+		}
+		d.Strings = append(d.Strings, column)
+	}
+
+	text("regime", func(i int) string { return string(s.regimeAt(i)) })
+	text("bbw_state", func(i int) string { return string(s.bbwStateAt(i, cfg).Status) })
+	text("signal", func(i int) string { return signals[i] })
+
+	for _, field := range candleColumns {
+		float(field, func(i int) float64 {
+			current := TechnicalIndicators{candles: s.Candles, index: i}
+			return current.candleField(field, 0)
+		})
+	}
+	if s.Indicators != nil {
+		for _, name := range s.Indicators.Names() {
+			series := s.Indicators.Get(name)
+			float(name, func(i int) float64 { return seriesAt(series, i) })
+		}
+	}
+	for k, name := range verdictColumns {
+		float(name, func(i int) float64 {
+			if v, ok := verdicts[i]; ok {
+				return v[k]
+			}
+			return math.NaN()
+		})
+	}
+
+	for _, h := range settings.horizons {
+		float(forwardReturnColumn(h), func(i int) float64 {
+			if i+h >= len(s.Candles) {
+				return math.NaN()
+			}
+			return s.Candles[i+h].Close/s.Candles[i].Close - 1
+		})
+	}
+	for _, direction := range []string{"long", "short"} {
+		labels := make(map[int][2]float64, len(rows))
+		for _, i := range rows {
+			label, bars := tripleBarrier(s.Candles, i, direction, cfg.TPRate, cfg.SLRate, settings.maxHolding)
+			labels[i] = [2]float64{label, bars}
+		}
+		float("tb_"+direction, func(i int) float64 { return labels[i][0] })
+		float("tb_"+direction+"_bars", func(i int) float64 { return labels[i][1] })
+	}
+	return d, nil
+}
+
+// tripleBarrier labels a trade in direction entered at the close of candle i: 1 when the
+// take-profit level is reached first, -1 for the stop-loss level and 0 when neither is reached
+// within maxHolding candles, together with the number of candles until then. A zero rate
+// disables its barrier. Both are NaN when the candles end first.
+func tripleBarrier(candles market.CandleSticks, i int, direction string, tpRate, slRate float64, maxHolding int) (label, bars float64) {
+	entry := candles[i].Close
+	for k := 1; k <= maxHolding; k++ {
+		if i+k >= len(candles) {
+			return math.NaN(), math.NaN()
+		}
+		c := candles[i+k]
+		favorable, adverse := c.High/entry-1, 1-c.Low/entry
+		if direction == "short" {
+			favorable, adverse = 1-c.Low/entry, c.High/entry-1
+		}
+		switch {
+		case tpRate > 0 && favorable >= tpRate:
+			return 1, float64(k)
+		case slRate > 0 && adverse >= slRate:
+			return -1, float64(k)
+		}
+	}
+	return 0, float64(maxHolding)
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,135 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTripleBarrier(t *testing.T) {
+	candles := make(market.CandleSticks, 6)
+	for i := range candles {
+		candles[i] = market.Candle{Time: time.Unix(int64(i*60), 0), Open: 100, High: 100.5, Low: 99.5, Close: 100}
+	}
+	candles[3].High = 101.5 // +1.5%
+	candles[4].Low = 97     // -3%
+
+	tests := []struct {
+		direction   string
+		tp, sl      float64
+		maxHolding  int
+		label, bars float64
+	}{
+		{"long", 0.01, 0.02, 5, 1, 3},
+		{"long", 0.02, 0.02, 5, -1, 4},
+		{"short", 0.02, 0.01, 5, -1, 3},
+		{"short", 0.02, 0.05, 5, 1, 4},
+		{"long", 0.02, 0.05, 2, 0, 2},
+		{"long", 0.05, 0.05, 10, math.NaN(), math.NaN()},
+	}
+	for _, tt := range tests {
+		label, bars := tripleBarrier(candles, 0, tt.direction, tt.tp, tt.sl, tt.maxHolding)
+		if !sameFloat(label, tt.label) || !sameFloat(bars, tt.bars) {
+			t.Errorf("Expected %s with TP %v and SL %v to be labelled %v after %v, but got %v after %v",
+				tt.direction, tt.tp, tt.sl, tt.label, tt.bars, label, bars)
+		}
+	}
+}
+
+func TestBuildDataset(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:          "test_data.csv",
+		VWZPeriod:         5,
+		EmaPeriod:         5,
+		ADXPeriod:         5,
+		AdxUpperThreshold: 100,
+		TPRate:            0.01,
+		SLRate:            0.01,
+		BBWPeriod:         20,
+		BBWMultiplier:     2.0,
+		Export:            config.ExportConfig{Horizons: []int{1, 3}},
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+
+	candles, err := BuildDataset(strategyData, cfg, DefaultLongCondition, DefaultShortCondition)
+	if err != nil {
+		t.Fatalf("BuildDataset failed: %v", err)
+	}
+	if candles.Len() != len(strategyData.Candles) {
+		t.Fatalf("Expected one row per candle, but got %d rows for %d candles", candles.Len(), len(strategyData.Candles))
+	}
+	columns := map[string][]float64{}
+	for _, c := range candles.Floats {
+		columns[c.Name] = c.Values
+	}
+	for _, name := range []string{"close", SeriesADX, SeriesVWZ, "long_entry", "short_stop", "fwd_return_1", "fwd_return_3", "tb_long", "tb_short_bars"} {
+		if len(columns[name]) != candles.Len() {
+			t.Errorf("Expected a %q column, but got %d values", name, len(columns[name]))
+		}
+	}
+	last := candles.Len() - 1
+	if want := strategyData.Candles[3].Close/strategyData.Candles[0].Close - 1; columns["fwd_return_3"][0] != want {
+		t.Errorf("Expected fwd_return_3 of the first candle to be %v, but got %v", want, columns["fwd_return_3"][0])
+	}
+	if !math.IsNaN(columns["fwd_return_1"][last]) || !math.IsNaN(columns["long_entry"][0]) {
+		t.Error("Expected labels past the data and verdicts during warmup to be missing")
+	}
+
+	cfg.Export.Rows = "signals"
+	signals, err := BuildDataset(strategyData, cfg, DefaultLongCondition, DefaultShortCondition)
+	if err != nil {
+		t.Fatalf("BuildDataset failed: %v", err)
+	}
+	if want := len(GenerateAllSignals(strategyData, cfg, DefaultLongCondition, DefaultShortCondition)); signals.Len() != want || want == 0 {
+		t.Errorf("Expected one row per signal (%d), but got %d", want, signals.Len())
+	}
+	for r, direction := range signals.Strings[2].Values {
+		if direction == "" {
+			t.Errorf("Expected signal row %d to have a direction", r)
+		}
+	}
+
+	// A regime sized 0 keeps its verdicts but takes no signals, as in the runners.
+	cfg.Regime.Sizing = map[string]float64{string(RegimeUnknown): 0}
+	cfg.Export.Rows = ""
+	unsized, err := BuildDataset(strategyData, cfg, DefaultLongCondition, DefaultShortCondition)
+	if err != nil {
+		t.Fatalf("BuildDataset failed: %v", err)
+	}
+	for _, column := range unsized.Floats {
+		for r, v := range column.Values {
+			if !sameFloat(v, columns[column.Name][r]) {
+				t.Fatalf("Expected the regime sizing to leave %q unchanged, but row %d changed", column.Name, r)
+			}
+		}
+	}
+	for r, direction := range unsized.Strings[2].Values {
+		if direction != "" {
+			t.Fatalf("Expected no signal in a regime sized 0, but got %q at row %d", direction, r)
+		}
+	}
+	cfg.Regime.Sizing = nil
+
+	cfg.Export.Rows = "trades"
+	if _, err := BuildDataset(strategyData, cfg, DefaultLongCondition, DefaultShortCondition); err == nil {
+		t.Error("Expected unknown export rows to be rejected")
+	}
+
+	cfg.Export.Rows = ""
+	for _, name := range []string{"close", "regime", "fwd_return_3", "tb_short_bars"} {
+		cfg.Indicators = []config.IndicatorConfig{{Name: name, Type: "ema", Params: map[string]float64{"period": 5}}}
+		clashing, err := InitializeStrategyDataContext(cfg)
+		if err != nil {
+			t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+		}
+		if _, err := BuildDataset(clashing, cfg, DefaultLongCondition, DefaultShortCondition); err == nil || !strings.Contains(err.Error(), name) {
+			t.Errorf("Expected an indicator named %q to be rejected, but got %v", name, err)
+		}
+	}
+}
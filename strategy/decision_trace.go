@@ -0,0 +1,247 @@
+package strategy
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"go-backtesting/config"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+)
+
+// Check is one named test inside a condition: the value it looked at, what it was compared
+// against (NaN when there is no single threshold) and whether it passed.
+type Check struct {
+	Name      string
+	Value     float64
+	Threshold float64
+	Passed    bool
+}
+
+// Verdict is a condition's decision together with the checks that led to it, in the order
+// they were made. A condition that stops at its first failing check records no later checks.
+type Verdict struct {
+	Entry  bool
+	Stop   bool
+	Checks []Check
+}
+
+// Explain evaluates condition and returns its verdict with the checks it recorded through
+// TechnicalIndicators.Check.
+func Explain(condition EntryCondition, indicators TechnicalIndicators) Verdict {
+	var checks []Check
+	indicators.checks = &checks
+	entry, stop := condition(indicators)
+	return Verdict{Entry: entry, Stop: stop, Checks: checks}
+}
+
+// Check records a named sub-check when the condition is being explained and returns passed,
+// so conditions can write: if !indicators.Check("adx_rising", adx, prevADX, adx > prevADX) { ... }.
+func (t TechnicalIndicators) Check(name string, value, threshold float64, passed bool) bool {
+	if t.checks != nil {
+		*t.checks = append(*t.checks, Check{Name: name, Value: value, Threshold: threshold, Passed: passed})
+	}
+	return passed
+}
+
+// Decision is the outcome of DetermineEntrySignal at one candle, after regime sizing, as written
+// to the trace.
+type Decision struct {
+	Time  time.Time
+	Index int
+	// Phase is "entry" when looking for a new trade and "exit" while a trade is open.
+	Phase string
+	// ADX is the current ADX, for reference; regime gates are recorded as checks.
+	ADX       float64
+	Direction string
+	Entry     bool
+	Stop      bool
+	Long      Verdict
+	Short     Verdict
+}
+
+// Rejection counts how often a check failed when no entry was taken.
+type Rejection struct {
+	Check string
+	Count int
+}
+
+// DecisionTrace writes one JSON line per decision and counts which checks reject entries.
+type DecisionTrace struct {
+	w          *bufio.Writer
+	closer     io.Closer
+	err        error
+	rejections map[string]int
+}
+
+// NewDecisionTrace writes decisions to w.
+func NewDecisionTrace(w io.Writer) *DecisionTrace {
+	return &DecisionTrace{w: bufio.NewWriter(w), rejections: make(map[string]int)}
+}
+
+// CreateDecisionTrace writes decisions to a new file at path.
+func CreateDecisionTrace(path string) (*DecisionTrace, error) {
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("error creating decision trace: %w", err)
+	}
+	trace := NewDecisionTrace(file)
+	trace.closer = file
+	return trace, nil
+}
+
+// Record writes a decision and, for entry decisions that did not enter, counts the failed checks.
+func (d *DecisionTrace) Record(decision Decision) {
+	if decision.Phase == "entry" && !decision.Entry {
+		d.countFailures("long", decision.Long)
+		d.countFailures("short", decision.Short)
+	}
+	if d.err != nil {
+		return
+	}
+	line, err := json.Marshal(decision.record())
+	if err == nil {
+		line = append(line, '\n')
+		_, err = d.w.Write(line)
+	}
+	d.err = err
+}
+
+func (d *DecisionTrace) countFailures(direction string, verdict Verdict) {
+	for _, check := range verdict.Checks {
+		if !check.Passed {
+			d.rejections[direction+" "+check.Name]++
+		}
+	}
+}
+
+// Rejections returns the failed checks, most frequent first.
+func (d *DecisionTrace) Rejections() []Rejection {
+	rejections := make([]Rejection, 0, len(d.rejections))
+	for check, count := range d.rejections {
+		rejections = append(rejections, Rejection{Check: check, Count: count})
+	}
+	sort.Slice(rejections, func(a, b int) bool {
+		if rejections[a].Count != rejections[b].Count {
+			return rejections[a].Count > rejections[b].Count
+		}
+		return rejections[a].Check < rejections[b].Check
+	})
+	return rejections
+}
+
+// Close flushes the trace and closes its file, returning the first write error.
+func (d *DecisionTrace) Close() error {
+	if err := d.w.Flush(); d.err == nil {
+		d.err = err
+	}
+	if d.closer != nil {
+		if err := d.closer.Close(); d.err == nil {
+			d.err = err
+		}
+	}
+	return d.err
+}
+
+// signal returns the verdict's decision in the form of an EntryCondition.
+func (v Verdict) signal() (bool, bool) {
+	return v.Entry, v.Stop
+}
+
+// traceDecision determines the entry signal at candle i and records it when tracing is enabled.
+// When tracing, the signal is derived from the recorded verdicts, so every condition runs once.
+// In the entry phase a signal is only taken when its regime is sized above 0 (see RegimeSize); a
+// signal the sizing rejects is recorded with a failed "regime_sizing" check in its direction.
+func (s *StrategyDataContext) traceDecision(i int, phase string, indicators TechnicalIndicators, cfg *config.Config, longCondition, shortCondition EntryCondition) (string, bool, bool) {
+	if s.Trace == nil {
+		direction, entry, stop := DetermineEntrySignal(indicators, cfg, longCondition, shortCondition)
+		if phase == "entry" && entry && RegimeSize(cfg, indicators.Regime) <= 0 {
+			entry = false
+		}
+		return direction, entry, stop
+	}
+
+	long, short := Explain(longCondition, indicators), Explain(shortCondition, indicators)
+	direction, entry, stop := applyConflictPolicy(cfg.ConflictPolicy, long.signal, short.signal)
+	if phase == "entry" && entry {
+		if size := RegimeSize(cfg, indicators.Regime); size <= 0 {
+			entry = false
+			verdict := &long
+			if direction == "short" {
+				verdict = &short
+			}
+			verdict.Checks = append(verdict.Checks, Check{Name: "regime_sizing", Value: size, Threshold: 0})
+		}
+	}
+	s.Trace.Record(Decision{
+		Time:      s.Candles[i].Time,
+		Index:     i,
+		Phase:     phase,
+		ADX:       last(indicators.ADX),
+		Direction: direction,
+		Entry:     entry,
+		Stop:      stop,
+		Long:      long,
+		Short:     short,
+	})
+	return direction, entry, stop
+}
+
+// The trace JSON uses lower-case keys and writes missing values (NaN) as null.
+
+type checkRecord struct {
+	Name      string   `json:"name"`
+	Value     *float64 `json:"value"`
+	Threshold *float64 `json:"threshold,omitempty"`
+	Passed    bool     `json:"passed"`
+}
+
+type verdictRecord struct {
+	Entry  bool          `json:"entry"`
+	Stop   bool          `json:"stop"`
+	Checks []checkRecord `json:"checks"`
+}
+
+type decisionRecord struct {
+	Time      time.Time     `json:"time"`
+	Index     int           `json:"index"`
+	Phase     string        `json:"phase"`
+	ADX       *float64      `json:"adx"`
+	Direction string        `json:"direction,omitempty"`
+	Entry     bool          `json:"entry"`
+	Stop      bool          `json:"stop"`
+	Long      verdictRecord `json:"long"`
+	Short     verdictRecord `json:"short"`
+}
+
+func (d Decision) record() decisionRecord {
+	return decisionRecord{
+		Time:      d.Time,
+		Index:     d.Index,
+		Phase:     d.Phase,
+		ADX:       finiteOrNil(d.ADX),
+		Direction: d.Direction,
+		Entry:     d.Entry,
+		Stop:      d.Stop,
+		Long:      d.Long.record(),
+		Short:     d.Short.record(),
+	}
+}
+
+func (v Verdict) record() verdictRecord {
+	checks := make([]checkRecord, len(v.Checks))
+	for k, c := range v.Checks {
+		checks[k] = checkRecord{Name: c.Name, Value: finiteOrNil(c.Value), Threshold: finiteOrNil(c.Threshold), Passed: c.Passed}
+	}
+	return verdictRecord{Entry: v.Entry, Stop: v.Stop, Checks: checks}
+}
+
+func finiteOrNil(v float64) *float64 {
+	if math.IsNaN(v) || math.IsInf(v, 0) {
+		return nil
+	}
+	return &v
+}
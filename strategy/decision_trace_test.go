@@ -0,0 +1,142 @@
+package strategy
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"testing"
+)
+
+func TestExplainRecordsChecks(t *testing.T) {
+	indicators := TechnicalIndicators{
+		EmaShort: []float64{1, 2, 3},
+		EmaLong:  []float64{2, 2, 2},
+		ZScore:   []float64{0, 0, 0.5},
+	}
+	verdict := Explain(DefaultLongCondition, indicators)
+	if verdict.Entry || len(verdict.Checks) != 2 {
+		t.Fatalf("Expected a rejected verdict with 2 checks, but got %+v", verdict)
+	}
+	if c := verdict.Checks[0]; c.Name != "ema_short_above_long" || !c.Passed || c.Value != 3 || c.Threshold != 2 {
+		t.Errorf("Expected a passed ema check comparing 3 with 2, but got %+v", c)
+	}
+	if c := verdict.Checks[1]; c.Name != "zscore_below_level" || c.Passed || c.Value != 0.5 {
+		t.Errorf("Expected a failed zscore check on 0.5, but got %+v", c)
+	}
+
+	// Without Explain nothing is recorded.
+	if entry, _ := DefaultLongCondition(indicators); entry {
+		t.Error("Expected no entry")
+	}
+	if indicators.checks != nil {
+		t.Error("Expected no recorder outside Explain")
+	}
+}
+
+func TestDecisionTraceWritesEveryDecision(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:          "test_data.csv",
+		VWZPeriod:         5,
+		EmaPeriod:         5,
+		ADXPeriod:         5,
+		AdxUpperThreshold: 100,
+		TPRate:            0.01,
+		SLRate:            0.01,
+		BBWPeriod:         20,
+		BBWMultiplier:     2.0,
+		BBWThreshold:      0.01,
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+	var buf bytes.Buffer
+	strategyData.Trace = NewDecisionTrace(&buf)
+
+	signals := GenerateAllSignals(strategyData, cfg, DefaultLongCondition, DefaultShortCondition)
+	if err := strategyData.Trace.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	lines, entries, rejected := 0, 0, 0
+	scanner := bufio.NewScanner(&buf)
+	for scanner.Scan() {
+		var decision struct {
+			Phase string   `json:"phase"`
+			ADX   *float64 `json:"adx"`
+			Entry bool     `json:"entry"`
+			Long  struct {
+				Checks []struct {
+					Name   string `json:"name"`
+					Passed bool   `json:"passed"`
+				} `json:"checks"`
+			} `json:"long"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &decision); err != nil {
+			t.Fatalf("Expected line %d to be valid JSON, but got %v: %s", lines+1, err, scanner.Text())
+		}
+		if decision.Phase != "entry" || len(decision.Long.Checks) == 0 {
+			t.Fatalf("Expected an entry decision with long checks, but got %s", scanner.Text())
+		}
+		if decision.Entry {
+			entries++
+		} else {
+			rejected++
+		}
+		lines++
+	}
+	if lines == 0 || entries != len(signals) {
+		t.Errorf("Expected one line per decision and %d entries, but got %d lines and %d entries", len(signals), lines, entries)
+	}
+
+	total := 0
+	rejections := strategyData.Trace.Rejections()
+	for k, r := range rejections {
+		total += r.Count
+		if k > 0 && r.Count > rejections[k-1].Count {
+			t.Errorf("Expected rejections sorted by count, but got %v", rejections)
+		}
+	}
+	if rejected > 0 && total < rejected {
+		t.Errorf("Expected at least one failed check per rejected decision (%d), but counted %d", rejected, total)
+	}
+}
+
+func TestDecisionRecordWritesNaNAsNull(t *testing.T) {
+	line, err := json.Marshal(Decision{ADX: math.NaN(), Long: Verdict{Checks: []Check{{Name: "x", Value: math.NaN(), Threshold: math.NaN()}}}}.record())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !bytes.Contains(line, []byte(`"adx":null`)) || !bytes.Contains(line, []byte(`{"name":"x","value":null,"passed":false}`)) {
+		t.Errorf("Expected NaN values as null, but got %s", line)
+	}
+}
+
+func TestTraceDecisionEvaluatesConditionsOnce(t *testing.T) {
+	longCalls, shortCalls := 0, 0
+	long := func(indicators TechnicalIndicators) (bool, bool) {
+		longCalls++
+		return indicators.Check("long", 1, 0, true), false
+	}
+	short := func(indicators TechnicalIndicators) (bool, bool) {
+		shortCalls++
+		return indicators.Check("short", 1, 0, true), false
+	}
+	s := &StrategyDataContext{Candles: make([]market.Candle, 1)}
+	var buf bytes.Buffer
+	s.Trace = NewDecisionTrace(&buf)
+
+	for policy, want := range map[string]string{"": "long", "short_first": "short", "skip": ""} {
+		longCalls, shortCalls = 0, 0
+		direction, entry, _ := s.traceDecision(0, "entry", TechnicalIndicators{}, &config.Config{ConflictPolicy: policy}, long, short)
+		if direction != want || entry != (want != "") {
+			t.Errorf("Expected policy %q to enter %q, but got %q (entry %v)", policy, want, direction, entry)
+		}
+		if longCalls != 1 || shortCalls != 1 {
+			t.Errorf("Expected each condition evaluated once with policy %q, but got %d long and %d short calls", policy, longCalls, shortCalls)
+		}
+	}
+}
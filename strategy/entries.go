@@ -2,171 +2,195 @@ package strategy
 
 import (
 	"go-backtesting/config"
-	"log"
 	"math"
 )
 
-// DefaultLongCondition provides the default logic for a long entry signal.
-func BBWLongCondition(indicators TechnicalIndicators) (bool, bool) {
-	entry := indicators.EmaShort[1] < indicators.EmaShort[2] &&
+// NewBBWLongCondition enters long on a rising short EMA above the long EMA, rising VWZ and a
+// box filter above p.MinBoxFilter.
+func NewBBWLongCondition(p config.BBWConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return bbwLong(indicators, p)
+	}
+}
+
+// NewBBWShortCondition is the mirror image of NewBBWLongCondition.
+func NewBBWShortCondition(p config.BBWConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return bbwShort(indicators, p)
+	}
+}
+
+func bbwLong(indicators TechnicalIndicators, p config.BBWConditionParams) (bool, bool) {
+	entry := ago(indicators.EmaShort, 1) < ago(indicators.EmaShort, 0) &&
 		last(indicators.EmaShort) > last(indicators.EmaLong) &&
 		// indicators.BBState.Status == ExpandingBullish &&
-		// indicators.ADX[0] < 25.0 &&
+		// ago(indicators.ADX, 2) < 25.0 &&
 		// last(indicators.BbwzScore) > 1.0 &&
-		// indicators.EmaShort[0] < indicators.EmaShort[1] &&
-		// indicators.ZScore[1] < indicators.ZScore[2] &&
-		indicators.VWZScore[1] < indicators.VWZScore[2] &&
-		// indicators.VWZScore[1]*indicators.VWZScore[2] > 0 &&
-		// indicators.VWZScore[2] > 1.5 &&
-		// indicators.VWZScore[0] < indicators.VWZScore[1] &&
-		// indicators.BbwzScore[1] < indicators.BbwzScore[2] &&
-		// indicators.PlusDI[1] < indicators.PlusDI[2] &&
-		// indicators.BoxFilter[2] < 1.0 &&
-		// indicators.BoxFilter[2] > 1.0 && //indicators.ADX[2] < 25.0) &&
-		// indicators.BoxFilter[1]*indicators.BoxFilter[2] > 0 &&
-		indicators.BoxFilter[2] > 2.0 &&
+		// ago(indicators.EmaShort, 2) < ago(indicators.EmaShort, 1) &&
+		// ago(indicators.ZScore, 1) < ago(indicators.ZScore, 0) &&
+		ago(indicators.VWZScore, 1) < ago(indicators.VWZScore, 0) &&
+		// ago(indicators.VWZScore, 1)*ago(indicators.VWZScore, 0) > 0 &&
+		// ago(indicators.VWZScore, 0) > 1.5 &&
+		// ago(indicators.VWZScore, 2) < ago(indicators.VWZScore, 1) &&
+		// ago(indicators.BbwzScore, 1) < ago(indicators.BbwzScore, 0) &&
+		// ago(indicators.PlusDI, 1) < ago(indicators.PlusDI, 0) &&
+		// ago(indicators.BoxFilter, 0) < 1.0 &&
+		// ago(indicators.BoxFilter, 0) > 1.0 && //ago(indicators.ADX, 0) < 25.0) &&
+		// ago(indicators.BoxFilter, 1)*ago(indicators.BoxFilter, 0) > 0 &&
+		ago(indicators.BoxFilter, 0) > p.MinBoxFilter &&
 		last(indicators.PlusDI) > last(indicators.MinusDI)
 	return entry, false
 }
 
-// DefaultShortCondition provides the default logic for a short entry signal.
-func BBWShortCondition(indicators TechnicalIndicators) (bool, bool) {
-	entry := indicators.EmaShort[1] > indicators.EmaShort[2] &&
+func bbwShort(indicators TechnicalIndicators, p config.BBWConditionParams) (bool, bool) {
+	entry := ago(indicators.EmaShort, 1) > ago(indicators.EmaShort, 0) &&
 		last(indicators.EmaShort) < last(indicators.EmaLong) &&
 		// indicators.BBState.Status == ExpandingBearish &&
-		// indicators.ADX[0] < 25.0 &&
+		// ago(indicators.ADX, 2) < 25.0 &&
 		// last(indicators.BbwzScore) < -1.0 &&
-		// indicators.EmaShort[0] > indicators.EmaShort[1] &&
-		// indicators.ZScore[1] > indicators.ZScore[2] &&
-		indicators.VWZScore[1] > indicators.VWZScore[2] &&
-		// indicators.VWZScore[1]*indicators.VWZScore[2] > 0 &&
-		// indicators.VWZScore[2] < -1.5 &&
-		// indicators.VWZScore[0] > indicators.VWZScore[1] &&
-		// indicators.BbwzScore[1] > indicators.BbwzScore[2] &&
-		// indicators.MinusDI[1] < indicators.MinusDI[2] &&
-		// indicators.BoxFilter[2] > 1.0 && // indicators.ADX[2] < 25.0) &&
-		// indicators.BoxFilter[1]*indicators.BoxFilter[2] > 0 &&
-		indicators.BoxFilter[2] > 2.0 &&
+		// ago(indicators.EmaShort, 2) > ago(indicators.EmaShort, 1) &&
+		// ago(indicators.ZScore, 1) > ago(indicators.ZScore, 0) &&
+		ago(indicators.VWZScore, 1) > ago(indicators.VWZScore, 0) &&
+		// ago(indicators.VWZScore, 1)*ago(indicators.VWZScore, 0) > 0 &&
+		// ago(indicators.VWZScore, 0) < -1.5 &&
+		// ago(indicators.VWZScore, 2) > ago(indicators.VWZScore, 1) &&
+		// ago(indicators.BbwzScore, 1) > ago(indicators.BbwzScore, 0) &&
+		// ago(indicators.MinusDI, 1) < ago(indicators.MinusDI, 0) &&
+		// ago(indicators.BoxFilter, 0) > 1.0 && // ago(indicators.ADX, 0) < 25.0) &&
+		// ago(indicators.BoxFilter, 1)*ago(indicators.BoxFilter, 0) > 0 &&
+		ago(indicators.BoxFilter, 0) > p.MinBoxFilter &&
 		last(indicators.PlusDI) < last(indicators.MinusDI)
 	return entry, false
 }
 
-func CombinedLongCondition(indicators TechnicalIndicators) (bool, bool) {
-	side := EvaluateSignal(indicators.ZScore, indicators.VWZScore,
-		indicators.BbwzScore, indicators.ADX, indicators.PlusDI, indicators.MinusDI, indicators.DX)
-	return side == "long", false
+// NewCombinedLongCondition enters when EvaluateSignal reads the indicator patterns as long.
+func NewCombinedLongCondition(p config.CombinedConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return combinedSide(indicators, p) == "long", false
+	}
 }
-func CombinedShortCondition(indicators TechnicalIndicators) (bool, bool) {
-	side := EvaluateSignal(indicators.ZScore, indicators.VWZScore,
+
+// NewCombinedShortCondition enters when EvaluateSignal reads the indicator patterns as short.
+func NewCombinedShortCondition(p config.CombinedConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return combinedSide(indicators, p) == "short", false
+	}
+}
+
+func combinedSide(indicators TechnicalIndicators, p config.CombinedConditionParams) string {
+	return EvaluateSignal(p, indicators.ZScore, indicators.VWZScore,
 		indicators.BbwzScore, indicators.ADX, indicators.PlusDI, indicators.MinusDI, indicators.DX)
-	return side == "short", false
 }
 
-func InverseLongCondition(indicators TechnicalIndicators) (bool, bool) {
-	entry := (last(indicators.BbwzScore) > 2.5 || last(indicators.BbwzScore) < -2.5) &&
+// NewInverseLongCondition enters long on an extreme BBW z-score while the short EMA is above the long EMA.
+func NewInverseLongCondition(p config.InverseConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return inverseLong(indicators, p)
+	}
+}
+
+// NewInverseShortCondition enters short on an extreme BBW z-score while the short EMA is below the long EMA.
+func NewInverseShortCondition(p config.InverseConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return inverseShort(indicators, p)
+	}
+}
+
+func inverseLong(indicators TechnicalIndicators, p config.InverseConditionParams) (bool, bool) {
+	entry := math.Abs(last(indicators.BbwzScore)) > p.BBWZExtreme &&
 		// last(indicators.VWZScore) < -1.5 &&
 		// indicators.BBState.Status == Squeeze &&
 		last(indicators.EmaShort) > last(indicators.EmaLong)
 	return entry, false
 }
-func InverseShortCondition(indicators TechnicalIndicators) (bool, bool) {
-	entry := (last(indicators.BbwzScore) > 2.5 || last(indicators.BbwzScore) < -2.5) &&
+func inverseShort(indicators TechnicalIndicators, p config.InverseConditionParams) (bool, bool) {
+	entry := math.Abs(last(indicators.BbwzScore)) > p.BBWZExtreme &&
 		// last(indicators.VWZScore) > 1.5 &&
 		// indicators.BBState.Status == Squeeze &&
 		last(indicators.EmaShort) < last(indicators.EmaLong)
 	return entry, false
 }
 
-func DMILongCondition(indicators TechnicalIndicators) (bool, bool) {
-	// --- 1. Load Configuration ---
-	cfg, err := config.LoadConfig("config.json")
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
+// NewDMILongCondition enters long on a strong, rising ADX with +DI above and rising away from -DI.
+// It stops a long trade once -DI is above +DI.
+func NewDMILongCondition(p config.DMIConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return dmiLong(indicators, p)
+	}
+}
+
+// NewDMIShortCondition is the mirror image of NewDMILongCondition.
+func NewDMIShortCondition(p config.DMIConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return dmiShort(indicators, p)
 	}
-	// if indicators.BbwzScore[2] > 1.0 {
+}
+
+func dmiLong(indicators TechnicalIndicators, p config.DMIConditionParams) (bool, bool) {
+	// if ago(indicators.BbwzScore, 0) > 1.0 {
 	// 	return false
 	// }
-	// if indicators.BoxFilter[2] > cfg.BoxFilter.Threshold {
+	// if ago(indicators.BoxFilter, 0) > cfg.BoxFilter.Threshold {
 	// 	return false
 	// }
-	// 기존 ADX 조건
-	condition1 := indicators.ADX[2] > cfg.ADXThreshold
-	// ADX 증가 조건
-	condition2 := indicators.ADX[2] > indicators.ADX[1]
+	adx, prevADX := ago(indicators.ADX, 0), ago(indicators.ADX, 1)
+	plusDI, minusDI := ago(indicators.PlusDI, 0), ago(indicators.MinusDI, 0)
 
-	stopCondition := indicators.PlusDI[2] < indicators.MinusDI[2]
+	stopCondition := plusDI < minusDI
 
-	if !condition1 || !condition2 {
-		// fmt.Printf("[%s 진입 거절] ADX가 작거나 증가하지 않음\n", symbol)
+	// 기존 ADX 조건, ADX 증가 조건
+	if !indicators.Check("adx_above_threshold", adx, p.ADXThreshold, adx > p.ADXThreshold) ||
+		!indicators.Check("adx_rising", adx, prevADX, adx > prevADX) {
 		return false, stopCondition
 	}
 
-	// DX 필터 추가
-	dx := math.Abs(indicators.PlusDI[2] - indicators.MinusDI[2])
-	if dx < cfg.ADXThreshold {
-		// fmt.Printf("[%s 진입 거절] DX %.2f < %.2f (방향성 약함)\n", symbol, dx, dmiParam.DXThreshold)
+	// DX 필터 추가: 방향성 약함
+	dx := math.Abs(plusDI - minusDI)
+	if !indicators.Check("di_spread", dx, p.DXThreshold, dx >= p.DXThreshold) {
 		return false, stopCondition
 	}
 
 	// DI 방향 및 DI 증가 조건
-	var condition3, diIncrease bool
-	condition3 = indicators.PlusDI[2] > indicators.MinusDI[2]
-	diIncrease = indicators.PlusDI[2] > indicators.PlusDI[1]
-
-	if !condition3 {
-		// fmt.Printf("[%s 진입 거절] 추세 방향 반대 side: %s PlusDI: %.2f, MinusDI: %.2f\n",
+	if !indicators.Check("plus_di_above_minus_di", plusDI, minusDI, plusDI > minusDI) {
 		return false, stopCondition
 	}
-
-	if !diIncrease {
-		// fmt.Printf("[%s 진입 거절] 진입 방향 DI가 증가하지 않음\n", symbol)
+	prevPlusDI := ago(indicators.PlusDI, 1)
+	if !indicators.Check("plus_di_rising", plusDI, prevPlusDI, plusDI > prevPlusDI) {
 		return false, stopCondition
 	}
 	return true, stopCondition
 }
 
-func DMIShortCondition(indicators TechnicalIndicators) (bool, bool) {
-
-	cfg, err := config.LoadConfig("config.json")
-	if err != nil {
-		log.Fatalf("Failed to load configuration: %v", err)
-	}
-	// if indicators.BbwzScore[2] > 1.0 {
+func dmiShort(indicators TechnicalIndicators, p config.DMIConditionParams) (bool, bool) {
+	// if ago(indicators.BbwzScore, 0) > 1.0 {
 	// 	return false
 	// }
-	// if indicators.BoxFilter[2] > cfg.BoxFilter.Threshold {
+	// if ago(indicators.BoxFilter, 0) > cfg.BoxFilter.Threshold {
 	// 	return false
 	// }
-	// 기존 ADX 조건
-	condition1 := indicators.ADX[2] > cfg.ADXThreshold
-	// ADX 증가 조건
-	condition2 := indicators.ADX[2] > indicators.ADX[1]
-	stopCondition := indicators.PlusDI[2] > indicators.MinusDI[2]
-
-	if !condition1 || !condition2 {
-		// fmt.Printf("[%s 진입 거절] ADX가 작거나 증가하지 않음\n", symbol)
+	adx, prevADX := ago(indicators.ADX, 0), ago(indicators.ADX, 1)
+	plusDI, minusDI := ago(indicators.PlusDI, 0), ago(indicators.MinusDI, 0)
+
+	stopCondition := plusDI > minusDI
+
+	// 기존 ADX 조건, ADX 증가 조건
+	if !indicators.Check("adx_above_threshold", adx, p.ADXThreshold, adx > p.ADXThreshold) ||
+		!indicators.Check("adx_rising", adx, prevADX, adx > prevADX) {
 		return false, stopCondition
 	}
 
-	// DX 필터 추가
-	dx := math.Abs(indicators.MinusDI[2] - indicators.PlusDI[2])
-	if dx < cfg.ADXThreshold {
-		// fmt.Printf("[%s 진입 거절] DX %.2f < %.2f (방향성 약함)\n", symbol, dx, dmiParam.DXThreshold)
+	// DX 필터 추가: 방향성 약함
+	dx := math.Abs(minusDI - plusDI)
+	if !indicators.Check("di_spread", dx, p.DXThreshold, dx >= p.DXThreshold) {
 		return false, stopCondition
 	}
 
 	// DI 방향 및 DI 증가 조건
-	var condition3, diIncrease bool
-	condition3 = indicators.PlusDI[2] < indicators.MinusDI[2]
-	diIncrease = indicators.MinusDI[2] > indicators.MinusDI[1]
-
-	if !condition3 {
-		// fmt.Printf("[%s 진입 거절] 추세 방향 반대 side: %s PlusDI: %.2f, MinusDI: %.2f\n",
+	if !indicators.Check("minus_di_above_plus_di", minusDI, plusDI, plusDI < minusDI) {
 		return false, stopCondition
 	}
-
-	if !diIncrease {
-		// fmt.Printf("[%s 진입 거절] 진입 방향 DI가 증가하지 않음\n", symbol)
+	prevMinusDI := ago(indicators.MinusDI, 1)
+	if !indicators.Check("minus_di_rising", minusDI, prevMinusDI, minusDI > prevMinusDI) {
 		return false, stopCondition
 	}
 	return true, stopCondition
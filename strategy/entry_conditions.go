@@ -1,60 +1,138 @@
 package strategy
 
-import "go-backtesting/config"
+import (
+	"go-backtesting/config"
+	"math"
+)
 
 // EntryCondition defines the signature for a function that checks for a trading signal.
+// It returns whether to enter and whether to stop an open trade in its direction. Conditions
+// that take parameters are built from their parameter block (see GetEntryCondition), so they
+// never read the configuration while running.
 type EntryCondition func(indicators TechnicalIndicators) (bool, bool)
 
-// DetermineEntrySignal determines the entry signal based on the indicators.
+// DetermineEntrySignal determines the entry signal based on the indicators. Regime gates are
+// part of the conditions (see ResolveEntryCondition). Only the entry flags pick a direction;
+// stops close open trades through the exits (see ResolveExits). When both directions enter,
+// config.ConflictPolicy decides: "long_first" (the default) takes the long entry,
+// "short_first" the short one and "skip" neither. The stop returned is that of the direction
+// entered in.
 func DetermineEntrySignal(indicators TechnicalIndicators, config *config.Config, longCondition EntryCondition, shortCondition EntryCondition) (string, bool, bool) {
-	adx := last(indicators.ADX)
-	if adx > config.ADXThreshold &&
-		adx < config.AdxUpperThreshold {
-		// indicators.ADX[0] < indicators.ADX[1] &&
-		// indicators.ADX[1] < indicators.ADX[2] {
-		if entry, stop := longCondition(indicators); entry || stop {
-			return "long", entry, stop
-		}
-		if entry, stop := shortCondition(indicators); entry || stop {
-			return "short", entry, stop
+	return applyConflictPolicy(config.ConflictPolicy,
+		func() (bool, bool) { return longCondition(indicators) },
+		func() (bool, bool) { return shortCondition(indicators) })
+}
+
+// applyConflictPolicy picks the signal of DetermineEntrySignal from the long and short
+// decisions, asking for each only when the policy needs it.
+func applyConflictPolicy(policy string, long, short func() (bool, bool)) (string, bool, bool) {
+	first, second := long, short
+	firstDirection, secondDirection := "long", "short"
+	if policy == "short_first" {
+		first, second = second, first
+		firstDirection, secondDirection = secondDirection, firstDirection
+	}
+
+	if entry, stop := first(); entry {
+		if policy == "skip" {
+			if otherEntry, _ := second(); otherEntry {
+				return "", false, false
+			}
 		}
+		return firstDirection, true, stop
+	}
+	if entry, stop := second(); entry {
+		return secondDirection, true, stop
 	}
 	return "", false, false
 }
 
+// conflictPolicies are the accepted values of config.ConflictPolicy.
+var conflictPolicies = map[string]bool{"": true, "long_first": true, "short_first": true, "skip": true}
+
 // DefaultLongCondition provides the default logic for a long entry signal.
 func DefaultLongCondition(indicators TechnicalIndicators) (bool, bool) {
-	entry := last(indicators.EmaShort) > last(indicators.EmaLong) && last(indicators.ZScore) < 0.0
-	return entry, false
+	return defaultLong(indicators, config.DefaultConditionParams{})
 }
 
 // DefaultShortCondition provides the default logic for a short entry signal.
 func DefaultShortCondition(indicators TechnicalIndicators) (bool, bool) {
-	entry := last(indicators.EmaShort) < last(indicators.EmaLong) && last(indicators.ZScore) > 0.0
+	return defaultShort(indicators, config.DefaultConditionParams{})
+}
+
+// NewDefaultLongCondition returns the default long condition with the z-score level p.ZScoreLevel.
+func NewDefaultLongCondition(p config.DefaultConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return defaultLong(indicators, p)
+	}
+}
+
+// NewDefaultShortCondition returns the default short condition with the z-score level p.ZScoreLevel.
+func NewDefaultShortCondition(p config.DefaultConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return defaultShort(indicators, p)
+	}
+}
+
+func defaultLong(indicators TechnicalIndicators, p config.DefaultConditionParams) (bool, bool) {
+	emaShort, emaLong, zscore := last(indicators.EmaShort), last(indicators.EmaLong), last(indicators.ZScore)
+	entry := indicators.Check("ema_short_above_long", emaShort, emaLong, emaShort > emaLong) &&
+		indicators.Check("zscore_below_level", zscore, p.ZScoreLevel, zscore < p.ZScoreLevel)
+	return entry, false
+}
+
+func defaultShort(indicators TechnicalIndicators, p config.DefaultConditionParams) (bool, bool) {
+	emaShort, emaLong, zscore := last(indicators.EmaShort), last(indicators.EmaLong), last(indicators.ZScore)
+	entry := indicators.Check("ema_short_below_long", emaShort, emaLong, emaShort < emaLong) &&
+		indicators.Check("zscore_above_level", zscore, p.ZScoreLevel, zscore > p.ZScoreLevel)
 	return entry, false
 }
 
 // MACDLongCondition checks for a bullish MACD crossover.
 func MACDLongCondition(indicators TechnicalIndicators) (bool, bool) {
-	// A bullish crossover occurs when the MACD histogram crosses from negative to positive.
+	return macdLong(indicators, config.MACDConditionParams{})
+}
+
+// MACDShortCondition checks for a bearish MACD crossover.
+func MACDShortCondition(indicators TechnicalIndicators) (bool, bool) {
+	return macdShort(indicators, config.MACDConditionParams{})
+}
+
+// NewMACDLongCondition checks for the MACD histogram crossing above p.HistogramLevel.
+func NewMACDLongCondition(p config.MACDConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return macdLong(indicators, p)
+	}
+}
+
+// NewMACDShortCondition checks for the MACD histogram crossing below p.HistogramLevel.
+func NewMACDShortCondition(p config.MACDConditionParams) EntryCondition {
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		return macdShort(indicators, p)
+	}
+}
+
+func macdLong(indicators TechnicalIndicators, p config.MACDConditionParams) (bool, bool) {
+	// A bullish crossover occurs when the MACD histogram crosses from below the level to above it.
 	if len(indicators.MACDHistogram) < 2 {
 		return false, false
 	}
 	prev := indicators.MACDHistogram[len(indicators.MACDHistogram)-2]
 	curr := indicators.MACDHistogram[len(indicators.MACDHistogram)-1]
-	entry := prev < 0 && curr > 0
+	entry := indicators.Check("histogram_was_below_level", prev, p.HistogramLevel, prev < p.HistogramLevel) &&
+		indicators.Check("histogram_above_level", curr, p.HistogramLevel, curr > p.HistogramLevel)
 	return entry, false
 }
 
-// MACDShortCondition checks for a bearish MACD crossover.
-func MACDShortCondition(indicators TechnicalIndicators) (bool, bool) {
-	// A bearish crossover occurs when the MACD histogram crosses from positive to negative.
+func macdShort(indicators TechnicalIndicators, p config.MACDConditionParams) (bool, bool) {
+	// A bearish crossover occurs when the MACD histogram crosses from above the level to below it.
 	if len(indicators.MACDHistogram) < 2 {
 		return false, false
 	}
 	prev := indicators.MACDHistogram[len(indicators.MACDHistogram)-2]
 	curr := indicators.MACDHistogram[len(indicators.MACDHistogram)-1]
-	entry := prev > 0 && curr < 0
+	entry := indicators.Check("histogram_was_above_level", prev, p.HistogramLevel, prev > p.HistogramLevel) &&
+		indicators.Check("histogram_below_level", curr, p.HistogramLevel, curr < p.HistogramLevel)
 	return entry, false
 }
 
@@ -65,3 +143,13 @@ func last(data []float64) float64 {
 	}
 	return data[len(data)-1]
 }
+
+// ago returns the value barsAgo positions before the last element of a window
+// (0 is the last), or NaN when the window does not reach back that far.
+func ago(data []float64, barsAgo int) float64 {
+	i := len(data) - 1 - barsAgo
+	if barsAgo < 0 || i < 0 {
+		return math.NaN()
+	}
+	return data[i]
+}
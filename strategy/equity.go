@@ -0,0 +1,23 @@
+package strategy
+
+import "time"
+
+// EquityPoint is the equity after a closed trade: the cumulative PnL, in price points, and
+// the drawdown from the highest equity reached so far.
+type EquityPoint struct {
+	Time     time.Time
+	Equity   float64
+	Drawdown float64
+}
+
+// EquityCurve returns the equity after each trade, in the order the trades were closed.
+func EquityCurve(trades []Trade) []EquityPoint {
+	curve := make([]EquityPoint, len(trades))
+	equity, peak := 0.0, 0.0
+	for k, t := range trades {
+		equity += t.Pnl
+		peak = max(peak, equity)
+		curve[k] = EquityPoint{Time: t.ExitTime, Equity: equity, Drawdown: peak - equity}
+	}
+	return curve
+}
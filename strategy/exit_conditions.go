@@ -0,0 +1,145 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+)
+
+// OpenTrade is what an exit condition knows about the trade it may close.
+type OpenTrade struct {
+	Direction  string // "long" or "short"
+	EntryPrice float64
+	EntryIndex int
+	// BarsHeld is the number of candles since entry (0 on the entry candle).
+	BarsHeld int
+	// Return is the unrealised return at the current close, and MaxFavorable and MaxAdverse
+	// the largest moves for and against the trade since entry, all as fractions of the entry price.
+	Return       float64
+	MaxFavorable float64
+	MaxAdverse   float64
+}
+
+// newOpenTrade starts tracking a trade entered at the close of candle i.
+func newOpenTrade(direction string, entryPrice float64, i int) OpenTrade {
+	return OpenTrade{Direction: direction, EntryPrice: entryPrice, EntryIndex: i}
+}
+
+// update moves the trade to candle i, extending its excursions by the candle's range.
+func (t *OpenTrade) update(i int, c market.Candle) {
+	t.BarsHeld = i - t.EntryIndex
+	favorable, adverse := c.High/t.EntryPrice-1, 1-c.Low/t.EntryPrice
+	t.Return = c.Close/t.EntryPrice - 1
+	if t.Direction == "short" {
+		favorable, adverse = 1-c.Low/t.EntryPrice, c.High/t.EntryPrice-1
+		t.Return = -t.Return
+	}
+	t.MaxFavorable = math.Max(t.MaxFavorable, favorable)
+	t.MaxAdverse = math.Max(t.MaxAdverse, adverse)
+}
+
+// ExitCondition reports whether the open trade should be closed at the current candle.
+type ExitCondition func(indicators TechnicalIndicators, trade OpenTrade) bool
+
+// Exit is a named exit condition; the name is recorded as the trade's exit reason.
+type Exit struct {
+	Name      string
+	Condition ExitCondition
+}
+
+// Exits holds the exit conditions for each direction, checked in order.
+type Exits struct {
+	Long  []Exit
+	Short []Exit
+}
+
+// For returns the exits for a trade direction.
+func (e Exits) For(direction string) []Exit {
+	if direction == "short" {
+		return e.Short
+	}
+	return e.Long
+}
+
+// DICrossExit closes a trade on the candle the opposite DI crosses above the trade's DI.
+func DICrossExit(indicators TechnicalIndicators, trade OpenTrade) bool {
+	own, opposite := indicators.PlusDI, indicators.MinusDI
+	if trade.Direction == "short" {
+		own, opposite = opposite, own
+	}
+	return ago(own, 1) >= ago(opposite, 1) && ago(own, 0) < ago(opposite, 0)
+}
+
+// MACDFlipExit closes a trade on the candle the MACD histogram crosses zero against it.
+func MACDFlipExit(indicators TechnicalIndicators, trade OpenTrade) bool {
+	prev, curr := ago(indicators.MACDHistogram, 1), ago(indicators.MACDHistogram, 0)
+	if trade.Direction == "short" {
+		return prev < 0 && curr >= 0
+	}
+	return prev > 0 && curr <= 0
+}
+
+// NewADXFallingExit closes a trade once ADX has fallen on each of the last p.Bars candles.
+func NewADXFallingExit(p config.ADXFallingExitParams) ExitCondition {
+	return func(indicators TechnicalIndicators, trade OpenTrade) bool {
+		for k := 0; k < p.Bars; k++ {
+			curr, _ := indicators.Ago(SeriesADX, k)
+			prev, _ := indicators.Ago(SeriesADX, k+1)
+			if !(curr < prev) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// NewVWZReversionExit closes a long trade once the VWZ score has come back up to -p.Band or
+// above, and a short trade once it has come back down to p.Band or below.
+func NewVWZReversionExit(p config.VWZReversionExitParams) ExitCondition {
+	return func(indicators TechnicalIndicators, trade OpenTrade) bool {
+		vwz := ago(indicators.VWZScore, 0)
+		if trade.Direction == "short" {
+			return vwz <= p.Band
+		}
+		return vwz >= -p.Band
+	}
+}
+
+// NewMaxBarsExit closes a trade that has been held for p.Bars candles.
+func NewMaxBarsExit(p config.MaxBarsExitParams) ExitCondition {
+	return func(indicators TechnicalIndicators, trade OpenTrade) bool {
+		return trade.BarsHeld >= p.Bars
+	}
+}
+
+// NewTrailingExit closes a trade once it has given back p.Giveback of the entry price from
+// its best excursion, measured at the close.
+func NewTrailingExit(p config.TrailingExitParams) ExitCondition {
+	return func(indicators TechnicalIndicators, trade OpenTrade) bool {
+		return trade.MaxFavorable-trade.Return >= p.Giveback
+	}
+}
+
+// signalExits returns the exits driven by the entry conditions: "signal_stop" closes a trade
+// when its own direction's condition raises the stop flag and "reverse_signal" when the
+// opposite direction's condition signals an entry. Neither goes through the conflict policy,
+// which only settles which direction a new trade enters in.
+func signalExits(longCondition, shortCondition EntryCondition) (stop, reverse ExitCondition) {
+	conditions := func(direction string) (own, opposite EntryCondition) {
+		if direction == "short" {
+			return shortCondition, longCondition
+		}
+		return longCondition, shortCondition
+	}
+	stop = func(indicators TechnicalIndicators, trade OpenTrade) bool {
+		own, _ := conditions(trade.Direction)
+		_, stop := own(indicators)
+		return stop
+	}
+	reverse = func(indicators TechnicalIndicators, trade OpenTrade) bool {
+		_, opposite := conditions(trade.Direction)
+		entry, _ := opposite(indicators)
+		return entry
+	}
+	return stop, reverse
+}
@@ -0,0 +1,197 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestOpenTradeTracksExcursion(t *testing.T) {
+	trade := newOpenTrade("short", 100, 10)
+	trade.update(11, market.Candle{High: 102, Low: 97, Close: 98})
+	trade.update(12, market.Candle{High: 101, Low: 99, Close: 101})
+
+	if trade.BarsHeld != 2 {
+		t.Errorf("Expected 2 bars held, but got %d", trade.BarsHeld)
+	}
+	if math.Abs(trade.Return+0.01) > 1e-12 || math.Abs(trade.MaxFavorable-0.03) > 1e-12 || math.Abs(trade.MaxAdverse-0.02) > 1e-12 {
+		t.Errorf("Expected return -1%%, best +3%% and worst -2%%, but got %+v", trade)
+	}
+	if !NewTrailingExit(config.TrailingExitParams{Giveback: 0.04})(TechnicalIndicators{}, trade) {
+		t.Error("Expected the trailing exit to close a trade that gave back 4% from its best")
+	}
+	if NewMaxBarsExit(config.MaxBarsExitParams{Bars: 3})(TechnicalIndicators{}, trade) {
+		t.Error("Expected max_bars(3) to keep a trade held for 2 bars")
+	}
+}
+
+func TestIndicatorExits(t *testing.T) {
+	long, short := OpenTrade{Direction: "long"}, OpenTrade{Direction: "short"}
+	crossDown := TechnicalIndicators{PlusDI: []float64{25, 20}, MinusDI: []float64{20, 22}}
+	if !DICrossExit(crossDown, long) || DICrossExit(crossDown, short) {
+		t.Error("Expected a -DI cross above +DI to close longs only")
+	}
+	flipUp := TechnicalIndicators{MACDHistogram: []float64{-0.5, 0.2}}
+	if MACDFlipExit(flipUp, long) || !MACDFlipExit(flipUp, short) {
+		t.Error("Expected the histogram turning positive to close shorts only")
+	}
+	reverting := TechnicalIndicators{VWZScore: []float64{-0.05}}
+	exit := NewVWZReversionExit(config.VWZReversionExitParams{Band: 0.1})
+	if !exit(reverting, long) || !exit(reverting, short) {
+		t.Error("Expected VWZ within the band of zero to close both directions")
+	}
+	if exit(TechnicalIndicators{VWZScore: []float64{-1}}, long) {
+		t.Error("Expected VWZ at -1 to keep a long open")
+	}
+}
+
+func TestResolveExits(t *testing.T) {
+	cfg := &config.Config{}
+	exits, err := ResolveExits(cfg, DefaultLongCondition, DefaultShortCondition)
+	if err != nil {
+		t.Fatalf("ResolveExits failed: %v", err)
+	}
+	if len(exits.Long) != 2 || exits.Long[0].Name != "signal_stop" || exits.Short[1].Name != "reverse_signal" {
+		t.Errorf("Expected the signal exits by default, but got %+v", exits)
+	}
+
+	cfg.LongExits = []string{"di_cross", "adx_falling"}
+	cfg.ShortExits = []string{}
+	if exits, err = ResolveExits(cfg, DefaultLongCondition, DefaultShortCondition); err != nil {
+		t.Fatalf("ResolveExits failed: %v", err)
+	}
+	if len(exits.For("long")) != 2 || len(exits.For("short")) != 0 {
+		t.Errorf("Expected two long exits and no short exits, but got %+v", exits)
+	}
+
+	for names, want := range map[string]string{
+		"missing":  "no exit condition found",
+		"max_bars": "bars must be positive",
+		"trailing": "giveback must be positive",
+	} {
+		cfg.ShortExits = []string{names}
+		if _, err := ResolveExits(cfg, DefaultLongCondition, DefaultShortCondition); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %s to fail with %q, but got %v", names, want, err)
+		}
+	}
+}
+
+func TestSignalExitsIgnoreConflictPolicy(t *testing.T) {
+	stopping := func(TechnicalIndicators) (bool, bool) { return false, true }
+	entering := func(TechnicalIndicators) (bool, bool) { return true, false }
+	idle := func(TechnicalIndicators) (bool, bool) { return false, false }
+	cfg := &config.Config{ConflictPolicy: "short_first"}
+
+	// A short entry used to take precedence and hide the long trade's own stop.
+	exits, err := ResolveExits(cfg, stopping, entering)
+	if err != nil {
+		t.Fatalf("ResolveExits failed: %v", err)
+	}
+	long := OpenTrade{Direction: "long"}
+	if !exits.Long[0].Condition(TechnicalIndicators{}, long) {
+		t.Error("Expected signal_stop to see the long condition's stop under short_first")
+	}
+	if !exits.Long[1].Condition(TechnicalIndicators{}, long) {
+		t.Error("Expected reverse_signal to close a long on a short entry")
+	}
+
+	// Under skip both directions entering cancel each other, but a short still reverses a long.
+	cfg.ConflictPolicy = "skip"
+	if exits, err = ResolveExits(cfg, entering, entering); err != nil {
+		t.Fatalf("ResolveExits failed: %v", err)
+	}
+	if !exits.Long[1].Condition(TechnicalIndicators{}, long) {
+		t.Error("Expected reverse_signal to look at the short condition alone")
+	}
+	if exits, err = ResolveExits(cfg, idle, stopping); err != nil {
+		t.Fatalf("ResolveExits failed: %v", err)
+	}
+	if exits.Long[0].Condition(TechnicalIndicators{}, long) || !exits.Short[0].Condition(TechnicalIndicators{}, OpenTrade{Direction: "short"}) {
+		t.Error("Expected signal_stop to follow only the open trade's own condition")
+	}
+}
+
+func TestRunBacktestRecordsExitReason(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:          "test_data.csv",
+		VWZPeriod:         5,
+		EmaPeriod:         5,
+		ADXPeriod:         5,
+		AdxUpperThreshold: 100,
+		TPRate:            1,
+		SLRate:            1,
+		BBWPeriod:         20,
+		BBWMultiplier:     2.0,
+		LongExits:         []string{"max_bars"},
+		ShortExits:        []string{"max_bars"},
+		ExitParams:        config.ExitParams{MaxBars: config.MaxBarsExitParams{Bars: 3}},
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+	exits, err := ResolveExits(cfg, DefaultLongCondition, DefaultShortCondition)
+	if err != nil {
+		t.Fatalf("ResolveExits failed: %v", err)
+	}
+
+	result := RunBacktest(strategyData, cfg, DefaultLongCondition, DefaultShortCondition, exits)
+	if result.TotalTrades == 0 {
+		t.Fatal("Expected the default conditions to trade on the test data")
+	}
+	interval := strategyData.Candles[1].Time.Sub(strategyData.Candles[0].Time)
+	for _, trade := range result.Trades {
+		if trade.ExitReason != "max_bars" || trade.ExitTime.Sub(trade.EntryTime) != 3*interval {
+			t.Errorf("Expected every trade closed by max_bars after 3 candles, but got %s after %v", trade.ExitReason, trade.ExitTime.Sub(trade.EntryTime))
+		}
+	}
+}
+
+func TestRunBacktestStopsLongsOnTheLow(t *testing.T) {
+	// Flat candles at 100, then one that dips to 99.5 and closes back at 100.1 without its
+	// high ever reaching the stop-loss level.
+	csv := "Timestamp,Open,High,Low,Close,Volume\n"
+	start := time.Date(2025, 9, 1, 0, 0, 0, 0, time.UTC)
+	for k := range 30 {
+		low, close := 99.9, 100.0
+		if k == 16 {
+			low, close = 99.5, 100.1
+		}
+		csv += fmt.Sprintf("%s,100,100.2,%g,%g,1\n", start.Add(time.Duration(k)*5*time.Minute).Format("2006-01-02 15:04:05"), low, close)
+	}
+	path := filepath.Join(t.TempDir(), "dip.csv")
+	if err := os.WriteFile(path, []byte(csv), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	cfg := &config.Config{
+		FilePath:      path,
+		VWZPeriod:     5,
+		EmaPeriod:     5,
+		ADXPeriod:     5,
+		TPRate:        1,
+		SLRate:        0.002,
+		BBWPeriod:     5,
+		BBWMultiplier: 2.0,
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+	alwaysLong := func(TechnicalIndicators) (bool, bool) { return true, false }
+	never := func(TechnicalIndicators) (bool, bool) { return false, false }
+
+	result := RunBacktest(strategyData, cfg, alwaysLong, never, Exits{})
+	if result.TotalTrades == 0 {
+		t.Fatal("Expected an always-long condition to trade")
+	}
+	trade := result.Trades[0]
+	if trade.ExitReason != ExitStopLoss || !trade.ExitTime.Equal(strategyData.Candles[16].Time) {
+		t.Errorf("Expected the first long stopped out on the candle whose low reached the stop-loss, but got %s at %v", trade.ExitReason, trade.ExitTime)
+	}
+}
@@ -0,0 +1,99 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+)
+
+// exitFactory builds a registered exit condition from the configuration, applying the
+// defaults of its parameter block and rejecting invalid parameters.
+type exitFactory func(cfg *config.Config) (ExitCondition, error)
+
+// exitConditions holds the registry for indicator-based exit conditions. Every exit applies
+// to both directions; "signal_stop" and "reverse_signal" are resolved separately because
+// they depend on the entry conditions.
+var exitConditions = map[string]exitFactory{
+	"di_cross": func(cfg *config.Config) (ExitCondition, error) {
+		return DICrossExit, nil
+	},
+	"macd_flip": func(cfg *config.Config) (ExitCondition, error) {
+		return MACDFlipExit, nil
+	},
+	"adx_falling": func(cfg *config.Config) (ExitCondition, error) {
+		p := cfg.ExitParams.ADXFalling
+		if p.Bars == 0 {
+			p.Bars = 1
+		}
+		if p.Bars < 0 {
+			return nil, fmt.Errorf("bars must be positive, got %d", p.Bars)
+		}
+		return NewADXFallingExit(p), nil
+	},
+	"vwz_reversion": func(cfg *config.Config) (ExitCondition, error) {
+		p := cfg.ExitParams.VWZReversion
+		if p.Band < 0 {
+			return nil, fmt.Errorf("band must not be negative, got %v", p.Band)
+		}
+		return NewVWZReversionExit(p), nil
+	},
+	"max_bars": func(cfg *config.Config) (ExitCondition, error) {
+		p := cfg.ExitParams.MaxBars
+		if p.Bars <= 0 {
+			return nil, fmt.Errorf("bars must be positive, got %d", p.Bars)
+		}
+		return NewMaxBarsExit(p), nil
+	},
+	"trailing": func(cfg *config.Config) (ExitCondition, error) {
+		p := cfg.ExitParams.Trailing
+		if p.Giveback <= 0 {
+			return nil, fmt.Errorf("giveback must be positive, got %v", p.Giveback)
+		}
+		return NewTrailingExit(p), nil
+	},
+}
+
+// defaultExits reproduces the exits used before exit conditions were configurable.
+var defaultExits = []string{"signal_stop", "reverse_signal"}
+
+// ResolveExits builds the configured exit conditions for both directions. The entry
+// conditions are needed by the "signal_stop" and "reverse_signal" exits.
+func ResolveExits(cfg *config.Config, longCondition, shortCondition EntryCondition) (Exits, error) {
+	long, err := resolveExits(cfg, "long", cfg.LongExits, longCondition, shortCondition)
+	if err != nil {
+		return Exits{}, err
+	}
+	short, err := resolveExits(cfg, "short", cfg.ShortExits, longCondition, shortCondition)
+	if err != nil {
+		return Exits{}, err
+	}
+	return Exits{Long: long, Short: short}, nil
+}
+
+func resolveExits(cfg *config.Config, direction string, names []string, longCondition, shortCondition EntryCondition) ([]Exit, error) {
+	if names == nil {
+		names = defaultExits
+	}
+	stop, reverse := signalExits(longCondition, shortCondition)
+
+	exits := make([]Exit, 0, len(names))
+	for _, name := range names {
+		var condition ExitCondition
+		switch name {
+		case "signal_stop":
+			condition = stop
+		case "reverse_signal":
+			condition = reverse
+		default:
+			factory, ok := exitConditions[name]
+			if !ok {
+				return nil, fmt.Errorf("no exit condition found for name: %s", name)
+			}
+			var err error
+			if condition, err = factory(cfg); err != nil {
+				return nil, fmt.Errorf("invalid parameters for %s exit condition %s: %w", direction, name, err)
+			}
+		}
+		exits = append(exits, Exit{Name: name, Condition: condition})
+	}
+	return exits, nil
+}
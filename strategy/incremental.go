@@ -0,0 +1,632 @@
+package strategy
+
+import (
+	"go-backtesting/market"
+	"math"
+)
+
+// Incremental indicators consume one candle at a time and produce exactly the values of their
+// batch counterparts at the same index, so a live feed and a backtest share one implementation:
+// the indicator registry computes its ema, sma, zscore, vwz, bbands, bbwz, dmi, macd and atr
+// series by streaming the candles through them (see streamSeries).
+// They follow talib's conventions: outputs are 0 until the lookback is filled (NaN for the
+// z-scores, matching ZScores and VWZScores), and Ready reports when the value is meaningful.
+// The arithmetic mirrors talib operation by operation; reordering it would change the results
+// in the last bits and break the equivalence with the batch functions.
+
+// talibEpsilon is the threshold talib uses to treat a denominator as zero.
+const talibEpsilon = 0.00000000000001
+
+func nearZero(x float64) bool {
+	return -talibEpsilon < x && x < talibEpsilon
+}
+
+// ring holds the most recent values of a fixed-size window.
+type ring struct {
+	buf   []float64
+	next  int
+	count int
+}
+
+func newRing(size int) ring {
+	return ring{buf: make([]float64, size)}
+}
+
+func (r *ring) push(x float64) {
+	r.buf[r.next] = x
+	r.next = (r.next + 1) % len(r.buf)
+	if r.count < len(r.buf) {
+		r.count++
+	}
+}
+
+func (r *ring) full() bool {
+	return r.count == len(r.buf)
+}
+
+// at returns the k-th value of the window in chronological order.
+func (r *ring) at(k int) float64 {
+	start := 0
+	if r.full() {
+		start = r.next
+	}
+	return r.buf[(start+k)%len(r.buf)]
+}
+
+// appendTo appends the window in chronological order.
+func (r *ring) appendTo(dst []float64) []float64 {
+	for k := 0; k < r.count; k++ {
+		dst = append(dst, r.at(k))
+	}
+	return dst
+}
+
+// IncrementalEMA matches talib.Ema: seeded with the simple average of the first period values.
+type IncrementalEMA struct {
+	period int
+	k      float64
+	n      int
+	sum    float64
+	value  float64
+}
+
+// NewIncrementalEMA returns an EMA over period values.
+func NewIncrementalEMA(period int) *IncrementalEMA {
+	return &IncrementalEMA{period: period, k: 2.0 / float64(period+1)}
+}
+
+// Update feeds the next candle's close and returns the EMA.
+func (e *IncrementalEMA) Update(c market.Candle) float64 {
+	return e.UpdateValue(c.Close)
+}
+
+// UpdateValue feeds a raw value, for EMAs of derived series such as the MACD signal line.
+func (e *IncrementalEMA) UpdateValue(x float64) float64 {
+	e.n++
+	switch {
+	case e.n < e.period:
+		e.sum += x
+		return 0
+	case e.n == e.period:
+		e.sum += x
+		e.value = e.sum / float64(e.period)
+	default:
+		e.value = ((x - e.value) * e.k) + e.value
+	}
+	return e.value
+}
+
+// Value returns the current EMA.
+func (e *IncrementalEMA) Value() float64 { return e.value }
+
+// Ready reports whether period values have been fed.
+func (e *IncrementalEMA) Ready() bool { return e.n >= e.period }
+
+// IncrementalSMA matches talib.Sma, including its running total.
+type IncrementalSMA struct {
+	window ring
+	total  float64
+	value  float64
+}
+
+// NewIncrementalSMA returns a simple moving average over period values.
+func NewIncrementalSMA(period int) *IncrementalSMA {
+	return &IncrementalSMA{window: newRing(period)}
+}
+
+// Update feeds the next candle's close and returns the average.
+func (s *IncrementalSMA) Update(c market.Candle) float64 {
+	return s.UpdateValue(c.Close)
+}
+
+// UpdateValue feeds a raw value and returns the average.
+func (s *IncrementalSMA) UpdateValue(x float64) float64 {
+	s.window.push(x)
+	s.total += x
+	if !s.window.full() {
+		return 0
+	}
+	s.value = s.total / float64(len(s.window.buf))
+	s.total -= s.window.at(0)
+	return s.value
+}
+
+// Value returns the current average.
+func (s *IncrementalSMA) Value() float64 { return s.value }
+
+// Ready reports whether the window is filled.
+func (s *IncrementalSMA) Ready() bool { return s.window.full() }
+
+// IncrementalStdDev matches talib.StdDev: the population standard deviation over period
+// values, scaled by nbDev, and 0 when the variance is below talib's epsilon.
+type IncrementalStdDev struct {
+	window ring
+	nbDev  float64
+	total1 float64
+	total2 float64
+	value  float64
+}
+
+// NewIncrementalStdDev returns the standard deviation over period values, scaled by nbDev.
+func NewIncrementalStdDev(period int, nbDev float64) *IncrementalStdDev {
+	return &IncrementalStdDev{window: newRing(period), nbDev: nbDev}
+}
+
+// Update feeds the next candle's close and returns the scaled deviation.
+func (s *IncrementalStdDev) Update(c market.Candle) float64 {
+	return s.UpdateValue(c.Close)
+}
+
+// UpdateValue feeds a raw value and returns the scaled deviation.
+func (s *IncrementalStdDev) UpdateValue(x float64) float64 {
+	s.window.push(x)
+	s.total1 += x
+	s.total2 += x * x
+	if !s.window.full() {
+		return 0
+	}
+	period := float64(len(s.window.buf))
+	mean1 := s.total1 / period
+	mean2 := s.total2 / period
+	trailing := s.window.at(0)
+	s.total1 -= trailing
+	s.total2 -= trailing * trailing
+
+	variance := mean2 - mean1*mean1
+	if !(variance < talibEpsilon) {
+		s.value = math.Sqrt(variance) * s.nbDev
+	} else {
+		s.value = 0
+	}
+	return s.value
+}
+
+// Value returns the current scaled deviation.
+func (s *IncrementalStdDev) Value() float64 { return s.value }
+
+// Ready reports whether the window is filled.
+func (s *IncrementalStdDev) Ready() bool { return s.window.full() }
+
+// emaMA matches talib.Ma with talib.EMA, which passes the input through for a period of 1.
+type emaMA struct {
+	ema *IncrementalEMA
+}
+
+func newEmaMA(period int) emaMA {
+	if period == 1 {
+		return emaMA{}
+	}
+	return emaMA{ema: NewIncrementalEMA(period)}
+}
+
+func (m emaMA) update(x float64) float64 {
+	if m.ema == nil {
+		return x
+	}
+	return m.ema.UpdateValue(x)
+}
+
+// BBandsValue is one bar of Bollinger Bands; Width is (upper-lower)/middle as in BBW.
+type BBandsValue struct {
+	Upper  float64
+	Middle float64
+	Lower  float64
+	Width  float64
+}
+
+// IncrementalBBands matches BBW: talib.BBands with an EMA middle band and equal deviations.
+type IncrementalBBands struct {
+	middle     emaMA
+	std        *IncrementalStdDev
+	multiplier float64
+	value      BBandsValue
+}
+
+// NewIncrementalBBands returns Bollinger Bands over period values, multiplier deviations wide.
+func NewIncrementalBBands(period int, multiplier float64) *IncrementalBBands {
+	return &IncrementalBBands{
+		middle:     newEmaMA(period),
+		std:        NewIncrementalStdDev(period, 1.0),
+		multiplier: multiplier,
+	}
+}
+
+// Update feeds the next candle and returns its bands.
+func (b *IncrementalBBands) Update(c market.Candle) BBandsValue {
+	middle := b.middle.update(c.Close)
+	deviation := b.std.UpdateValue(c.Close) * b.multiplier
+
+	v := BBandsValue{Upper: middle + deviation, Middle: middle, Lower: middle - deviation}
+	if middle != 0 {
+		v.Width = (v.Upper - v.Lower) / middle
+	}
+	b.value = v
+	return v
+}
+
+// Value returns the current bands.
+func (b *IncrementalBBands) Value() BBandsValue { return b.value }
+
+// Ready reports whether the deviation window is filled.
+func (b *IncrementalBBands) Ready() bool { return b.std.Ready() }
+
+// IncrementalATR matches talib.Atr: seeded with the mean true range of bars 1..period,
+// then smoothed with Wilder's method. The first bar has no true range.
+type IncrementalATR struct {
+	period    int
+	n         int
+	prevClose float64
+	sum       float64
+	value     float64
+}
+
+// NewIncrementalATR returns an average true range over period bars.
+func NewIncrementalATR(period int) *IncrementalATR {
+	return &IncrementalATR{period: period}
+}
+
+// Update feeds the next candle and returns the ATR.
+func (a *IncrementalATR) Update(c market.Candle) float64 {
+	i := a.n
+	a.n++
+	if i == 0 {
+		a.prevClose = c.Close
+		return 0
+	}
+	tr := trueRange(c, a.prevClose)
+	a.prevClose = c.Close
+
+	p := float64(a.period)
+	switch {
+	case a.period <= 1:
+		a.value = tr
+	case i < a.period:
+		a.sum += tr
+		return 0
+	case i == a.period:
+		a.sum += tr
+		a.value = a.sum / p
+	default:
+		a.value *= p - 1.0
+		a.value += tr
+		a.value /= p
+	}
+	return a.value
+}
+
+// Value returns the current ATR.
+func (a *IncrementalATR) Value() float64 { return a.value }
+
+// Ready reports whether period true ranges have been averaged.
+func (a *IncrementalATR) Ready() bool { return a.n > a.period }
+
+// trueRange matches talib.TRange for a bar and the previous close.
+func trueRange(c market.Candle, prevClose float64) float64 {
+	tr := c.High - c.Low
+	if v := math.Abs(prevClose - c.High); v > tr {
+		tr = v
+	}
+	if v := math.Abs(prevClose - c.Low); v > tr {
+		tr = v
+	}
+	return tr
+}
+
+// DMIValue is one bar of the directional movement system.
+type DMIValue struct {
+	ADX     float64
+	PlusDI  float64
+	MinusDI float64
+	DX      float64
+}
+
+// IncrementalDMI matches talib.Adx, talib.PlusDI, talib.MinusDI and talib.Dx for a period of
+// at least 2. The DIs and DX start at index period, the ADX at index 2*period-1.
+type IncrementalDMI struct {
+	period    int
+	n         int
+	prevHigh  float64
+	prevLow   float64
+	prevClose float64
+	plusDM    float64
+	minusDM   float64
+	tr        float64
+	sumDX     float64
+	value     DMIValue
+}
+
+// NewIncrementalDMI returns the directional movement system over period bars.
+func NewIncrementalDMI(period int) *IncrementalDMI {
+	return &IncrementalDMI{period: period}
+}
+
+// Update feeds the next candle and returns its ADX, DIs and DX.
+func (d *IncrementalDMI) Update(c market.Candle) DMIValue {
+	i := d.n
+	d.n++
+	if i == 0 {
+		d.prevHigh, d.prevLow, d.prevClose = c.High, c.Low, c.Close
+		return d.value
+	}
+
+	diffP := c.High - d.prevHigh
+	diffM := d.prevLow - c.Low
+	d.prevHigh, d.prevLow = c.High, c.Low
+	tr := trueRange(c, d.prevClose)
+	d.prevClose = c.Close
+
+	p := float64(d.period)
+	if i < d.period {
+		if diffM > 0 && diffP < diffM {
+			d.minusDM += diffM
+		} else if diffP > 0 && diffP > diffM {
+			d.plusDM += diffP
+		}
+		d.tr += tr
+		return d.value
+	}
+
+	d.minusDM -= d.minusDM / p
+	d.plusDM -= d.plusDM / p
+	if diffM > 0 && diffP < diffM {
+		d.minusDM += diffM
+	} else if diffP > 0 && diffP > diffM {
+		d.plusDM += diffP
+	}
+	d.tr = d.tr - (d.tr / p) + tr
+
+	// Each talib function handles a degenerate true range its own way: the DIs drop to 0,
+	// DX repeats its previous value and the ADX skips the bar.
+	dx, dxValid := 0.0, false
+	d.value.PlusDI, d.value.MinusDI = 0, 0
+	if !nearZero(d.tr) {
+		minusDI := 100.0 * (d.minusDM / d.tr)
+		plusDI := 100.0 * (d.plusDM / d.tr)
+		d.value.PlusDI, d.value.MinusDI = plusDI, minusDI
+		if sum := minusDI + plusDI; !nearZero(sum) {
+			dx, dxValid = 100.0*(math.Abs(minusDI-plusDI)/sum), true
+		}
+	}
+	if dxValid {
+		d.value.DX = dx
+	}
+
+	switch {
+	case i < 2*d.period-1:
+		if dxValid {
+			d.sumDX += dx
+		}
+	case i == 2*d.period-1:
+		if dxValid {
+			d.sumDX += dx
+		}
+		d.value.ADX = d.sumDX / p
+	default:
+		if dxValid {
+			d.value.ADX = ((d.value.ADX * (p - 1)) + dx) / p
+		}
+	}
+	return d.value
+}
+
+// Value returns the current ADX, DIs and DX.
+func (d *IncrementalDMI) Value() DMIValue { return d.value }
+
+// Ready reports whether the ADX is available; the DIs are available period-1 bars earlier.
+func (d *IncrementalDMI) Ready() bool { return d.n >= 2*d.period }
+
+// MACDValue is one bar of MACD.
+type MACDValue struct {
+	MACD      float64
+	Signal    float64
+	Histogram float64
+}
+
+// IncrementalMACD matches talib.Macd, including its signal line being seeded over the zero
+// MACD values that precede the slow EMA.
+type IncrementalMACD struct {
+	fast, slow, signal *IncrementalEMA
+	lookback           int
+	n                  int
+	value              MACDValue
+}
+
+// NewIncrementalMACD returns MACD with the given EMA periods; like talib, the longer of
+// fastPeriod and slowPeriod is used as the slow one.
+func NewIncrementalMACD(fastPeriod, slowPeriod, signalPeriod int) *IncrementalMACD {
+	if slowPeriod < fastPeriod {
+		fastPeriod, slowPeriod = slowPeriod, fastPeriod
+	}
+	return &IncrementalMACD{
+		fast:     NewIncrementalEMA(fastPeriod),
+		slow:     NewIncrementalEMA(slowPeriod),
+		signal:   NewIncrementalEMA(signalPeriod),
+		lookback: signalPeriod - 1 + slowPeriod - 1,
+	}
+}
+
+// Update feeds the next candle's close and returns the MACD, signal and histogram.
+func (m *IncrementalMACD) Update(c market.Candle) MACDValue {
+	i := m.n
+	m.n++
+	fast := m.fast.Update(c)
+	slow := m.slow.Update(c)
+
+	v := MACDValue{}
+	if i >= m.lookback-1 {
+		v.MACD = fast - slow
+	}
+	v.Signal = m.signal.UpdateValue(v.MACD)
+	if i >= m.lookback {
+		v.Histogram = v.MACD - v.Signal
+	}
+	m.value = v
+	return v
+}
+
+// Value returns the current MACD, signal and histogram.
+func (m *IncrementalMACD) Value() MACDValue { return m.value }
+
+// Ready reports whether the histogram is available.
+func (m *IncrementalMACD) Ready() bool { return m.n > m.lookback }
+
+// IncrementalZScore matches ZScores: the close relative to its EMA in units of the
+// standard deviation over period bars, NaN until both are available.
+type IncrementalZScore struct {
+	mean  emaMA
+	std   *IncrementalStdDev
+	value float64
+}
+
+// NewIncrementalZScore returns the z-score of the close over period bars.
+func NewIncrementalZScore(period int) *IncrementalZScore {
+	return &IncrementalZScore{mean: newEmaMA(period), std: NewIncrementalStdDev(period, 1.0), value: math.NaN()}
+}
+
+// Update feeds the next candle and returns its z-score.
+func (z *IncrementalZScore) Update(c market.Candle) float64 {
+	mean := z.mean.update(c.Close)
+	std := z.std.UpdateValue(c.Close)
+	if math.IsNaN(mean) || math.IsNaN(std) || std == 0 {
+		z.value = math.NaN()
+	} else {
+		z.value = (c.Close - mean) / std
+	}
+	return z.value
+}
+
+// Value returns the current z-score.
+func (z *IncrementalZScore) Value() float64 { return z.value }
+
+// Ready reports whether the current z-score is defined.
+func (z *IncrementalZScore) Ready() bool { return !math.IsNaN(z.value) }
+
+// IncrementalRollingZ matches NormalizeZ and NormalizeBBW: each value is scored against the
+// mean and sample standard deviation of the window values before it, and is 0 until the
+// window is filled.
+type IncrementalRollingZ struct {
+	window  ring
+	scratch []float64
+	value   float64
+}
+
+// NewIncrementalRollingZ returns a z-score against the previous window values.
+func NewIncrementalRollingZ(window int) *IncrementalRollingZ {
+	return &IncrementalRollingZ{window: newRing(window), scratch: make([]float64, 0, window)}
+}
+
+// Update feeds the next candle's close and returns its score.
+func (z *IncrementalRollingZ) Update(c market.Candle) float64 {
+	return z.UpdateValue(c.Close)
+}
+
+// UpdateValue feeds a raw value, such as a band width, and returns its score.
+func (z *IncrementalRollingZ) UpdateValue(x float64) float64 {
+	z.value = 0
+	if z.window.full() {
+		z.scratch = z.window.appendTo(z.scratch[:0])
+		z.value = windowZScore(z.scratch, x)
+	}
+	z.window.push(x)
+	return z.value
+}
+
+// Value returns the current score.
+func (z *IncrementalRollingZ) Value() float64 { return z.value }
+
+// Ready reports whether the window is filled.
+func (z *IncrementalRollingZ) Ready() bool { return z.window.full() }
+
+// IncrementalVWZ matches VWZScores: the close relative to the volume-weighted mean in units
+// of the volume-weighted standard deviation over period bars. It is NaN before the window
+// is filled, when the window has no volume, or when the deviation is below minStdDev.
+type IncrementalVWZ struct {
+	closes    ring
+	vols      ring
+	minStdDev float64
+	value     float64
+}
+
+// NewIncrementalVWZ returns the volume-weighted z-score of the close over period bars.
+func NewIncrementalVWZ(period int, minStdDev float64) *IncrementalVWZ {
+	return &IncrementalVWZ{closes: newRing(period), vols: newRing(period), minStdDev: minStdDev, value: math.NaN()}
+}
+
+// Update feeds the next candle and returns its score.
+func (z *IncrementalVWZ) Update(c market.Candle) float64 {
+	z.closes.push(c.Close)
+	z.vols.push(c.Vol)
+	z.value = math.NaN()
+	if !z.closes.full() {
+		return z.value
+	}
+
+	var weightedSum, weightSum float64
+	for k := 0; k < z.closes.count; k++ {
+		weightedSum += z.closes.at(k) * z.vols.at(k)
+		weightSum += z.vols.at(k)
+	}
+	if weightSum == 0 {
+		return z.value
+	}
+	mean := weightedSum / weightSum
+
+	var variance float64
+	for k := 0; k < z.closes.count; k++ {
+		diff := z.closes.at(k) - mean
+		variance += z.vols.at(k) * diff * diff
+	}
+	if std := math.Sqrt(variance / weightSum); std >= z.minStdDev {
+		z.value = (c.Close - mean) / std
+	}
+	return z.value
+}
+
+// Value returns the current score.
+func (z *IncrementalVWZ) Value() float64 { return z.value }
+
+// Ready reports whether the current score is defined.
+func (z *IncrementalVWZ) Ready() bool { return !math.IsNaN(z.value) }
+
+// streamSeries feeds the candles through update, an incremental indicator's Update, and
+// returns one value per candle.
+func streamSeries(candles market.CandleSticks, update func(market.Candle) float64) []float64 {
+	series := make([]float64, len(candles))
+	for i, c := range candles {
+		series[i] = update(c)
+	}
+	return series
+}
+
+// streamBBands returns the width, upper, middle and lower band of every candle.
+func streamBBands(candles market.CandleSticks, period int, multiplier float64) [][]float64 {
+	bands := NewIncrementalBBands(period, multiplier)
+	series := [][]float64{make([]float64, len(candles)), make([]float64, len(candles)), make([]float64, len(candles)), make([]float64, len(candles))}
+	for i, c := range candles {
+		v := bands.Update(c)
+		series[0][i], series[1][i], series[2][i], series[3][i] = v.Width, v.Upper, v.Middle, v.Lower
+	}
+	return series
+}
+
+// streamDMI returns the ADX, +DI, -DI and DX of every candle.
+func streamDMI(candles market.CandleSticks, period int) [][]float64 {
+	dmi := NewIncrementalDMI(period)
+	series := [][]float64{make([]float64, len(candles)), make([]float64, len(candles)), make([]float64, len(candles)), make([]float64, len(candles))}
+	for i, c := range candles {
+		v := dmi.Update(c)
+		series[0][i], series[1][i], series[2][i], series[3][i] = v.ADX, v.PlusDI, v.MinusDI, v.DX
+	}
+	return series
+}
+
+// streamMACD returns the MACD, signal and histogram of every candle.
+func streamMACD(candles market.CandleSticks, fastPeriod, slowPeriod, signalPeriod int) [][]float64 {
+	macd := NewIncrementalMACD(fastPeriod, slowPeriod, signalPeriod)
+	series := [][]float64{make([]float64, len(candles)), make([]float64, len(candles)), make([]float64, len(candles))}
+	for i, c := range candles {
+		v := macd.Update(c)
+		series[0][i], series[1][i], series[2][i] = v.MACD, v.Signal, v.Histogram
+	}
+	return series
+}
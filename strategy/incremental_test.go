@@ -0,0 +1,182 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"testing"
+
+	"github.com/markcheno/go-talib"
+)
+
+// equivalenceCandles returns a GARCH series with a flat stretch, so the zero-range and
+// zero-variance branches of the indicators are exercised as well.
+func equivalenceCandles() market.CandleSticks {
+	candles := syntheticSource(21).GARCH(600, 1e-6, 0.1, 0.85)
+	flat := candles[149].Close
+	for i := 150; i < 190; i++ {
+		candles[i].Open, candles[i].High, candles[i].Low, candles[i].Close = flat, flat, flat, flat
+	}
+	return candles
+}
+
+func assertSameSeries(t *testing.T, name string, batch, streamed []float64) {
+	t.Helper()
+	if len(batch) != len(streamed) {
+		t.Fatalf("Expected %s to have %d values, but got %d", name, len(batch), len(streamed))
+	}
+	for i := range batch {
+		if batch[i] != streamed[i] && !(math.IsNaN(batch[i]) && math.IsNaN(streamed[i])) {
+			t.Errorf("Expected %s[%d] to be %v, but got %v", name, i, batch[i], streamed[i])
+			return
+		}
+	}
+}
+
+func streamValues(candles market.CandleSticks, update func(market.Candle) float64) []float64 {
+	out := make([]float64, len(candles))
+	for i, c := range candles {
+		out[i] = update(c)
+	}
+	return out
+}
+
+func TestIncrementalMovingAveragesMatchBatch(t *testing.T) {
+	candles := equivalenceCandles()
+	closes := closesOf(candles)
+
+	for _, period := range []int{1, 2, 9, 20} {
+		assertSameSeries(t, "ema", talib.Ema(closes, period), streamValues(candles, NewIncrementalEMA(period).Update))
+		assertSameSeries(t, "sma", talib.Sma(closes, period), streamValues(candles, NewIncrementalSMA(period).Update))
+		assertSameSeries(t, "stddev", talib.StdDev(closes, period, 2.0), streamValues(candles, NewIncrementalStdDev(period, 2.0).Update))
+	}
+}
+
+func TestIncrementalBBandsMatchesBBW(t *testing.T) {
+	candles := equivalenceCandles()
+	width, upper, middle, lower := BBW(candles, 20, 2.0)
+
+	bands := NewIncrementalBBands(20, 2.0)
+	var gotWidth, gotUpper, gotMiddle, gotLower []float64
+	for _, c := range candles {
+		v := bands.Update(c)
+		gotWidth = append(gotWidth, v.Width)
+		gotUpper = append(gotUpper, v.Upper)
+		gotMiddle = append(gotMiddle, v.Middle)
+		gotLower = append(gotLower, v.Lower)
+	}
+	assertSameSeries(t, "bbw", width, gotWidth)
+	assertSameSeries(t, "upper", upper, gotUpper)
+	assertSameSeries(t, "middle", middle, gotMiddle)
+	assertSameSeries(t, "lower", lower, gotLower)
+
+	rolling := NewIncrementalRollingZ(20)
+	var normalized []float64
+	for _, w := range gotWidth {
+		normalized = append(normalized, rolling.UpdateValue(w))
+	}
+	assertSameSeries(t, "normalized bbw", NormalizeBBW(width, 20), normalized)
+}
+
+func TestIncrementalATRMatchesBatch(t *testing.T) {
+	candles := equivalenceCandles()
+	for _, period := range []int{1, 5, 14} {
+		assertSameSeries(t, "atr", ATR(candles, period), streamValues(candles, NewIncrementalATR(period).Update))
+	}
+}
+
+func TestIncrementalDMIMatchesBatch(t *testing.T) {
+	candles := equivalenceCandles()
+	highs, lows, closes := hlcOf(candles)
+
+	for _, period := range []int{2, 14} {
+		dmi := NewIncrementalDMI(period)
+		var adx, plusDI, minusDI, dx []float64
+		for _, c := range candles {
+			v := dmi.Update(c)
+			adx = append(adx, v.ADX)
+			plusDI = append(plusDI, v.PlusDI)
+			minusDI = append(minusDI, v.MinusDI)
+			dx = append(dx, v.DX)
+		}
+		assertSameSeries(t, "adx", talib.Adx(highs, lows, closes, period), adx)
+		assertSameSeries(t, "plus_di", talib.PlusDI(highs, lows, closes, period), plusDI)
+		assertSameSeries(t, "minus_di", talib.MinusDI(highs, lows, closes, period), minusDI)
+		assertSameSeries(t, "dx", talib.Dx(highs, lows, closes, period), dx)
+	}
+}
+
+func TestIncrementalMACDMatchesBatch(t *testing.T) {
+	candles := equivalenceCandles()
+	macd, signal, hist := talib.Macd(closesOf(candles), 12, 26, 9)
+
+	stream := NewIncrementalMACD(26, 12, 9)
+	var gotMACD, gotSignal, gotHist []float64
+	for _, c := range candles {
+		v := stream.Update(c)
+		gotMACD = append(gotMACD, v.MACD)
+		gotSignal = append(gotSignal, v.Signal)
+		gotHist = append(gotHist, v.Histogram)
+	}
+	assertSameSeries(t, "macd", macd, gotMACD)
+	assertSameSeries(t, "signal", signal, gotSignal)
+	assertSameSeries(t, "histogram", hist, gotHist)
+}
+
+func TestIncrementalZScoresMatchBatch(t *testing.T) {
+	candles := equivalenceCandles()
+
+	assertSameSeries(t, "zscore", ZScores(candles, 20), streamValues(candles, NewIncrementalZScore(20).Update))
+	assertSameSeries(t, "vwz", VWZScores(candles, 20, 0.01), streamValues(candles, NewIncrementalVWZ(20, 0.01).Update))
+	assertSameSeries(t, "rolling z", NormalizeZ(closesOf(candles), 48), streamValues(candles, NewIncrementalRollingZ(48).Update))
+}
+
+func TestRegistryStreamsMatchBatch(t *testing.T) {
+	candles := equivalenceCandles()
+	highs, lows, closes := hlcOf(candles)
+	cfg := &config.Config{Indicators: []config.IndicatorConfig{
+		{Name: "ema9", Type: "ema", Params: map[string]float64{"period": 9}},
+		{Name: "sma9", Type: "sma", Params: map[string]float64{"period": 9}},
+		{Name: "z", Type: "zscore", Params: map[string]float64{"period": 20}},
+		{Name: "w", Type: "vwz", Params: map[string]float64{"period": 20, "minStdDev": 0.01}},
+		{Name: "bands", Type: "bbands", Params: map[string]float64{"period": 20, "multiplier": 2}},
+		{Name: "bandsz", Type: "bbwz", Params: map[string]float64{"period": 20, "multiplier": 2, "window": 48}},
+		{Name: "dmi", Type: "dmi", Params: map[string]float64{"period": 14}},
+		{Name: "m", Type: "macd"},
+		{Name: "range", Type: "atr", Params: map[string]float64{"period": 14}},
+	}}
+	set, err := ComputeIndicators(candles, cfg)
+	if err != nil {
+		t.Fatalf("ComputeIndicators failed: %v", err)
+	}
+
+	width, upper, middle, lower := BBW(candles, 20, 2)
+	macd, signal, histogram := talib.Macd(closes, 12, 26, 9)
+	for name, batch := range map[string][]float64{
+		"ema9":         talib.Ema(closes, 9),
+		"sma9":         talib.Sma(closes, 9),
+		"z":            ZScores(candles, 20),
+		"w":            VWZScores(candles, 20, 0.01),
+		"bands":        width,
+		"bands.upper":  upper,
+		"bands.middle": middle,
+		"bands.lower":  lower,
+		"bandsz":       NormalizeBBW(width, 48),
+		"dmi":          talib.Adx(highs, lows, closes, 14),
+		"dmi.plus_di":  talib.PlusDI(highs, lows, closes, 14),
+		"dmi.minus_di": talib.MinusDI(highs, lows, closes, 14),
+		"dmi.dx":       talib.Dx(highs, lows, closes, 14),
+		"m":            macd,
+		"m.signal":     signal,
+		"m.histogram":  histogram,
+		"range":        talib.Atr(highs, lows, closes, 14),
+	} {
+		assertSameSeries(t, name, batch, set.Get(name))
+	}
+
+	// talib's DMI needs a period of at least 2, and so does IncrementalDMI.
+	cfg.Indicators = []config.IndicatorConfig{{Name: "dmi", Type: "dmi", Params: map[string]float64{"period": 1}}}
+	if _, err := ComputeIndicators(candles, cfg); err == nil {
+		t.Error("Expected a dmi period of 1 to be rejected")
+	}
+}
@@ -0,0 +1,183 @@
+package strategy
+
+import (
+	"go-backtesting/market"
+	"math"
+	"time"
+
+	"github.com/markcheno/go-talib"
+)
+
+// The indicators below are not provided by talib. Like the other series they are aligned to
+// candle indices and are NaN until enough candles are available.
+
+// KeltnerChannels returns an EMA of the close with bands multiplier ATRs above and below.
+func KeltnerChannels(candles market.CandleSticks, period int, multiplier float64, atrPeriod int) (middle, upper, lower []float64) {
+	n := len(candles)
+	middle, upper, lower = nanSeries(n), nanSeries(n), nanSeries(n)
+	if n < period || n <= atrPeriod {
+		return middle, upper, lower
+	}
+	highs, lows, closes := hlcOf(candles)
+	ema := talib.Ema(closes, period)
+	atr := talib.Atr(highs, lows, closes, atrPeriod)
+	for i := max(period-1, atrPeriod); i < n; i++ {
+		middle[i] = ema[i]
+		upper[i] = ema[i] + multiplier*atr[i]
+		lower[i] = ema[i] - multiplier*atr[i]
+	}
+	return middle, upper, lower
+}
+
+// DonchianChannels returns the highest high and lowest low over period candles and their midpoint.
+func DonchianChannels(candles market.CandleSticks, period int) (middle, upper, lower []float64) {
+	n := len(candles)
+	middle, upper, lower = nanSeries(n), nanSeries(n), nanSeries(n)
+	for i := period - 1; i < n; i++ {
+		upper[i], lower[i] = highestLowest(candles[i-period+1 : i+1])
+		middle[i] = (upper[i] + lower[i]) / 2
+	}
+	return middle, upper, lower
+}
+
+// SuperTrend returns the SuperTrend line and its direction (1 up, -1 down). The line trails
+// price by multiplier ATRs from the high-low midpoint and flips when the close crosses it.
+func SuperTrend(candles market.CandleSticks, period int, multiplier float64) (line, direction []float64) {
+	n := len(candles)
+	line, direction = nanSeries(n), nanSeries(n)
+	if n <= period {
+		return line, direction
+	}
+	highs, lows, closes := hlcOf(candles)
+	atr := talib.Atr(highs, lows, closes, period)
+
+	var finalUpper, finalLower float64
+	trend := 1.0
+	for i := period; i < n; i++ {
+		mid := (highs[i] + lows[i]) / 2
+		basicUpper := mid + multiplier*atr[i]
+		basicLower := mid - multiplier*atr[i]
+
+		if i == period {
+			finalUpper, finalLower = basicUpper, basicLower
+		} else {
+			// The bands only tighten while price stays inside them.
+			if basicUpper < finalUpper || closes[i-1] > finalUpper {
+				finalUpper = basicUpper
+			}
+			if basicLower > finalLower || closes[i-1] < finalLower {
+				finalLower = basicLower
+			}
+		}
+
+		if trend > 0 && closes[i] < finalLower {
+			trend = -1
+		} else if trend < 0 && closes[i] > finalUpper {
+			trend = 1
+		}
+
+		direction[i] = trend
+		if trend > 0 {
+			line[i] = finalLower
+		} else {
+			line[i] = finalUpper
+		}
+	}
+	return line, direction
+}
+
+// Ichimoku returns the conversion line (tenkan-sen), base line (kijun-sen) and the two leading
+// spans as they stand at each candle: the spans are projected kijun candles ahead, so the value
+// at i was computed from the candles up to i-kijun. The lagging span is left out because it is
+// plotted in the past and aligning it to the current candle would require future closes.
+func Ichimoku(candles market.CandleSticks, tenkan, kijun, senkou int) (conversion, base, spanA, spanB []float64) {
+	n := len(candles)
+	conversion, base, spanA, spanB = nanSeries(n), nanSeries(n), nanSeries(n), nanSeries(n)
+	midpoint := func(i, period int) float64 {
+		if i < period-1 {
+			return math.NaN()
+		}
+		high, low := highestLowest(candles[i-period+1 : i+1])
+		return (high + low) / 2
+	}
+
+	for i := range candles {
+		conversion[i] = midpoint(i, tenkan)
+		base[i] = midpoint(i, kijun)
+		if j := i - kijun; j >= 0 {
+			spanA[i] = (midpoint(j, tenkan) + midpoint(j, kijun)) / 2
+			spanB[i] = midpoint(j, senkou)
+		}
+	}
+	return conversion, base, spanA, spanB
+}
+
+// SessionVWAP returns the volume-weighted average typical price since the start of each
+// calendar day in loc.
+func SessionVWAP(candles market.CandleSticks, loc *time.Location) []float64 {
+	vwap := nanSeries(len(candles))
+	var pv, vol float64
+	var day time.Time
+	for i, c := range candles {
+		t := c.Time.In(loc)
+		if start := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc); !start.Equal(day) {
+			day = start
+			pv, vol = 0, 0
+		}
+		pv += typicalPrice(c) * c.Vol
+		vol += c.Vol
+		if vol > 0 {
+			vwap[i] = pv / vol
+		}
+	}
+	return vwap
+}
+
+// AnchoredVWAP returns the volume-weighted average typical price of the candles from anchor on.
+func AnchoredVWAP(candles market.CandleSticks, anchor time.Time) []float64 {
+	vwap := nanSeries(len(candles))
+	var pv, vol float64
+	for i, c := range candles {
+		if c.Time.Before(anchor) {
+			continue
+		}
+		pv += typicalPrice(c) * c.Vol
+		vol += c.Vol
+		if vol > 0 {
+			vwap[i] = pv / vol
+		}
+	}
+	return vwap
+}
+
+// ChaikinMoneyFlow returns the period sum of money flow volume divided by the period volume,
+// where each candle's volume is weighted by where it closed within its range (-1 to 1).
+func ChaikinMoneyFlow(candles market.CandleSticks, period int) []float64 {
+	cmf := nanSeries(len(candles))
+	for i := period - 1; i < len(candles); i++ {
+		var flow, vol float64
+		for _, c := range candles[i-period+1 : i+1] {
+			if c.High > c.Low {
+				flow += ((c.Close - c.Low) - (c.High - c.Close)) / (c.High - c.Low) * c.Vol
+			}
+			vol += c.Vol
+		}
+		if vol > 0 {
+			cmf[i] = flow / vol
+		}
+	}
+	return cmf
+}
+
+func highestLowest(candles market.CandleSticks) (high, low float64) {
+	high, low = candles[0].High, candles[0].Low
+	for _, c := range candles[1:] {
+		high = math.Max(high, c.High)
+		low = math.Min(low, c.Low)
+	}
+	return high, low
+}
+
+func typicalPrice(c market.Candle) float64 {
+	return (c.High + c.Low + c.Close) / 3
+}
@@ -0,0 +1,171 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"testing"
+	"time"
+)
+
+func TestDonchianAndIchimokuAreCausal(t *testing.T) {
+	candles := syntheticSource(3).GBM(200, 0, 0.01)
+	middle, upper, lower := DonchianChannels(candles, 20)
+	if !math.IsNaN(upper[18]) {
+		t.Errorf("Expected no Donchian value before 20 candles, but got %f", upper[18])
+	}
+	high, low := highestLowest(candles[80:100])
+	if upper[99] != high || lower[99] != low || middle[99] != (high+low)/2 {
+		t.Errorf("Expected the channel at 99 to span [%f, %f], but got [%f, %f]", low, high, lower[99], upper[99])
+	}
+
+	// The leading spans at i are computed from the candles up to i-kijun only.
+	tenkan, kijun, spanA, spanB := Ichimoku(candles, 9, 26, 52)
+	for i := 26; i < len(candles); i++ {
+		j := i - 26
+		if want := (tenkan[j] + kijun[j]) / 2; !sameFloat(spanA[i], want) {
+			t.Fatalf("Expected span A at %d to be %f, but got %f", i, want, spanA[i])
+		}
+	}
+	if !math.IsNaN(spanB[26+50]) || math.IsNaN(spanB[26+51]) {
+		t.Errorf("Expected span B to start at index %d", 26+51)
+	}
+}
+
+func TestSuperTrendFollowsTrend(t *testing.T) {
+	up := syntheticSource(4).GBM(300, 0.003, 0.002)
+	_, direction := SuperTrend(up, 10, 3)
+	if direction[len(direction)-1] != 1 {
+		t.Errorf("Expected an up direction at the end of a rising series, but got %f", direction[len(direction)-1])
+	}
+	down := syntheticSource(4).GBM(300, -0.003, 0.002)
+	line, direction := SuperTrend(down, 10, 3)
+	last := len(down) - 1
+	if direction[last] != -1 || line[last] < down[last].Close {
+		t.Errorf("Expected a down direction with the line above price, but got direction %f line %f close %f", direction[last], line[last], down[last].Close)
+	}
+}
+
+func TestVWAPAndCMF(t *testing.T) {
+	start := time.Date(2024, 1, 1, 23, 0, 0, 0, time.UTC)
+	candles := market.CandleSticks{
+		{Time: start, High: 12, Low: 8, Close: 10, Vol: 1},
+		{Time: start.Add(30 * time.Minute), High: 22, Low: 18, Close: 20, Vol: 3},
+		{Time: start.Add(60 * time.Minute), High: 32, Low: 28, Close: 30, Vol: 2},
+	}
+
+	// UTC resets at midnight; at UTC+2 all three candles share a day.
+	if got := SessionVWAP(candles, time.UTC); got[1] != 17.5 || got[2] != 30 {
+		t.Errorf("Expected UTC session VWAP [10 17.5 30], but got %v", got)
+	}
+	if got := SessionVWAP(candles, time.FixedZone("UTC+2", 2*3600)); got[2] != 130.0/6 {
+		t.Errorf("Expected the VWAP to run over the whole day at UTC+2, but got %v", got)
+	}
+	if got := AnchoredVWAP(candles, start.Add(30*time.Minute)); !math.IsNaN(got[0]) || got[2] != 24 {
+		t.Errorf("Expected the anchored VWAP to start at the anchor, but got %v", got)
+	}
+
+	// Closing at the middle of the range contributes no money flow.
+	if got := ChaikinMoneyFlow(candles, 2); !math.IsNaN(got[0]) || got[2] != 0 {
+		t.Errorf("Expected CMF [NaN x 0], but got %v", got)
+	}
+}
+
+func TestComputeIndicatorsLibraryTypes(t *testing.T) {
+	candles := syntheticSource(8).GBM(300, 0, 0.005)
+	cfg := &config.Config{
+		Indicators: []config.IndicatorConfig{
+			{Name: "rsi", Type: "rsi"},
+			{Name: "stoch", Type: "stoch"},
+			{Name: "srsi", Type: "stoch_rsi"},
+			{Name: "kc", Type: "keltner"},
+			{Name: "dc", Type: "donchian"},
+			{Name: "st", Type: "supertrend"},
+			{Name: "ichi", Type: "ichimoku"},
+			{Name: "vwap", Type: "vwap"},
+			{Name: "avwap", Type: "anchored_vwap", Anchor: "2023-01-01 05:00:00"},
+			{Name: "obv", Type: "obv"},
+			{Name: "cmf", Type: "cmf"},
+			{Name: "ha", Type: "heikin_ashi"},
+		},
+	}
+	set, err := ComputeIndicators(candles, cfg)
+	if err != nil {
+		t.Fatalf("ComputeIndicators failed: %v", err)
+	}
+	for _, name := range []string{
+		"rsi", "stoch", "stoch.d", "srsi", "srsi.d", "kc", "kc.upper", "kc.lower", "dc", "dc.upper", "dc.lower",
+		"st", "st.direction", "ichi", "ichi.kijun", "ichi.span_a", "ichi.span_b", "vwap", "avwap", "obv", "cmf",
+		"ha", "ha.open", "ha.high", "ha.low",
+	} {
+		if series := set.Get(name); len(series) != len(candles) {
+			t.Errorf("Expected series %q aligned to %d candles, but got length %d", name, len(candles), len(series))
+		}
+	}
+	if avwap := set.Get("avwap"); !math.IsNaN(avwap[59]) || math.IsNaN(avwap[60]) {
+		t.Errorf("Expected the anchored VWAP to start at candle 60 (05:00), but got %f, %f", avwap[59], avwap[60])
+	}
+
+	// talib leaves the oscillators' warmup at 0; the registry reports it as NaN.
+	for name, warmup := range map[string]int{"rsi": 14, "stoch": 17, "stoch.d": 17, "srsi": 29, "srsi.d": 29} {
+		series := set.Get(name)
+		if !math.IsNaN(series[warmup-1]) || math.IsNaN(series[warmup]) {
+			t.Errorf("Expected %q to start at candle %d, but got %f, %f", name, warmup, series[warmup-1], series[warmup])
+		}
+	}
+
+	cfg.Indicators = []config.IndicatorConfig{{Name: "avwap", Type: "anchored_vwap"}}
+	if _, err := ComputeIndicators(candles, cfg); err == nil {
+		t.Error("Expected an error for an anchored VWAP without an anchor")
+	}
+}
+
+func TestOscillatorValues(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	bars := [][3]float64{{10, 8, 9}, {12, 9, 11}, {13, 10, 13}, {12, 10, 10}, {14, 11, 14}}
+	candles := make(market.CandleSticks, len(bars))
+	for i, b := range bars {
+		candles[i] = market.Candle{Time: start.Add(time.Duration(i) * 5 * time.Minute), High: b[0], Low: b[1], Close: b[2]}
+	}
+	// Alternating closes make Wilder's RSI(2) 50, 75, 37.5 and 68.75 once warmed up.
+	alternating := make(market.CandleSticks, 6)
+	for i := range alternating {
+		close := 10 + float64(i%2)
+		alternating[i] = market.Candle{Time: start.Add(time.Duration(i) * 5 * time.Minute), High: close, Low: close, Close: close}
+	}
+	nan := math.NaN()
+
+	tests := []struct {
+		candles market.CandleSticks
+		decl    config.IndicatorConfig
+		want    map[string][]float64
+	}{
+		{alternating, config.IndicatorConfig{Name: "rsi", Type: "rsi", Params: map[string]float64{"period": 2}},
+			map[string][]float64{"rsi": {nan, nan, 50, 75, 37.5, 68.75}}},
+		// The %K of the 3-candle range is 100, 25 and 100 from candle 2; %D averages two of them.
+		{candles, config.IndicatorConfig{Name: "stoch", Type: "stoch", Params: map[string]float64{"period": 3, "smoothK": 1, "smoothD": 2}},
+			map[string][]float64{"stoch": {nan, nan, nan, 25, 100}, "stoch.d": {nan, nan, nan, 62.5, 62.5}}},
+		// The RSI values above alternate between the bottom and the top of their 2-bar range.
+		{alternating, config.IndicatorConfig{Name: "srsi", Type: "stoch_rsi", Params: map[string]float64{"period": 2, "stochPeriod": 2, "smoothD": 1}},
+			map[string][]float64{"srsi": {nan, nan, nan, 100, 0, 100}}},
+	}
+	for _, tt := range tests {
+		set, err := ComputeIndicators(tt.candles, &config.Config{Indicators: []config.IndicatorConfig{tt.decl}})
+		if err != nil {
+			t.Fatalf("ComputeIndicators failed for %s: %v", tt.decl.Type, err)
+		}
+		for name, want := range tt.want {
+			got := set.Get(name)
+			for i := range want {
+				if !sameFloat(got[i], want[i]) && math.Abs(got[i]-want[i]) > 1e-9 {
+					t.Errorf("Expected %s %v, but got %v", name, want, got)
+					break
+				}
+			}
+		}
+	}
+}
+
+func sameFloat(a, b float64) bool {
+	return a == b || (math.IsNaN(a) && math.IsNaN(b))
+}
@@ -0,0 +1,571 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/markcheno/go-talib"
+)
+
+// IndicatorParams holds the numeric parameters of an indicator declaration.
+type IndicatorParams map[string]float64
+
+// Int returns a parameter as an integer period or window length.
+func (p IndicatorParams) Int(name string) int {
+	return int(p[name])
+}
+
+// indicatorType describes how to compute one kind of indicator.
+// A type produces one or more output series; the first output is registered under the
+// indicator's name and the others under "name.output" (e.g. "macd.signal").
+type indicatorType struct {
+	defaults IndicatorParams
+	outputs  []string
+	// minBars returns the number of candles required before the indicator can be computed;
+	// talib panics on shorter inputs, so shorter data yields NaN series instead.
+	minBars func(p IndicatorParams) int
+	// validate, when set, rejects parameters the type cannot be computed with.
+	validate func(p IndicatorParams) error
+	compute  func(candles market.CandleSticks, p IndicatorParams, env indicatorEnv) [][]float64
+}
+
+// indicatorEnv carries the non-numeric settings a few indicator types depend on.
+type indicatorEnv struct {
+	// location is the timezone whose calendar days reset session indicators.
+	location *time.Location
+	// anchor is the start of anchored indicators.
+	anchor time.Time
+}
+
+// indicatorTypes is the registry of indicator types that can be declared in the configuration.
+var indicatorTypes = map[string]indicatorType{
+	"ema": {
+		defaults: IndicatorParams{"period": 20},
+		outputs:  []string{"ema"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return [][]float64{streamSeries(candles, NewIncrementalEMA(p.Int("period")).Update)}
+		},
+	},
+	"sma": {
+		defaults: IndicatorParams{"period": 20},
+		outputs:  []string{"sma"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return [][]float64{streamSeries(candles, NewIncrementalSMA(p.Int("period")).Update)}
+		},
+	},
+	"zscore": {
+		defaults: IndicatorParams{"period": 20},
+		outputs:  []string{"zscore"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return [][]float64{streamSeries(candles, NewIncrementalZScore(p.Int("period")).Update)}
+		},
+	},
+	"vwz": {
+		defaults: IndicatorParams{"period": 20, "minStdDev": 1e-4},
+		outputs:  []string{"vwz"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return [][]float64{streamSeries(candles, NewIncrementalVWZ(p.Int("period"), p["minStdDev"]).Update)}
+		},
+	},
+	"bbands": {
+		defaults: IndicatorParams{"period": 20, "multiplier": 2},
+		outputs:  []string{"width", "upper", "middle", "lower"},
+		minBars:  func(p IndicatorParams) int { return 2 * p.Int("period") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return streamBBands(candles, p.Int("period"), p["multiplier"])
+		},
+	},
+	"bbwz": {
+		defaults: IndicatorParams{"period": 20, "multiplier": 2, "window": 48},
+		outputs:  []string{"bbwz"},
+		minBars:  func(p IndicatorParams) int { return max(2*p.Int("period"), p.Int("window")) },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			width := streamBBands(candles, p.Int("period"), p["multiplier"])[0]
+			rolling := NewIncrementalRollingZ(p.Int("window"))
+			for i, w := range width {
+				width[i] = rolling.UpdateValue(w)
+			}
+			return [][]float64{width}
+		},
+	},
+	"box_filter": {
+		defaults: IndicatorParams{"period": 12, "window": 48, "smoothing": 14},
+		outputs:  []string{"box_filter"},
+		minBars: func(p IndicatorParams) int {
+			return max(p.Int("period"), p.Int("window")+p.Int("smoothing"))
+		},
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return [][]float64{BoxFilterNormalized(candles, p.Int("period"), p.Int("window"), p.Int("smoothing"))}
+		},
+	},
+	"dmi": {
+		defaults: IndicatorParams{"period": 14},
+		outputs:  []string{"adx", "plus_di", "minus_di", "dx"},
+		minBars:  func(p IndicatorParams) int { return 2*p.Int("period") + 1 },
+		validate: func(p IndicatorParams) error {
+			if p.Int("period") < 2 {
+				return fmt.Errorf("parameter \"period\" must be at least 2, got %d", p.Int("period"))
+			}
+			return nil
+		},
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return streamDMI(candles, p.Int("period"))
+		},
+	},
+	"macd": {
+		defaults: IndicatorParams{"fast": 12, "slow": 26, "signal": 9},
+		outputs:  []string{"macd", "signal", "histogram"},
+		minBars:  func(p IndicatorParams) int { return max(p.Int("fast"), p.Int("slow")) + p.Int("signal") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return streamMACD(candles, p.Int("fast"), p.Int("slow"), p.Int("signal"))
+		},
+	},
+	"atr": {
+		defaults: IndicatorParams{"period": 14},
+		outputs:  []string{"atr"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") + 1 },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return [][]float64{streamSeries(candles, NewIncrementalATR(p.Int("period")).Update)}
+		},
+	},
+	"rsi": {
+		defaults: IndicatorParams{"period": 14},
+		outputs:  []string{"rsi"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") + 1 },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return withWarmup(p.Int("period"), talib.Rsi(closesOf(candles), p.Int("period")))
+		},
+	},
+	"stoch": {
+		defaults: IndicatorParams{"period": 14, "smoothK": 3, "smoothD": 3},
+		outputs:  []string{"k", "d"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") + p.Int("smoothK") + p.Int("smoothD") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			highs, lows, closes := hlcOf(candles)
+			k, d := talib.Stoch(highs, lows, closes, p.Int("period"), p.Int("smoothK"), talib.SMA, p.Int("smoothD"), talib.SMA)
+			return withWarmup(p.Int("period")+p.Int("smoothK")+p.Int("smoothD")-3, k, d)
+		},
+	},
+	"stoch_rsi": {
+		defaults: IndicatorParams{"period": 14, "stochPeriod": 14, "smoothD": 3},
+		outputs:  []string{"k", "d"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") + p.Int("stochPeriod") + p.Int("smoothD") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			k, d := talib.StochRsi(closesOf(candles), p.Int("period"), p.Int("stochPeriod"), p.Int("smoothD"), talib.SMA)
+			return withWarmup(p.Int("period")+p.Int("stochPeriod")+p.Int("smoothD")-2, k, d)
+		},
+	},
+	"keltner": {
+		defaults: IndicatorParams{"period": 20, "multiplier": 2, "atrPeriod": 10},
+		outputs:  []string{"middle", "upper", "lower"},
+		minBars:  func(p IndicatorParams) int { return max(p.Int("period"), p.Int("atrPeriod")+1) },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			middle, upper, lower := KeltnerChannels(candles, p.Int("period"), p["multiplier"], p.Int("atrPeriod"))
+			return [][]float64{middle, upper, lower}
+		},
+	},
+	"donchian": {
+		defaults: IndicatorParams{"period": 20},
+		outputs:  []string{"middle", "upper", "lower"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			middle, upper, lower := DonchianChannels(candles, p.Int("period"))
+			return [][]float64{middle, upper, lower}
+		},
+	},
+	"supertrend": {
+		defaults: IndicatorParams{"period": 10, "multiplier": 3},
+		outputs:  []string{"supertrend", "direction"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") + 1 },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			line, direction := SuperTrend(candles, p.Int("period"), p["multiplier"])
+			return [][]float64{line, direction}
+		},
+	},
+	"ichimoku": {
+		defaults: IndicatorParams{"tenkan": 9, "kijun": 26, "senkou": 52},
+		outputs:  []string{"tenkan", "kijun", "span_a", "span_b"},
+		minBars:  func(p IndicatorParams) int { return 1 },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			tenkan, kijun, spanA, spanB := Ichimoku(candles, p.Int("tenkan"), p.Int("kijun"), p.Int("senkou"))
+			return [][]float64{tenkan, kijun, spanA, spanB}
+		},
+	},
+	"vwap": {
+		defaults: IndicatorParams{},
+		outputs:  []string{"vwap"},
+		minBars:  func(p IndicatorParams) int { return 1 },
+		compute: func(candles market.CandleSticks, _ IndicatorParams, env indicatorEnv) [][]float64 {
+			return [][]float64{SessionVWAP(candles, env.location)}
+		},
+	},
+	"anchored_vwap": {
+		defaults: IndicatorParams{},
+		outputs:  []string{"vwap"},
+		minBars:  func(p IndicatorParams) int { return 1 },
+		compute: func(candles market.CandleSticks, _ IndicatorParams, env indicatorEnv) [][]float64 {
+			return [][]float64{AnchoredVWAP(candles, env.anchor)}
+		},
+	},
+	"obv": {
+		defaults: IndicatorParams{},
+		outputs:  []string{"obv"},
+		minBars:  func(p IndicatorParams) int { return 1 },
+		compute: func(candles market.CandleSticks, _ IndicatorParams, _ indicatorEnv) [][]float64 {
+			volumes := make([]float64, len(candles))
+			for i, c := range candles {
+				volumes[i] = c.Vol
+			}
+			return [][]float64{talib.Obv(closesOf(candles), volumes)}
+		},
+	},
+	"cmf": {
+		defaults: IndicatorParams{"period": 20},
+		outputs:  []string{"cmf"},
+		minBars:  func(p IndicatorParams) int { return p.Int("period") },
+		compute: func(candles market.CandleSticks, p IndicatorParams, _ indicatorEnv) [][]float64 {
+			return [][]float64{ChaikinMoneyFlow(candles, p.Int("period"))}
+		},
+	},
+	"heikin_ashi": {
+		defaults: IndicatorParams{},
+		outputs:  []string{"close", "open", "high", "low"},
+		minBars:  func(p IndicatorParams) int { return 1 },
+		compute: func(candles market.CandleSticks, _ IndicatorParams, _ indicatorEnv) [][]float64 {
+			ha := market.HeikinAshi(candles)
+			series := make([][]float64, 4)
+			for k := range series {
+				series[k] = make([]float64, len(ha))
+			}
+			for i, c := range ha {
+				series[0][i], series[1][i], series[2][i], series[3][i] = c.Close, c.Open, c.High, c.Low
+			}
+			return series
+		},
+	},
+}
+
+// IndicatorSet holds named indicator series, each aligned to candle indices.
+type IndicatorSet struct {
+	series map[string][]float64
+	names  []string
+}
+
+// Get returns the series registered under name, or nil if there is none.
+func (s *IndicatorSet) Get(name string) []float64 {
+	if s == nil {
+		return nil
+	}
+	return s.series[name]
+}
+
+// Has reports whether a series is registered under name.
+func (s *IndicatorSet) Has(name string) bool {
+	if s == nil {
+		return false
+	}
+	_, ok := s.series[name]
+	return ok
+}
+
+// Names returns the registered series names in declaration order.
+func (s *IndicatorSet) Names() []string {
+	if s == nil {
+		return nil
+	}
+	return s.names
+}
+
+func (s *IndicatorSet) add(name string, series []float64) {
+	if _, ok := s.series[name]; !ok {
+		s.names = append(s.names, name)
+	}
+	s.series[name] = series
+}
+
+// DefaultIndicators returns the indicator declarations every strategy relies on, derived from
+// the top-level configuration, followed by those of cfg.HigherTimeframes: "ema_short_<tf>" and
+// "adx_<tf>" computed on the resampled bars. Declarations in cfg.Indicators with the same name
+// replace these.
+func DefaultIndicators(cfg *config.Config) []config.IndicatorConfig {
+	decls := []config.IndicatorConfig{
+		{Name: SeriesEmaShort, Type: "ema", Params: periodParam(cfg.EmaPeriod)},
+		{Name: SeriesEmaLong, Type: "ema", Params: periodParam(cfg.EmaPeriod * 10)},
+		{Name: SeriesZScore, Type: "zscore", Params: periodParam(cfg.VWZPeriod)},
+		{Name: SeriesVWZ, Type: "vwz", Params: withParam(periodParam(cfg.VWZPeriod), "minStdDev", cfg.VWZScore.MinStdDev)},
+		{Name: SeriesBBW, Type: "bbands", Params: withParam(periodParam(cfg.BBWPeriod), "multiplier", cfg.BBWMultiplier)},
+		{Name: SeriesBBWZ, Type: "bbwz", Params: withParam(periodParam(cfg.BBWPeriod), "multiplier", cfg.BBWMultiplier)},
+		{Name: SeriesBoxFilter, Type: "box_filter", Params: periodParam(cfg.BoxFilter.Period)},
+		{Name: SeriesADX, Type: "dmi", Params: periodParam(cfg.ADXPeriod)},
+		{Name: SeriesMACD, Type: "macd"},
+		{Name: SeriesATR, Type: "atr", Params: periodParam(atrPeriod(cfg))},
+	}
+	for _, htf := range cfg.HigherTimeframes {
+		emaPeriod, adxPeriod := htf.EmaPeriod, htf.ADXPeriod
+		if emaPeriod <= 0 {
+			emaPeriod = cfg.EmaPeriod
+		}
+		if adxPeriod <= 0 {
+			adxPeriod = cfg.ADXPeriod
+		}
+		decls = append(decls,
+			config.IndicatorConfig{Name: SeriesEmaShort + "_" + htf.Timeframe, Type: "ema", Timeframe: htf.Timeframe, Params: periodParam(emaPeriod)},
+			config.IndicatorConfig{Name: SeriesADX + "_" + htf.Timeframe, Type: "dmi", Timeframe: htf.Timeframe, Params: periodParam(adxPeriod)},
+		)
+	}
+	return decls
+}
+
+// periodParam builds parameters with the given period, leaving it unset when the configuration
+// does not specify one so that the indicator type's default applies.
+func periodParam(period int) map[string]float64 {
+	params := map[string]float64{}
+	if period > 0 {
+		params["period"] = float64(period)
+	}
+	return params
+}
+
+func withParam(params map[string]float64, name string, value float64) map[string]float64 {
+	params[name] = value
+	return params
+}
+
+// Well-known series names used by the built-in conditions. The "dmi" declaration named "adx"
+// also registers "adx.plus_di", "adx.minus_di" and "adx.dx", which are aliased to these names.
+const (
+	SeriesEmaShort      = "ema_short"
+	SeriesEmaLong       = "ema_long"
+	SeriesZScore        = "zscore"
+	SeriesVWZ           = "vwz"
+	SeriesBBW           = "bbw"
+	SeriesBBWZ          = "bbwz"
+	SeriesBoxFilter     = "box_filter"
+	SeriesADX           = "adx"
+	SeriesPlusDI        = "plus_di"
+	SeriesMinusDI       = "minus_di"
+	SeriesDX            = "dx"
+	SeriesMACD          = "macd"
+	SeriesMACDSignal    = "macd.signal"
+	SeriesMACDHistogram = "macd.histogram"
+	SeriesATR           = "atr"
+)
+
+var seriesAliases = map[string]string{
+	SeriesPlusDI:  "adx.plus_di",
+	SeriesMinusDI: "adx.minus_di",
+	SeriesDX:      "adx.dx",
+}
+
+// ComputeIndicators validates the declared indicators, merged over the defaults, and computes
+// each of them once over the candles.
+func ComputeIndicators(candles market.CandleSticks, cfg *config.Config) (*IndicatorSet, error) {
+	decls := mergeIndicatorConfigs(DefaultIndicators(cfg), cfg.Indicators)
+
+	set := &IndicatorSet{series: make(map[string][]float64)}
+	for _, decl := range decls {
+		outputs, err := computeIndicator(candles, decl, cfg)
+		if err != nil {
+			return nil, fmt.Errorf("indicator %q: %w", decl.Name, err)
+		}
+		for name, series := range outputs {
+			if set.Has(name) {
+				return nil, fmt.Errorf("indicator %q: series %q is already defined", decl.Name, name)
+			}
+			set.add(name, series)
+		}
+	}
+	for _, alias := range []string{SeriesPlusDI, SeriesMinusDI, SeriesDX} {
+		if target := seriesAliases[alias]; !set.Has(alias) && set.Has(target) {
+			set.add(alias, set.Get(target))
+		}
+	}
+	return set, nil
+}
+
+// mergeIndicatorConfigs overlays declarations by name, keeping the defaults' order first.
+func mergeIndicatorConfigs(defaults, overrides []config.IndicatorConfig) []config.IndicatorConfig {
+	merged := append([]config.IndicatorConfig(nil), defaults...)
+	for _, o := range overrides {
+		replaced := false
+		for i := range merged {
+			if merged[i].Name == o.Name {
+				merged[i] = o
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			merged = append(merged, o)
+		}
+	}
+	return merged
+}
+
+// computeIndicator computes one declaration and returns its output series by registered name.
+func computeIndicator(candles market.CandleSticks, decl config.IndicatorConfig, cfg *config.Config) (map[string][]float64, error) {
+	if decl.Name == "" {
+		return nil, fmt.Errorf("indicator name is required")
+	}
+	if strings.ContainsAny(decl.Name, ".[] ") {
+		return nil, fmt.Errorf("indicator name must not contain '.', '[', ']' or spaces")
+	}
+	typ, ok := indicatorTypes[decl.Type]
+	if !ok {
+		return nil, fmt.Errorf("unknown indicator type %q (available: %s)", decl.Type, strings.Join(IndicatorTypeNames(), ", "))
+	}
+
+	params := IndicatorParams{}
+	for k, v := range typ.defaults {
+		params[k] = v
+	}
+	for k, v := range decl.Params {
+		if _, ok := typ.defaults[k]; !ok {
+			return nil, fmt.Errorf("unknown parameter %q for type %q", k, decl.Type)
+		}
+		params[k] = v
+	}
+	for k, v := range params {
+		if isLengthParam(k) && (v < 1 || v != math.Trunc(v)) {
+			return nil, fmt.Errorf("parameter %q must be a positive integer, got %v", k, v)
+		}
+	}
+	if typ.validate != nil {
+		if err := typ.validate(params); err != nil {
+			return nil, err
+		}
+	}
+
+	// Higher-timeframe indicators are computed on resampled bars and aligned back without look-ahead.
+	source := candles
+	var barIndex []int
+	if decl.Timeframe != "" {
+		resampled, index, err := resampleForIndicator(candles, decl.Timeframe, cfg)
+		if err != nil {
+			return nil, err
+		}
+		source, barIndex = resampled, index
+	}
+
+	env, err := newIndicatorEnv(decl, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	var values [][]float64
+	if len(source) >= typ.minBars(params) {
+		values = typ.compute(source, params, env)
+	}
+
+	outputs := make(map[string][]float64, len(typ.outputs))
+	for k, output := range typ.outputs {
+		var series []float64
+		if k < len(values) {
+			series = values[k]
+		}
+		if len(series) != len(source) {
+			series = nanSeries(len(source))
+		}
+		if barIndex != nil {
+			series = alignToBase(series, barIndex)
+		}
+
+		name := decl.Name
+		if k > 0 {
+			name = decl.Name + "." + output
+		}
+		outputs[name] = series
+	}
+	return outputs, nil
+}
+
+// newIndicatorEnv resolves the declaration's anchor and the timezone of session resets,
+// which is the session timezone when a session is configured and UTC otherwise.
+func newIndicatorEnv(decl config.IndicatorConfig, cfg *config.Config) (indicatorEnv, error) {
+	env := indicatorEnv{location: time.UTC}
+	if cfg.Session != nil {
+		loc, err := time.LoadLocation(cfg.Session.Timezone)
+		if err != nil {
+			return env, fmt.Errorf("invalid session timezone: %w", err)
+		}
+		env.location = loc
+	}
+	if decl.Anchor != "" {
+		anchor, err := cfg.ParseTime(decl.Anchor)
+		if err != nil {
+			return env, fmt.Errorf("invalid anchor: %w", err)
+		}
+		env.anchor = anchor
+	} else if decl.Type == "anchored_vwap" {
+		return env, fmt.Errorf("an anchor time is required for type %q", decl.Type)
+	}
+	return env, nil
+}
+
+// isLengthParam reports whether a parameter is a bar count that must be a positive integer.
+func isLengthParam(name string) bool {
+	switch name {
+	case "period", "window", "smoothing", "fast", "slow", "signal",
+		"smoothK", "smoothD", "stochPeriod", "atrPeriod", "tenkan", "kijun", "senkou":
+		return true
+	}
+	return false
+}
+
+// atrPeriod returns the configured ATR period, defaulting to 14.
+func atrPeriod(cfg *config.Config) int {
+	if cfg.ATRPeriod > 0 {
+		return cfg.ATRPeriod
+	}
+	return 14
+}
+
+// IndicatorTypeNames returns the registered indicator type names, sorted.
+func IndicatorTypeNames() []string {
+	names := make([]string, 0, len(indicatorTypes))
+	for name := range indicatorTypes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func closesOf(candles market.CandleSticks) []float64 {
+	closes := make([]float64, len(candles))
+	for i, c := range candles {
+		closes[i] = c.Close
+	}
+	return closes
+}
+
+func hlcOf(candles market.CandleSticks) (highs, lows, closes []float64) {
+	highs = make([]float64, len(candles))
+	lows = make([]float64, len(candles))
+	closes = make([]float64, len(candles))
+	for i, c := range candles {
+		highs[i] = c.High
+		lows[i] = c.Low
+		closes[i] = c.Close
+	}
+	return highs, lows, closes
+}
+
+// withWarmup replaces the first lookback values of talib outputs, which talib leaves at 0, with
+// NaN so that they read as "not available yet" like the other series.
+func withWarmup(lookback int, series ...[]float64) [][]float64 {
+	for _, values := range series {
+		for i := 0; i < lookback && i < len(values); i++ {
+			values[i] = math.NaN()
+		}
+	}
+	return series
+}
@@ -0,0 +1,167 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/markcheno/go-talib"
+)
+
+func TestComputeIndicatorsDefaultsAndOverrides(t *testing.T) {
+	candles, err := market.ReadCandlesFromCSV("test_data.csv")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	cfg := &config.Config{
+		EmaPeriod: 5,
+		ADXPeriod: 5,
+		Indicators: []config.IndicatorConfig{
+			{Name: "ema_long", Type: "ema", Params: map[string]float64{"period": 8}},
+			{Name: "fast_macd", Type: "macd", Params: map[string]float64{"fast": 3, "slow": 6, "signal": 3}},
+			{Name: "adx_15m", Type: "dmi", Timeframe: "15m", Params: map[string]float64{"period": 3}},
+		},
+	}
+
+	set, err := ComputeIndicators(candles, cfg)
+	if err != nil {
+		t.Fatalf("ComputeIndicators failed: %v", err)
+	}
+
+	for _, name := range []string{
+		SeriesEmaShort, SeriesEmaLong, SeriesZScore, SeriesVWZ, SeriesBBW, SeriesBBWZ, SeriesBoxFilter,
+		SeriesADX, SeriesPlusDI, SeriesMinusDI, SeriesDX, SeriesMACD, SeriesMACDSignal, SeriesMACDHistogram, SeriesATR,
+		"fast_macd", "fast_macd.signal", "fast_macd.histogram", "adx_15m", "adx_15m.plus_di",
+	} {
+		if series := set.Get(name); len(series) != len(candles) {
+			t.Errorf("Expected series %q aligned to %d candles, but got length %d", name, len(candles), len(series))
+		}
+	}
+
+	closes := closesOf(candles)
+	expected := talib.Ema(closes, 8)
+	if got := set.Get(SeriesEmaLong); got[len(got)-1] != expected[len(expected)-1] {
+		t.Errorf("Expected the ema_long override to use period 8, got %f instead of %f", got[len(got)-1], expected[len(expected)-1])
+	}
+
+	// The higher-timeframe series shows the value of the last completed 15m bar.
+	bars, barIndex, err := resampleForIndicator(candles, "15m", cfg)
+	if err != nil {
+		t.Fatalf("resampleForIndicator failed: %v", err)
+	}
+	highs, lows, barCloses := hlcOf(bars)
+	native := talib.Adx(highs, lows, barCloses, 3)
+	htf := set.Get("adx_15m")
+	for i, j := range barIndex {
+		if j < 0 {
+			if !math.IsNaN(htf[i]) {
+				t.Errorf("Candle %d: expected NaN before the first completed 15m bar, but got %f", i, htf[i])
+			}
+		} else if htf[i] != native[j] {
+			t.Errorf("Candle %d: expected 15m ADX %f from bar %d, but got %f", i, native[j], j, htf[i])
+		}
+	}
+}
+
+func TestComputeIndicatorsValidation(t *testing.T) {
+	candles, err := market.ReadCandlesFromCSV("test_data.csv")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	invalid := map[string]config.IndicatorConfig{
+		"unknown type":      {Name: "x", Type: "nope"},
+		"unknown parameter": {Name: "x", Type: "ema", Params: map[string]float64{"length": 5}},
+		"zero period":       {Name: "x", Type: "ema", Params: map[string]float64{"period": 0}},
+		"fractional period": {Name: "x", Type: "ema", Params: map[string]float64{"period": 2.5}},
+		"missing name":      {Type: "ema"},
+		"dotted name":       {Name: "a.b", Type: "ema"},
+		"clashing output":   {Name: "macd", Type: "ema"},
+	}
+	for reason, decl := range invalid {
+		cfg := &config.Config{Indicators: []config.IndicatorConfig{decl}}
+		if reason == "clashing output" {
+			// "macd" replaces the default MACD, so clash with its signal output instead.
+			cfg.Indicators = []config.IndicatorConfig{{Name: "macd", Type: "bbands"}, {Name: "macd.signal", Type: "ema"}}
+		}
+		if _, err := ComputeIndicators(candles, cfg); err == nil {
+			t.Errorf("Expected ComputeIndicators to fail for %s", reason)
+		}
+	}
+}
+
+func TestTechnicalIndicatorsGet(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:      "test_data.csv",
+		EmaPeriod:     5,
+		ADXPeriod:     5,
+		VWZPeriod:     5,
+		BBWPeriod:     20,
+		BBWMultiplier: 2.0,
+		Indicators:    []config.IndicatorConfig{{Name: "sma_10", Type: "sma", Params: map[string]float64{"period": 10}}},
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+
+	i := len(strategyData.Candles) - 1
+	indicators := strategyData.createTechnicalIndicators(i, cfg)
+	sma := indicators.Get("sma_10")
+	if len(sma) != 3 || sma[2] != strategyData.Series("sma_10")[i] {
+		t.Errorf("Expected the last 3 sma_10 values ending at candle %d, but got %v", i, sma)
+	}
+	if indicators.Get("missing") != nil {
+		t.Error("Expected nil for an unregistered series")
+	}
+}
+
+func TestTechnicalIndicatorsLookbackDepth(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:      "test_data.csv",
+		EmaPeriod:     5,
+		ADXPeriod:     5,
+		VWZPeriod:     5,
+		BBWPeriod:     20,
+		BBWMultiplier: 2.0,
+		LookbackDepth: 6,
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+	ema := strategyData.Series(SeriesEmaShort)
+
+	i := len(strategyData.Candles) - 1
+	indicators := strategyData.createTechnicalIndicators(i, cfg)
+	if len(indicators.EmaShort) != 6 || indicators.EmaShort[5] != ema[i] || indicators.EmaShort[0] != ema[i-5] {
+		t.Errorf("Expected the last 6 ema_short values ending at candle %d, but got %v", i, indicators.EmaShort)
+	}
+	if v, ok := indicators.Ago(SeriesEmaShort, 10); !ok || v != ema[i-10] {
+		t.Errorf("Expected Ago to reach past the window depth, but got %f, %v", v, ok)
+	}
+	if values, ok := indicators.Lookback(SeriesEmaShort, 12); !ok || len(values) != 12 || values[11] != ema[i] {
+		t.Errorf("Expected a 12-value lookback, but got %v, %v", values, ok)
+	}
+
+	// Near the start there is not enough history: windows are nil rather than shortened.
+	early := strategyData.createTechnicalIndicators(4, cfg)
+	if early.EmaShort != nil || early.Get(SeriesEmaShort) != nil {
+		t.Errorf("Expected nil windows with 5 candles of history and depth 6, but got %v", early.EmaShort)
+	}
+	if _, ok := early.Ago(SeriesEmaShort, 5); ok {
+		t.Error("Expected Ago to report missing history before the first candle")
+	}
+	if _, ok := early.Lookback(SeriesEmaShort, 6); ok {
+		t.Error("Expected Lookback to report missing history")
+	}
+
+	// A window without the previous value would keep every condition from entering.
+	for _, depth := range []int{1, -1} {
+		cfg.LookbackDepth = depth
+		if _, err := InitializeStrategyDataContext(cfg); err == nil || !strings.Contains(err.Error(), "lookbackDepth must be at least 2") {
+			t.Errorf("Expected lookback depth %d to be rejected, but got %v", depth, err)
+		}
+	}
+}
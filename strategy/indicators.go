@@ -24,6 +24,9 @@ const (
 )
 
 // TechnicalIndicators holds the values of all technical indicators for a given candle.
+// Each series field is a window of the last lookback-depth values, oldest first, ending at the
+// current candle. A window is nil when there is not enough history to fill it, so conditions
+// never see a silently shortened window.
 type TechnicalIndicators struct {
 	BBState       BBWState
 	PlusDI        []float64
@@ -40,61 +43,197 @@ type TechnicalIndicators struct {
 	MACDSignal    []float64
 	MACDHistogram []float64
 	BoxFilter     []float64
+
+	// Regime is the market regime of the current candle (see ClassifyRegimes).
+	Regime Regime
+
+	indicators *IndicatorSet
+	candles    market.CandleSticks
+	index      int
+	depth      int
+
+	// data and cfg rebuild the indicators of earlier candles; lastExit is the candle of the
+	// most recent exit when exited is set.
+	data     *StrategyDataContext
+	cfg      *config.Config
+	lastExit int
+	exited   bool
+	// checks collects the sub-checks recorded while a condition is explained.
+	checks *[]Check
 }
 
 // StrategyDataContext holds all the data required for a strategy.
 type StrategyDataContext struct {
-	Candles       market.CandleSticks
-	EmaShort      []float64
-	EmaLong       []float64
-	ZScores       []float64
-	VwzScores     []float64
-	PlusDI        []float64
-	MinusDI       []float64
-	AdxSeries     []float64
-	BbwzScores    []float64
-	Bbw           []float64
-	DX            []float64
-	MACD          []float64
-	MACDSignal    []float64
-	MACDHistogram []float64
-	BoxFilter     []float64
+	Candles market.CandleSticks
+	// Indicators holds every declared indicator series by name, aligned to Candles.
+	Indicators *IndicatorSet
+	// Session restricts entries to a trading window; nil allows entries at any time.
+	Session *Session
+	// StartIndex is the first candle that may open a trade; earlier candles only warm up indicators.
+	StartIndex int
+	// BBWStates holds the BBW regime of every candle; it is computed on first use when empty.
+	BBWStates []BBWState
+	// Regimes holds the market regime of every candle; candles beyond it are RegimeUnknown.
+	Regimes []Regime
+	// Trace, when set, receives every entry decision the runners make.
+	Trace *DecisionTrace
+}
+
+// Series returns the indicator series registered under name, or nil if there is none.
+func (s *StrategyDataContext) Series(name string) []float64 {
+	return s.Indicators.Get(name)
 }
 
 // createTechnicalIndicators creates a TechnicalIndicators struct for a given index,
-// populating it with the last 3 values of each indicator.
+// populating it with the last lookback-depth values of each indicator.
 func (s *StrategyDataContext) createTechnicalIndicators(i int, config *config.Config) TechnicalIndicators {
+	depth := lookbackDepth(config)
+
 	return TechnicalIndicators{
-		BBState:       DetectBBWState(s.Candles[:i+1], config.BBWPeriod, config.BBWMultiplier, config.BBWThreshold),
-		PlusDI:        getLastThree(s.PlusDI, i),
-		MinusDI:       getLastThree(s.MinusDI, i),
-		VWZScore:      getLastThree(s.VwzScores, i),
-		ZScore:        getLastThree(s.ZScores, i),
-		EmaShort:      getLastThree(s.EmaShort, i),
-		EmaLong:       getLastThree(s.EmaLong, i),
-		ADX:           getLastThree(s.AdxSeries, i),
-		DX:            getLastThree(s.DX, i),
-		BBW:           getLastThree(s.Bbw, i),
-		BbwzScore:     getLastThree(s.BbwzScores, i),
-		MACD:          getLastThree(s.MACD, i),
-		MACDSignal:    getLastThree(s.MACDSignal, i),
-		MACDHistogram: getLastThree(s.MACDHistogram, i),
-		BoxFilter:     getLastThree(s.BoxFilter, i),
+		BBState:       s.bbwStateAt(i, config),
+		Regime:        s.regimeAt(i),
+		PlusDI:        getLastN(s.Series(SeriesPlusDI), i, depth),
+		MinusDI:       getLastN(s.Series(SeriesMinusDI), i, depth),
+		VWZScore:      getLastN(s.Series(SeriesVWZ), i, depth),
+		ZScore:        getLastN(s.Series(SeriesZScore), i, depth),
+		EmaShort:      getLastN(s.Series(SeriesEmaShort), i, depth),
+		EmaLong:       getLastN(s.Series(SeriesEmaLong), i, depth),
+		ADX:           getLastN(s.Series(SeriesADX), i, depth),
+		DX:            getLastN(s.Series(SeriesDX), i, depth),
+		BBW:           getLastN(s.Series(SeriesBBW), i, depth),
+		BbwzScore:     getLastN(s.Series(SeriesBBWZ), i, depth),
+		MACD:          getLastN(s.Series(SeriesMACD), i, depth),
+		MACDSignal:    getLastN(s.Series(SeriesMACDSignal), i, depth),
+		MACDHistogram: getLastN(s.Series(SeriesMACDHistogram), i, depth),
+		BoxFilter:     getLastN(s.Series(SeriesBoxFilter), i, depth),
+
+		indicators: s.Indicators,
+		candles:    s.Candles,
+		index:      i,
+		depth:      depth,
+		data:       s,
+		cfg:        config,
 	}
 }
 
-// getLastThree safely retrieves the last 1, 2, or 3 values from a slice up to a given index.
-func getLastThree(data []float64, index int) []float64 {
-	start := index - 2
-	if start < 0 {
-		start = 0
+// minLookbackDepth is the shortest window depth accepted: conditions compare the current value
+// of a series with the previous one, which a single-value window does not hold.
+const minLookbackDepth = 2
+
+// lookbackDepth returns the configured window depth, defaulting to 3.
+func lookbackDepth(cfg *config.Config) int {
+	if cfg.LookbackDepth > 0 {
+		return cfg.LookbackDepth
 	}
-	// The end index for slicing is exclusive, so `index + 1` includes the element at `index`.
-	end := index + 1
-	if end > len(data) {
-		end = len(data)
+	return 3
+}
+
+// bbwStateAt returns the BBW regime at candle i, computing the whole series once.
+func (s *StrategyDataContext) bbwStateAt(i int, config *config.Config) BBWState {
+	if len(s.BBWStates) != len(s.Candles) {
+		s.BBWStates = BBWStateSeries(s.Candles, config.BBWPeriod, config.BBWMultiplier, config.BBWThreshold, atrPeriod(config))
 	}
-	return data[start:end]
+	return s.BBWStates[i]
+}
+
+// BBWStatesFor returns the BBW regime of every candle, computing the series on first use.
+func (s *StrategyDataContext) BBWStatesFor(config *config.Config) []BBWState {
+	if len(s.Candles) == 0 {
+		return nil
+	}
+	s.bbwStateAt(0, config)
+	return s.BBWStates
+}
+
+// regimeAt returns the market regime at candle i.
+func (s *StrategyDataContext) regimeAt(i int) Regime {
+	if i < len(s.Regimes) {
+		return s.Regimes[i]
+	}
+	return RegimeUnknown
+}
+
+// Get returns the lookback window of any registered indicator series, by name.
+// It returns nil when no series of that name is registered or the history is too short.
+func (t TechnicalIndicators) Get(name string) []float64 {
+	return getLastN(t.indicators.Get(name), t.index, t.depth)
+}
+
+// Lookback returns the last n values of a registered series ending at the current candle,
+// oldest first, reaching past the configured depth if needed. ok is false when the series is
+// unknown or has fewer than n values up to the current candle.
+func (t TechnicalIndicators) Lookback(name string, n int) (values []float64, ok bool) {
+	values = getLastN(t.indicators.Get(name), t.index, n)
+	return values, values != nil
+}
+
+// Ago returns the value of a registered series barsAgo candles before the current one
+// (0 is the current candle). ok is false when the series is unknown or does not reach back that far.
+func (t TechnicalIndicators) Ago(name string, barsAgo int) (value float64, ok bool) {
+	series := t.indicators.Get(name)
+	i := t.index - barsAgo
+	if barsAgo < 0 || i < 0 || i >= len(series) {
+		return math.NaN(), false
+	}
+	return series[i], true
+}
+
+// BarsSinceExit returns the number of candles since the last trade was closed (0 on the exit
+// candle itself). ok is false when no trade has been closed yet.
+func (t TechnicalIndicators) BarsSinceExit() (bars int, ok bool) {
+	return t.index - t.lastExit, t.exited
+}
+
+// withLastExit returns the indicators with the last exit at candle exitIndex; a negative
+// exitIndex means no trade has been closed.
+func (t TechnicalIndicators) withLastExit(exitIndex int) TechnicalIndicators {
+	t.lastExit, t.exited = exitIndex, exitIndex >= 0
+	return t
+}
+
+// at returns the indicators barsAgo candles before the current one. ok is false when there is
+// no such candle or the indicators were not built from a data context.
+func (t TechnicalIndicators) at(barsAgo int) (past TechnicalIndicators, ok bool) {
+	i := t.index - barsAgo
+	if t.data == nil || barsAgo < 0 || i < 0 || i >= len(t.data.Candles) {
+		return TechnicalIndicators{}, false
+	}
+	past = t.data.createTechnicalIndicators(i, t.cfg)
+	if t.exited && t.lastExit <= i {
+		past = past.withLastExit(t.lastExit)
+	}
+	return past, true
+}
+
+// candleField returns open, high, low, close or volume of the candle barsAgo before the
+// current one, or NaN when there is no such candle.
+func (t *TechnicalIndicators) candleField(field string, barsAgo int) float64 {
+	i := t.index - barsAgo
+	if barsAgo < 0 || i < 0 || i >= len(t.candles) {
+		return math.NaN()
+	}
+	c := t.candles[i]
+	switch field {
+	case "open":
+		return c.Open
+	case "high":
+		return c.High
+	case "low":
+		return c.Low
+	case "close":
+		return c.Close
+	default:
+		return c.Vol
+	}
+}
+
+// getLastN returns the n values of data ending at index, oldest first, or nil when
+// data does not hold n values up to index.
+func getLastN(data []float64, index, n int) []float64 {
+	if n <= 0 || index >= len(data) || index+1 < n {
+		return nil
+	}
+	return data[index+1-n : index+1]
 }
 
 func Ema(zscores []float64, period int) []float64 {
@@ -212,10 +351,13 @@ func BoxFilter(candles market.CandleSticks, period int, minRangePct float64) []f
 	return isRanging
 }
 
+// BoxFilterNormalized returns the rolling z-score of the period high-low range relative to the
+// close, normalized over window bars and EMA-smoothed over smoothing bars.
 func BoxFilterNormalized(
 	candles market.CandleSticks,
 	period int,
 	window int,
+	smoothing int,
 ) []float64 {
 
 	highs := make([]float64, len(candles))
@@ -241,7 +383,7 @@ func BoxFilterNormalized(
 	}
 
 	normalized := NormalizeZ(rangeSeries, window)
-	return talib.Ema(normalized, 14)
+	return talib.Ema(normalized, smoothing)
 }
 
 // NormalizeZ calculates rolling Z-score for any float series.
@@ -251,6 +393,7 @@ func NormalizeZ(series []float64, window int) []float64 {
 	}
 
 	zScores := make([]float64, len(series))
+	windowData := make([]float64, window)
 
 	for i := range series {
 		if i < window {
@@ -258,16 +401,22 @@ func NormalizeZ(series []float64, window int) []float64 {
 			continue
 		}
 
-		windowData := series[i-window : i]
-		mean := stat.Mean(windowData, nil)
-		std := stat.StdDev(windowData, nil)
-
-		if std == 0 {
-			zScores[i] = 0
-		} else {
-			zScores[i] = (series[i] - mean) / std
-		}
+		copy(windowData, series[i-window:i])
+		zScores[i] = windowZScore(windowData, series[i])
 	}
 
 	return zScores
 }
+
+// windowZScore scores x against the mean and sample standard deviation of window, or returns 0
+// for a flat window. gonum's vectorised sums depend on the memory alignment of their input, so
+// callers pass a buffer of their own rather than a subslice; this keeps the batch and
+// incremental results bit-identical.
+func windowZScore(window []float64, x float64) float64 {
+	mean := stat.Mean(window, nil)
+	std := stat.StdDev(window, nil)
+	if std == 0 {
+		return 0
+	}
+	return (x - mean) / std
+}
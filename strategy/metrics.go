@@ -0,0 +1,83 @@
+package strategy
+
+import (
+	"math"
+	"time"
+
+	"gonum.org/v1/gonum/stat"
+)
+
+// Metrics summarizes the trades of a backtest. Returns are trade PnL percentages.
+type Metrics struct {
+	TotalTrades int
+	WinRate     float64 // percent
+	TotalPnl    float64 // price points
+	// TotalReturn is the sum of the trade returns, in percent.
+	TotalReturn float64
+	// AverageWin and AverageLoss are the mean returns of winning and losing trades, in percent.
+	AverageWin  float64
+	AverageLoss float64
+	// Expectancy is the mean trade return, in percent.
+	Expectancy float64
+	// ProfitFactor is the gross profit over the gross loss; +Inf without losses.
+	ProfitFactor float64
+	// MaxDrawdown is the largest fall of the equity curve from a peak, in price points.
+	MaxDrawdown float64
+	// SharpeRatio is the mean trade return over its standard deviation, per trade and not annualized.
+	SharpeRatio         float64
+	LongestLosingStreak int
+	AverageHolding      time.Duration
+}
+
+// ComputeMetrics summarizes the trades of result.
+func ComputeMetrics(result BacktestResult) Metrics {
+	m := Metrics{TotalTrades: result.TotalTrades, WinRate: result.WinRate, TotalPnl: result.TotalPnl}
+	if len(result.Trades) == 0 {
+		return m
+	}
+
+	returns := make([]float64, len(result.Trades))
+	var wins, losses []float64
+	grossProfit, grossLoss := 0.0, 0.0
+	streak := 0
+	var holding time.Duration
+	for k, t := range result.Trades {
+		returns[k] = t.PnlPercentage
+		holding += t.ExitTime.Sub(t.EntryTime)
+		if t.Pnl > 0 {
+			wins = append(wins, t.PnlPercentage)
+			grossProfit += t.Pnl
+			streak = 0
+			continue
+		}
+		losses = append(losses, t.PnlPercentage)
+		grossLoss -= t.Pnl
+		streak++
+		m.LongestLosingStreak = max(m.LongestLosingStreak, streak)
+	}
+
+	for _, r := range returns {
+		m.TotalReturn += r
+	}
+	m.Expectancy = stat.Mean(returns, nil)
+	if len(wins) > 0 {
+		m.AverageWin = stat.Mean(wins, nil)
+	}
+	if len(losses) > 0 {
+		m.AverageLoss = stat.Mean(losses, nil)
+	}
+	m.ProfitFactor = math.Inf(1)
+	if grossLoss > 0 {
+		m.ProfitFactor = grossProfit / grossLoss
+	}
+	for _, p := range EquityCurve(result.Trades) {
+		m.MaxDrawdown = max(m.MaxDrawdown, p.Drawdown)
+	}
+	if len(returns) > 1 {
+		if std := stat.StdDev(returns, nil); std > 0 {
+			m.SharpeRatio = m.Expectancy / std
+		}
+	}
+	m.AverageHolding = holding / time.Duration(len(result.Trades))
+	return m
+}
@@ -0,0 +1,51 @@
+package strategy
+
+import (
+	"math"
+	"testing"
+	"time"
+)
+
+func TestComputeMetrics(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	trade := func(k int, pnl float64) Trade {
+		entry := start.Add(time.Duration(k) * 2 * time.Hour)
+		return Trade{EntryTime: entry, ExitTime: entry.Add(time.Hour), EntryPrice: 100, Pnl: pnl, PnlPercentage: pnl}
+	}
+	trades := []Trade{trade(0, 2), trade(1, -1), trade(2, -1), trade(3, 4)}
+	m := ComputeMetrics(BacktestResult{Trades: trades, TotalTrades: 4, WinRate: 50, TotalPnl: 4})
+
+	if m.TotalReturn != 4 || m.Expectancy != 1 {
+		t.Errorf("Expected total return 4 and expectancy 1, but got %v and %v", m.TotalReturn, m.Expectancy)
+	}
+	if m.AverageWin != 3 || m.AverageLoss != -1 {
+		t.Errorf("Expected average win 3 and loss -1, but got %v and %v", m.AverageWin, m.AverageLoss)
+	}
+	if m.ProfitFactor != 3 {
+		t.Errorf("Expected profit factor 3, but got %v", m.ProfitFactor)
+	}
+	if m.MaxDrawdown != 2 || m.LongestLosingStreak != 2 {
+		t.Errorf("Expected max drawdown 2 over a streak of 2 losses, but got %v and %d", m.MaxDrawdown, m.LongestLosingStreak)
+	}
+	if m.AverageHolding != time.Hour {
+		t.Errorf("Expected an average holding of 1h, but got %v", m.AverageHolding)
+	}
+	if m.SharpeRatio <= 0 {
+		t.Errorf("Expected a positive Sharpe ratio, but got %v", m.SharpeRatio)
+	}
+
+	if m := ComputeMetrics(BacktestResult{Trades: trades[:1]}); !math.IsInf(m.ProfitFactor, 1) {
+		t.Errorf("Expected an infinite profit factor without losses, but got %v", m.ProfitFactor)
+	}
+}
+
+func TestTradeLevels(t *testing.T) {
+	long := Trade{Direction: "long", EntryPrice: 100}
+	if tp, sl := long.Levels(0.02, 0.01); math.Abs(tp-102) > 1e-9 || math.Abs(sl-99) > 1e-9 {
+		t.Errorf("Expected long levels 102 and 99, but got %v and %v", tp, sl)
+	}
+	short := Trade{Direction: "short", EntryPrice: 100}
+	if tp, sl := short.Levels(0.02, 0.01); math.Abs(tp-98) > 1e-9 || math.Abs(sl-101) > 1e-9 {
+		t.Errorf("Expected short levels 98 and 101, but got %v and %v", tp, sl)
+	}
+}
@@ -1,5 +1,7 @@
 package strategy
 
+import "go-backtesting/config"
+
 type PatternType string
 
 const (
@@ -8,22 +10,26 @@ const (
 	PatternMixed      PatternType = "mixed"      // 혼합형 (불규칙)
 )
 
-// AnalyzePattern inspects the last 3 values and returns the pattern type.
+// minPatternBars is the shortest window a pattern can be read from.
+const minPatternBars = 3
+
+// AnalyzePattern inspects a window of values, oldest first, and returns the pattern type.
+// A pattern needs at least minPatternBars values and must hold across the whole window.
 // Sign-aware: positive series => normal increasing (a < b < c).
 //
 //	negative series => "more negative" (a > b > c) is treated as Increasing (strengthening short).
 func AnalyzePattern(values []float64) PatternType {
-	if len(values) != 3 {
+	if len(values) < minPatternBars {
 		return PatternMixed
 	}
-	a, b, c := values[0], values[1], values[2]
+	rising, falling := isMonotonic(values)
 
 	// all non-negative -> standard increasing/decreasing
-	if a >= 0 && b >= 0 && c >= 0 {
+	if allSigns(values, func(v float64) bool { return v >= 0 }) {
 		switch {
-		case a < b && b < c:
+		case rising:
 			return PatternIncreasing
-		case a > b && b > c:
+		case falling:
 			return PatternDecreasing
 		default:
 			return PatternMixed
@@ -31,13 +37,13 @@ func AnalyzePattern(values []float64) PatternType {
 	}
 
 	// all non-positive -> for shorts: becoming more negative (a > b > c) means "strengthening short"
-	if a <= 0 && b <= 0 && c <= 0 {
+	if allSigns(values, func(v float64) bool { return v <= 0 }) {
 		switch {
 		// a > b > c : e.g. -1, -2, -3 (더 음수) => short-strengthening => treat as Increasing (signal gets stronger)
-		case a > b && b > c:
+		case falling:
 			return PatternIncreasing
 		// a < b < c : e.g. -3, -2, -1 (음수지만 값이 커짐) => short-weakening => treat as Decreasing
-		case a < b && b < c:
+		case rising:
 			return PatternDecreasing
 		default:
 			return PatternMixed
@@ -48,30 +54,56 @@ func AnalyzePattern(values []float64) PatternType {
 	return PatternMixed
 }
 
-// DetectVolatilityExplosion detects sudden volatility expansion in BBW.
+// isMonotonic reports whether values strictly rise or strictly fall across the window.
+func isMonotonic(values []float64) (rising, falling bool) {
+	rising, falling = true, true
+	for k := 1; k < len(values); k++ {
+		rising = rising && values[k-1] < values[k]
+		falling = falling && values[k-1] > values[k]
+	}
+	return rising, falling
+}
+
+func allSigns(values []float64, ok func(float64) bool) bool {
+	for _, v := range values {
+		if !ok(v) {
+			return false
+		}
+	}
+	return true
+}
+
+// DetectVolatilityExplosion detects sudden volatility expansion in BBW: every step of the
+// window rises by more than thresholdRatio.
 // Example: 0.01 → 0.03 → 0.05 (폭발적 증가)
 func DetectVolatilityExplosion(values []float64, thresholdRatio float64) bool {
-	if len(values) != 3 {
+	if len(values) < minPatternBars {
 		return false
 	}
 
-	a, b, c := values[0], values[1], values[2]
-
-	// 변동성 폭발 조건: 직전 대비 두 번 연속 threshold 이상 상승
-	return (b > a*thresholdRatio) && (c > b*thresholdRatio)
+	// 변동성 폭발 조건: 직전 대비 매번 threshold 이상 상승
+	for k := 1; k < len(values); k++ {
+		if !(values[k] > values[k-1]*thresholdRatio) {
+			return false
+		}
+	}
+	return true
 }
 
-// DetectSpike detects a large spike in value.
+// DetectSpike detects a large spike in value: any step of the window rising by spikeDiff or more.
 // zscore 또는 vwzscore 급등 감지용
 func DetectSpike(values []float64, spikeDiff float64) bool {
-	if len(values) != 3 {
+	if len(values) < minPatternBars {
 		return false
 	}
 
-	a, b, c := values[0], values[1], values[2]
-
 	// 급격한 값 증가 (예: 1.0 → 3.0 → 2.9)
-	return (b-a >= spikeDiff) || (c-b >= spikeDiff)
+	for k := 1; k < len(values); k++ {
+		if values[k]-values[k-1] >= spikeDiff {
+			return true
+		}
+	}
+	return false
 }
 
 // IsTrendStrengthening checks if ADX is strengthening.
@@ -106,6 +138,7 @@ type SignalQuality struct {
 }
 
 func EvaluateSignal(
+	p config.CombinedConditionParams,
 	zscore, vwz, bbw, adx, plusDI, minusDI, dx []float64,
 ) string {
 
@@ -118,9 +151,9 @@ func EvaluateSignal(
 	dir := AnalyzeDirection(plusDI, minusDI, dx)
 
 	// --- 3) 부가 필터 ---
-	volExplosion := DetectVolatilityExplosion(bbw, 1.5)
-	zSpike := DetectSpike(zscore, 1.2)
-	vSpike := DetectSpike(vwz, 1.0)
+	volExplosion := DetectVolatilityExplosion(bbw, p.VolatilityRatio)
+	zSpike := DetectSpike(zscore, p.ZScoreSpike)
+	vSpike := DetectSpike(vwz, p.VWZSpike)
 
 	longCondition := zPattern == PatternIncreasing &&
 		vPattern == PatternIncreasing &&
@@ -0,0 +1,39 @@
+package strategy
+
+import "testing"
+
+func TestAnalyzePatternWindows(t *testing.T) {
+	cases := []struct {
+		values []float64
+		want   PatternType
+	}{
+		{[]float64{1, 2}, PatternMixed}, // too short to form a pattern
+		{[]float64{1, 2, 3}, PatternIncreasing},
+		{[]float64{1, 2, 3, 4, 5}, PatternIncreasing},
+		{[]float64{1, 2, 1.5, 4, 5}, PatternMixed}, // must hold across the whole window
+		{[]float64{5, 4, 3, 2}, PatternDecreasing},
+		{[]float64{-1, -2, -3, -4}, PatternIncreasing},
+		{[]float64{-4, -3, -2, -1}, PatternDecreasing},
+		{[]float64{-1, 1, 2, 3}, PatternMixed},
+	}
+	for _, c := range cases {
+		if got := AnalyzePattern(c.values); got != c.want {
+			t.Errorf("Expected AnalyzePattern(%v) to be %s, but got %s", c.values, c.want, got)
+		}
+	}
+}
+
+func TestDetectSpikeAndExplosionWindows(t *testing.T) {
+	if !DetectSpike([]float64{0, 0.1, 0.2, 2.0, 1.9}, 1.2) {
+		t.Error("Expected a spike anywhere in the window to be detected")
+	}
+	if DetectSpike([]float64{0, 1.0}, 0.5) {
+		t.Error("Expected no spike from a window shorter than three values")
+	}
+	if !DetectVolatilityExplosion([]float64{0.01, 0.02, 0.05, 0.1}, 1.5) {
+		t.Error("Expected an explosion when every step rises by more than the ratio")
+	}
+	if DetectVolatilityExplosion([]float64{0.01, 0.02, 0.025, 0.1}, 1.5) {
+		t.Error("Expected no explosion when one step rises by less than the ratio")
+	}
+}
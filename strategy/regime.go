@@ -0,0 +1,613 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"sort"
+	"strings"
+
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+	"gonum.org/v1/gonum/stat/distmv"
+)
+
+// Regime is the market regime of a candle, as labelled by ClassifyRegimes.
+type Regime string
+
+const (
+	RegimeTrendingUp     Regime = "trending_up"
+	RegimeTrendingDown   Regime = "trending_down"
+	RegimeRanging        Regime = "ranging"
+	RegimeHighVolatility Regime = "high_volatility"
+	RegimeLowVolatility  Regime = "low_volatility"
+	RegimeUnknown        Regime = "unknown"
+)
+
+// Regimes lists every regime label, in report order.
+var Regimes = []Regime{RegimeTrendingUp, RegimeTrendingDown, RegimeRanging, RegimeHighVolatility, RegimeLowVolatility, RegimeUnknown}
+
+// regimeInputs are the accepted values of config.RegimeConfig.Inputs.
+var regimeInputs = map[string]bool{"adx": true, "bbwz": true, "realized_vol": true}
+
+// regimeSettings is a RegimeConfig with its defaults applied.
+type regimeSettings struct {
+	method            string
+	adx, bbwz, rv     bool
+	trendADX          float64
+	highVolZ, lowVolZ float64
+	volWindow         int
+	volLookback       int
+	states            int
+	iterations        int
+	fitBars           int
+}
+
+// regimeSettingsFor validates cfg.Regime and applies its defaults.
+func regimeSettingsFor(cfg *config.Config) (regimeSettings, error) {
+	r := cfg.Regime
+	s := regimeSettings{
+		method:      r.Method,
+		trendADX:    r.TrendADX,
+		highVolZ:    r.HighVolZ,
+		lowVolZ:     r.LowVolZ,
+		volWindow:   r.VolWindow,
+		volLookback: r.VolLookback,
+		states:      r.HMM.States,
+		iterations:  r.HMM.Iterations,
+		fitBars:     r.HMM.FitBars,
+	}
+	if s.method == "" {
+		s.method = "rules"
+	}
+	if s.method != "rules" && s.method != "hmm" {
+		return s, fmt.Errorf("unknown regime method %q (available: rules, hmm)", r.Method)
+	}
+
+	inputs := r.Inputs
+	if len(inputs) == 0 {
+		inputs = []string{"adx", "bbwz", "realized_vol"}
+	}
+	for _, input := range inputs {
+		if !regimeInputs[input] {
+			return s, fmt.Errorf("unknown regime input %q (available: adx, bbwz, realized_vol)", input)
+		}
+	}
+	s.adx, s.bbwz, s.rv = contains(inputs, "adx"), contains(inputs, "bbwz"), contains(inputs, "realized_vol")
+
+	if s.trendADX == 0 {
+		s.trendADX = cfg.ADXThreshold
+	}
+	if s.highVolZ == 0 {
+		s.highVolZ = 1
+	}
+	if s.lowVolZ == 0 {
+		s.lowVolZ = -1
+	}
+	if s.lowVolZ >= s.highVolZ {
+		return s, fmt.Errorf("regime lowVolZ (%v) must be below highVolZ (%v)", s.lowVolZ, s.highVolZ)
+	}
+	if s.volWindow == 0 {
+		s.volWindow = 20
+	}
+	if s.volLookback == 0 {
+		s.volLookback = 100
+	}
+	if s.volWindow < 2 || s.volLookback < 2 {
+		return s, fmt.Errorf("regime volWindow and volLookback must be at least 2")
+	}
+	if s.states == 0 {
+		s.states = 3
+	}
+	if s.iterations == 0 {
+		s.iterations = 100
+	}
+	if s.states < 2 || s.iterations < 1 || s.fitBars < 0 {
+		return s, fmt.Errorf("regime hmm needs at least 2 states, 1 iteration and a non-negative fitBars")
+	}
+
+	for name, size := range r.Sizing {
+		if !contains(regimeNames(), name) {
+			return s, fmt.Errorf("unknown regime %q in sizing (available: %s)", name, strings.Join(regimeNames(), ", "))
+		}
+		if size < 0 {
+			return s, fmt.Errorf("regime sizing for %q must not be negative, got %v", name, size)
+		}
+	}
+	return s, nil
+}
+
+func regimeNames() []string {
+	names := make([]string, len(Regimes))
+	for k, r := range Regimes {
+		names[k] = string(r)
+	}
+	return names
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+// ClassifyRegimes labels every candle with a market regime. A candle with ADX at or above the
+// trend threshold is trending, up when +DI is above -DI; otherwise it is high or low volatility
+// when the mean of the volatility z-scores reaches HighVolZ or LowVolZ, and ranging in between.
+// With the "hmm" method the same rules label the mean of each hidden state, and every candle
+// takes the label of its most likely state given the candles up to it. warmup is the number of
+// candles before the trading window; the model is fitted on them, or on the first FitBars of
+// them, and never on candles of the trading window.
+func ClassifyRegimes(candles market.CandleSticks, indicators *IndicatorSet, warmup int, cfg *config.Config) ([]Regime, error) {
+	s, err := regimeSettingsFor(cfg)
+	if err != nil {
+		return nil, err
+	}
+	features := regimeFeatures(candles, indicators, s)
+
+	regimes := make([]Regime, len(candles))
+	if s.method == "rules" {
+		for i, row := range features {
+			regimes[i] = s.label(row)
+		}
+		return regimes, nil
+	}
+
+	// The model is only fitted on candles before the trading window, so no label depends on
+	// candles after it.
+	fitBars := s.fitBars
+	if fitBars == 0 {
+		fitBars = warmup
+	}
+	switch {
+	case fitBars == 0:
+		return nil, fmt.Errorf("regime hmm needs candles before the trading window to fit on; set startTime and warmupBars")
+	case fitBars > warmup:
+		return nil, fmt.Errorf("regime hmm fitBars %d reaches into the trading window, which starts at candle %d", fitBars, warmup)
+	}
+	model, err := fitHMM(features[:fitBars], s.states, s.iterations)
+	if err != nil {
+		return nil, fmt.Errorf("regime hmm: %w", err)
+	}
+	labels := make([]Regime, s.states)
+	for k, mean := range model.means() {
+		labels[k] = s.label(mean)
+	}
+	states, err := model.filter(features)
+	if err != nil {
+		return nil, fmt.Errorf("regime hmm: %w", err)
+	}
+	for i, state := range states {
+		regimes[i] = RegimeUnknown
+		if state >= 0 {
+			regimes[i] = labels[state]
+		}
+	}
+	return regimes, nil
+}
+
+// RegimeSize returns the position size multiplier configured for trades entered in regime.
+func RegimeSize(cfg *config.Config, regime Regime) float64 {
+	if size, ok := cfg.Regime.Sizing[string(regime)]; ok {
+		return size
+	}
+	return 1
+}
+
+// The feature row of a candle holds, for the enabled inputs and in this order: ADX, +DI - -DI,
+// the BBW z-score and the realized volatility z-score. Missing values are NaN.
+
+// regimeFeatures builds the feature row of every candle.
+func regimeFeatures(candles market.CandleSticks, indicators *IndicatorSet, s regimeSettings) [][]float64 {
+	var columns [][]float64
+	if s.adx {
+		adx, plus, minus := indicators.Get(SeriesADX), indicators.Get(SeriesPlusDI), indicators.Get(SeriesMinusDI)
+		spread := make([]float64, len(candles))
+		for i := range spread {
+			spread[i] = seriesAt(plus, i) - seriesAt(minus, i)
+		}
+		columns = append(columns, adx, spread)
+	}
+	if s.bbwz {
+		columns = append(columns, indicators.Get(SeriesBBWZ))
+	}
+	if s.rv {
+		columns = append(columns, rollingZ(RealizedVolatility(candles, s.volWindow), s.volLookback))
+	}
+
+	rows := make([][]float64, len(candles))
+	for i := range rows {
+		rows[i] = make([]float64, len(columns))
+		for k, column := range columns {
+			rows[i][k] = seriesAt(column, i)
+		}
+	}
+	return rows
+}
+
+// label applies the classification rules to a feature row.
+func (s regimeSettings) label(row []float64) Regime {
+	if hasNaN(row) {
+		return RegimeUnknown
+	}
+	k := 0
+	if s.adx {
+		adx, spread := row[0], row[1]
+		k = 2
+		if adx >= s.trendADX {
+			if spread > 0 {
+				return RegimeTrendingUp
+			}
+			return RegimeTrendingDown
+		}
+	}
+	if volatility := row[k:]; len(volatility) > 0 {
+		z := stat.Mean(volatility, nil)
+		switch {
+		case z >= s.highVolZ:
+			return RegimeHighVolatility
+		case z <= s.lowVolZ:
+			return RegimeLowVolatility
+		}
+	}
+	return RegimeRanging
+}
+
+// RealizedVolatility returns the sample standard deviation of the last period log returns at
+// every candle, NaN until period returns are available.
+func RealizedVolatility(candles market.CandleSticks, period int) []float64 {
+	result := make([]float64, len(candles))
+	window := make([]float64, period)
+	for i := range candles {
+		if i < period {
+			result[i] = math.NaN()
+			continue
+		}
+		for k := range window {
+			j := i - period + 1 + k
+			window[k] = math.Log(candles[j].Close / candles[j-1].Close)
+		}
+		result[i] = stat.StdDev(window, nil)
+	}
+	return result
+}
+
+// rollingZ scores each value against the lookback values before it, NaN while any of them is missing.
+func rollingZ(series []float64, lookback int) []float64 {
+	result := make([]float64, len(series))
+	window := make([]float64, lookback)
+	for i := range series {
+		result[i] = math.NaN()
+		if i < lookback || math.IsNaN(series[i-lookback]) || math.IsNaN(series[i]) {
+			continue
+		}
+		copy(window, series[i-lookback:i])
+		result[i] = windowZScore(window, series[i])
+	}
+	return result
+}
+
+func seriesAt(series []float64, i int) float64 {
+	if i < len(series) {
+		return series[i]
+	}
+	return math.NaN()
+}
+
+// hmm is a Gaussian hidden Markov model with diagonal covariances, fitted on standardized features.
+type hmm struct {
+	initial    []float64
+	transition [][]float64
+	mean, vari [][]float64 // per state, in standardized units
+	// center and scale standardize a feature row: (x - center) / scale.
+	center, scale []float64
+}
+
+// minHMMVariance keeps a state from collapsing onto a single standardized value.
+const minHMMVariance = 1e-3
+
+// fitHMM fits a model with the given number of states to the complete rows by Baum-Welch.
+func fitHMM(rows [][]float64, states, iterations int) (*hmm, error) {
+	var complete [][]float64
+	for _, row := range rows {
+		if len(row) > 0 && !hasNaN(row) {
+			complete = append(complete, row)
+		}
+	}
+	if len(complete) < 10*states {
+		return nil, fmt.Errorf("need at least %d complete candles to fit %d states, got %d", 10*states, states, len(complete))
+	}
+
+	dims := len(complete[0])
+	m := &hmm{center: make([]float64, dims), scale: make([]float64, dims)}
+	column := make([]float64, len(complete))
+	for d := range dims {
+		for t, row := range complete {
+			column[t] = row[d]
+		}
+		m.center[d], m.scale[d] = stat.MeanStdDev(column, nil)
+		if m.scale[d] == 0 {
+			m.scale[d] = 1
+		}
+	}
+	obs := make([][]float64, len(complete))
+	for t, row := range complete {
+		obs[t] = m.standardize(row)
+	}
+	m.initialize(obs, states)
+
+	prevLogLik := math.Inf(-1)
+	for range iterations {
+		logLik, err := m.step(obs)
+		if err != nil {
+			return nil, err
+		}
+		if logLik-prevLogLik < 1e-6 {
+			break
+		}
+		prevLogLik = logLik
+	}
+	return m, nil
+}
+
+func hasNaN(row []float64) bool {
+	for _, v := range row {
+		if math.IsNaN(v) {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *hmm) standardize(row []float64) []float64 {
+	x := make([]float64, len(row))
+	for d, v := range row {
+		x[d] = (v - m.center[d]) / m.scale[d]
+	}
+	return x
+}
+
+// initialize spreads the state means over quantiles of the observations ordered by their mean
+// feature, so fitting is deterministic.
+func (m *hmm) initialize(obs [][]float64, states int) {
+	order := make([]int, len(obs))
+	for t := range order {
+		order[t] = t
+	}
+	sort.SliceStable(order, func(a, b int) bool {
+		return stat.Mean(obs[order[a]], nil) < stat.Mean(obs[order[b]], nil)
+	})
+
+	dims := len(obs[0])
+	m.initial = make([]float64, states)
+	m.transition = make([][]float64, states)
+	m.mean = make([][]float64, states)
+	m.vari = make([][]float64, states)
+	for k := range states {
+		m.initial[k] = 1 / float64(states)
+		m.transition[k] = make([]float64, states)
+		for j := range states {
+			m.transition[k][j] = 0.1 / float64(states-1)
+		}
+		m.transition[k][k] = 0.9
+		m.mean[k] = append([]float64(nil), obs[order[(2*k+1)*len(obs)/(2*states)]]...)
+		m.vari[k] = make([]float64, dims)
+		for d := range dims {
+			m.vari[k][d] = 1
+		}
+	}
+}
+
+// distributions returns the emission distribution of every state.
+func (m *hmm) distributions() ([]*distmv.Normal, error) {
+	dists := make([]*distmv.Normal, len(m.mean))
+	for k := range m.mean {
+		var ok bool
+		dists[k], ok = distmv.NewNormal(m.mean[k], mat.NewDiagDense(len(m.vari[k]), m.vari[k]), nil)
+		if !ok {
+			return nil, fmt.Errorf("state %d has a degenerate covariance", k)
+		}
+	}
+	return dists, nil
+}
+
+// emissions returns each state's density at every observation, scaled per observation by its
+// largest log density (returned as offsets) to avoid underflow.
+func (m *hmm) emissions(obs [][]float64) (b [][]float64, offsets []float64, err error) {
+	dists, err := m.distributions()
+	if err != nil {
+		return nil, nil, err
+	}
+	b = make([][]float64, len(obs))
+	offsets = make([]float64, len(obs))
+	for t, x := range obs {
+		b[t] = make([]float64, len(dists))
+		offsets[t] = emission(dists, x, b[t])
+	}
+	return b, offsets, nil
+}
+
+// emission fills b with each distribution's density at x divided by the largest one, and
+// returns the log of that largest density.
+func emission(dists []*distmv.Normal, x []float64, b []float64) float64 {
+	offset := math.Inf(-1)
+	for k, dist := range dists {
+		b[k] = dist.LogProb(x)
+		offset = math.Max(offset, b[k])
+	}
+	for k := range b {
+		b[k] = math.Exp(b[k] - offset)
+	}
+	return offset
+}
+
+// step runs one Baum-Welch iteration and returns the log-likelihood of obs under the model it
+// started from.
+func (m *hmm) step(obs [][]float64) (float64, error) {
+	b, offsets, err := m.emissions(obs)
+	if err != nil {
+		return 0, err
+	}
+	n, states := len(obs), len(m.initial)
+
+	alpha := make([][]float64, n)
+	scale := make([]float64, n)
+	logLik := 0.0
+	for t := range n {
+		alpha[t] = make([]float64, states)
+		for j := range states {
+			if t == 0 {
+				alpha[t][j] = m.initial[j] * b[t][j]
+				continue
+			}
+			for i := range states {
+				alpha[t][j] += alpha[t-1][i] * m.transition[i][j]
+			}
+			alpha[t][j] *= b[t][j]
+		}
+		scale[t] = normalize(alpha[t])
+		logLik += math.Log(scale[t]) + offsets[t]
+	}
+
+	beta := make([][]float64, n)
+	beta[n-1] = make([]float64, states)
+	for k := range states {
+		beta[n-1][k] = 1
+	}
+	for t := n - 2; t >= 0; t-- {
+		beta[t] = make([]float64, states)
+		for i := range states {
+			for j := range states {
+				beta[t][i] += m.transition[i][j] * b[t+1][j] * beta[t+1][j]
+			}
+			beta[t][i] /= scale[t+1]
+		}
+	}
+
+	gamma := make([][]float64, n)
+	xi := make([][]float64, states)
+	for i := range xi {
+		xi[i] = make([]float64, states)
+	}
+	for t := range n {
+		gamma[t] = make([]float64, states)
+		for k := range states {
+			gamma[t][k] = alpha[t][k] * beta[t][k]
+		}
+		normalize(gamma[t])
+		if t == n-1 {
+			continue
+		}
+		for i := range states {
+			for j := range states {
+				xi[i][j] += alpha[t][i] * m.transition[i][j] * b[t+1][j] * beta[t+1][j] / scale[t+1]
+			}
+		}
+	}
+
+	copy(m.initial, gamma[0])
+	for i := range states {
+		copy(m.transition[i], xi[i])
+		normalize(m.transition[i])
+	}
+	dims := len(obs[0])
+	for k := range states {
+		weight := 0.0
+		mean, vari := make([]float64, dims), make([]float64, dims)
+		for t, x := range obs {
+			weight += gamma[t][k]
+			for d := range dims {
+				mean[d] += gamma[t][k] * x[d]
+			}
+		}
+		if weight == 0 {
+			continue // an unused state keeps its parameters
+		}
+		for d := range dims {
+			mean[d] /= weight
+		}
+		for t, x := range obs {
+			for d := range dims {
+				vari[d] += gamma[t][k] * (x[d] - mean[d]) * (x[d] - mean[d])
+			}
+		}
+		for d := range dims {
+			vari[d] = math.Max(vari[d]/weight, minHMMVariance)
+		}
+		m.mean[k], m.vari[k] = mean, vari
+	}
+	return logLik, nil
+}
+
+// normalize scales values to sum to 1 and returns their previous sum; all-zero values become uniform.
+func normalize(values []float64) float64 {
+	sum := 0.0
+	for _, v := range values {
+		sum += v
+	}
+	for k := range values {
+		if sum > 0 {
+			values[k] /= sum
+		} else {
+			values[k] = 1 / float64(len(values))
+		}
+	}
+	return sum
+}
+
+// means returns the state means in the original feature units.
+func (m *hmm) means() [][]float64 {
+	means := make([][]float64, len(m.mean))
+	for k, mean := range m.mean {
+		means[k] = make([]float64, len(mean))
+		for d, v := range mean {
+			means[k][d] = v*m.scale[d] + m.center[d]
+		}
+	}
+	return means
+}
+
+// filter returns the most likely state of every row given the rows up to it, or -1 for rows
+// with missing features, which only advance the state probabilities by the transitions.
+func (m *hmm) filter(rows [][]float64) ([]int, error) {
+	dists, err := m.distributions()
+	if err != nil {
+		return nil, err
+	}
+	states := make([]int, len(rows))
+	belief := append([]float64(nil), m.initial...)
+	next := make([]float64, len(belief))
+	b := make([]float64, len(belief))
+	for t, row := range rows {
+		if t > 0 {
+			for j := range next {
+				next[j] = 0
+				for i, p := range belief {
+					next[j] += p * m.transition[i][j]
+				}
+			}
+			copy(belief, next)
+		}
+		if hasNaN(row) {
+			states[t] = -1
+			continue
+		}
+		emission(dists, m.standardize(row), b)
+		for k := range belief {
+			belief[k] *= b[k]
+		}
+		normalize(belief)
+		best := 0
+		for k, p := range belief {
+			if p > belief[best] {
+				best = k
+			}
+		}
+		states[t] = best
+	}
+	return states, nil
+}
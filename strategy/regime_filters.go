@@ -0,0 +1,178 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"math"
+)
+
+// RegimeFilter reports whether the market at the indicators' candle is in the regime a
+// condition is allowed to trade in.
+type RegimeFilter func(indicators TechnicalIndicators) bool
+
+// defaultRegime is the ADX band every condition was gated on before regimes were configurable.
+var defaultRegime = []config.RegimeFilterConfig{{Type: "adx_band"}}
+
+// bbwStatuses are the BBW regimes a "bbw_state" filter may name.
+var bbwStatuses = map[MarketState]bool{
+	ExpandingBullish: true, ExpandingBearish: true, Squeeze: true, Neutral: true, Volatile: true,
+	InsufficientData: true, InsufficientATR: true, InsufficientBBW: true, InsufficientBBWSeries: true,
+}
+
+// NewRegimeFilter validates a regime filter configuration and returns the filter for a
+// condition trading in direction.
+func NewRegimeFilter(filter config.RegimeFilterConfig, direction string, cfg *config.Config) (RegimeFilter, error) {
+	switch filter.Type {
+	case "none":
+		return func(TechnicalIndicators) bool { return true }, nil
+
+	case "adx_band":
+		low, high := boundOr(filter.Min, cfg.ADXThreshold), boundOr(filter.Max, cfg.AdxUpperThreshold)
+		if low >= high {
+			return nil, fmt.Errorf("adx_band needs min < max, got %v and %v", low, high)
+		}
+		return func(indicators TechnicalIndicators) bool {
+			adx := last(indicators.ADX)
+			return adx > low && adx < high
+		}, nil
+
+	case "bbw_state":
+		if len(filter.States) == 0 {
+			return nil, fmt.Errorf("bbw_state needs at least one state")
+		}
+		allowed := make(map[MarketState]bool, len(filter.States))
+		for _, state := range filter.States {
+			if !bbwStatuses[MarketState(state)] {
+				return nil, fmt.Errorf("unknown BBW state %q", state)
+			}
+			allowed[MarketState(state)] = true
+		}
+		return func(indicators TechnicalIndicators) bool {
+			return allowed[indicators.BBState.Status]
+		}, nil
+
+	case "volatility_percentile":
+		window, low, high := filter.Window, boundOr(filter.Min, 0), boundOr(filter.Max, 100)
+		if window == 0 {
+			window = 100
+		}
+		if window < 2 {
+			return nil, fmt.Errorf("volatility_percentile window must be at least 2, got %d", window)
+		}
+		if low < 0 || high > 100 || low > high {
+			return nil, fmt.Errorf("volatility_percentile needs 0 <= min <= max <= 100, got %v and %v", low, high)
+		}
+		return func(indicators TechnicalIndicators) bool {
+			values, ok := indicators.Lookback(SeriesATR, window)
+			if !ok {
+				return false
+			}
+			rank := percentileRank(values)
+			return rank >= low && rank <= high
+		}, nil
+
+	case "regime":
+		if len(filter.States) == 0 {
+			return nil, fmt.Errorf("regime needs at least one state")
+		}
+		allowed := make(map[Regime]bool, len(filter.States))
+		for _, state := range filter.States {
+			if !contains(regimeNames(), state) {
+				return nil, fmt.Errorf("unknown regime %q", state)
+			}
+			allowed[Regime(state)] = true
+		}
+		return func(indicators TechnicalIndicators) bool {
+			return allowed[indicators.Regime]
+		}, nil
+
+	case "score":
+		path, threshold := filter.Model, boundOr(filter.Min, 0.5)
+		if path == "" {
+			path = cfg.Scoring.Model
+		}
+		if path == "" {
+			return nil, fmt.Errorf("score needs a model; train one with the score command")
+		}
+		if threshold < 0 || threshold > 1 {
+			return nil, fmt.Errorf("score threshold must be a probability, got %v", threshold)
+		}
+		model, err := LoadScoreModel(path)
+		if err != nil {
+			return nil, err
+		}
+		return func(indicators TechnicalIndicators) bool {
+			return model.Score(indicators, direction) >= threshold
+		}, nil
+
+	case "time_of_day":
+		if filter.Session == nil {
+			return nil, fmt.Errorf("time_of_day needs a session")
+		}
+		session, err := NewSession(*filter.Session)
+		if err != nil {
+			return nil, fmt.Errorf("time_of_day: %w", err)
+		}
+		return func(indicators TechnicalIndicators) bool {
+			return indicators.index < len(indicators.candles) && session.Contains(indicators.candles[indicators.index].Time)
+		}, nil
+	}
+	return nil, fmt.Errorf("unknown regime filter type %q", filter.Type)
+}
+
+// boundOr returns the configured bound, or def when it is left out.
+func boundOr(bound *float64, def float64) float64 {
+	if bound == nil {
+		return def
+	}
+	return *bound
+}
+
+// percentileRank returns the share of values, in percent, at or below the last one.
+// It is NaN when the last value is missing.
+func percentileRank(values []float64) float64 {
+	current := values[len(values)-1]
+	if math.IsNaN(current) {
+		return math.NaN()
+	}
+	below, counted := 0, 0
+	for _, v := range values {
+		if math.IsNaN(v) {
+			continue
+		}
+		counted++
+		if v <= current {
+			below++
+		}
+	}
+	return float64(below) / float64(counted) * 100
+}
+
+// withRegime gates condition on every filter in regime; a condition outside its regime
+// neither enters nor stops. A nil regime means the default ADX band. Each filter is recorded
+// as a "regime <type>" check.
+func withRegime(condition EntryCondition, regime []config.RegimeFilterConfig, direction string, cfg *config.Config) (EntryCondition, error) {
+	if regime == nil {
+		regime = defaultRegime
+	}
+	if len(regime) == 0 {
+		return condition, nil
+	}
+	filters := make([]RegimeFilter, len(regime))
+	names := make([]string, len(regime))
+	for k, r := range regime {
+		filter, err := NewRegimeFilter(r, direction, cfg)
+		if err != nil {
+			return nil, err
+		}
+		filters[k], names[k] = filter, "regime "+r.Type
+	}
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		for k, filter := range filters {
+			if !indicators.Check(names[k], math.NaN(), math.NaN(), filter(indicators)) {
+				return false, false
+			}
+		}
+		return condition(indicators)
+	}, nil
+}
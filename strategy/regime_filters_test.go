@@ -0,0 +1,142 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRegimeFilters(t *testing.T) {
+	cfg := &config.Config{ADXThreshold: 20, AdxUpperThreshold: 50}
+	candles := syntheticSource(3).GBM(200, 0, 0.01)
+	set := &IndicatorSet{series: map[string][]float64{SeriesATR: make([]float64, len(candles))}}
+	for i := range candles {
+		set.series[SeriesATR][i] = float64(i % 10) // the last ATR (199 % 10 = 9) is the highest
+	}
+	indicators := TechnicalIndicators{
+		ADX:        []float64{10, 12, 15},
+		BBState:    BBWState{Status: Squeeze},
+		indicators: set,
+		candles:    candles,
+		index:      len(candles) - 1,
+	}
+	at := candles[len(candles)-1].Time
+
+	tests := []struct {
+		filter config.RegimeFilterConfig
+		want   bool
+	}{
+		{config.RegimeFilterConfig{Type: "none"}, true},
+		{config.RegimeFilterConfig{Type: "adx_band"}, false},
+		{config.RegimeFilterConfig{Type: "adx_band", Min: bound(5), Max: bound(20)}, true},
+		{config.RegimeFilterConfig{Type: "bbw_state", States: []string{"Squeeze", "Neutral"}}, true},
+		{config.RegimeFilterConfig{Type: "bbw_state", States: []string{"Volatile"}}, false},
+		{config.RegimeFilterConfig{Type: "volatility_percentile", Window: 50, Min: bound(90)}, true},
+		{config.RegimeFilterConfig{Type: "volatility_percentile", Window: 50, Max: bound(50)}, false},
+		{config.RegimeFilterConfig{Type: "time_of_day", Session: &config.SessionConfig{
+			Timezone: "UTC", Start: at.Add(-time.Hour).Format("15:04"), End: at.Add(time.Hour).Format("15:04"),
+		}}, true},
+	}
+	for _, tt := range tests {
+		filter, err := NewRegimeFilter(tt.filter, "long", cfg)
+		if err != nil {
+			t.Errorf("%s: NewRegimeFilter failed: %v", tt.filter.Type, err)
+			continue
+		}
+		if got := filter(indicators); got != tt.want {
+			t.Errorf("Expected %+v to be %v, but got %v", tt.filter, tt.want, got)
+		}
+	}
+
+	for filter, want := range map[string]config.RegimeFilterConfig{
+		"unknown regime filter":    {Type: "moon_phase"},
+		"unknown BBW state":        {Type: "bbw_state", States: []string{"Sideways"}},
+		"needs a session":          {Type: "time_of_day"},
+		"min <= max":               {Type: "volatility_percentile", Min: bound(80), Max: bound(20)},
+		"adx_band needs min < max": {Type: "adx_band", Max: bound(cfg.ADXThreshold)},
+	} {
+		if _, err := NewRegimeFilter(want, "long", cfg); err == nil || !strings.Contains(err.Error(), filter) {
+			t.Errorf("Expected %+v to fail with %q, but got %v", want, filter, err)
+		}
+	}
+}
+
+func bound(v float64) *float64 {
+	return &v
+}
+
+func TestRegimeGatesResolvedConditions(t *testing.T) {
+	// "inverse" trades extremes of the BBW z-score, which often come with a low ADX.
+	lowADX := TechnicalIndicators{
+		ADX:       []float64{10, 10, 10},
+		BbwzScore: []float64{0, 0, 3},
+		EmaShort:  []float64{2, 2, 2},
+		EmaLong:   []float64{1, 1, 1},
+	}
+	cfg := &config.Config{ADXThreshold: 20, AdxUpperThreshold: 50, LongCondition: "inverse"}
+
+	gated, err := ResolveEntryCondition(cfg, "long")
+	if err != nil {
+		t.Fatalf("ResolveEntryCondition failed: %v", err)
+	}
+	if entry, _ := gated(lowADX); entry {
+		t.Error("Expected the default ADX band to block the entry")
+	}
+	verdict := Explain(gated, lowADX)
+	if len(verdict.Checks) != 1 || verdict.Checks[0].Name != "regime adx_band" || verdict.Checks[0].Passed {
+		t.Errorf("Expected a failed regime check, but got %+v", verdict.Checks)
+	}
+
+	cfg.LongRegime = []config.RegimeFilterConfig{{Type: "adx_band", Min: bound(5), Max: bound(20)}}
+	ranging, err := ResolveEntryCondition(cfg, "long")
+	if err != nil {
+		t.Fatalf("ResolveEntryCondition failed: %v", err)
+	}
+	if entry, _ := ranging(lowADX); !entry {
+		t.Error("Expected a low-ADX band to let the range entry through")
+	}
+
+	// An explicit 0 is a bound, not a request for the default adxThreshold.
+	cfg.LongRegime = []config.RegimeFilterConfig{{Type: "adx_band", Min: bound(0), Max: bound(20)}}
+	if ranging, err = ResolveEntryCondition(cfg, "long"); err != nil {
+		t.Fatalf("ResolveEntryCondition failed: %v", err)
+	}
+	if entry, _ := ranging(lowADX); !entry {
+		t.Error("Expected an adx_band from 0 to 20 to let the range entry through")
+	}
+
+	cfg.ConflictPolicy = "random"
+	if _, err := ResolveEntryCondition(cfg, "long"); err == nil {
+		t.Error("Expected an unknown conflict policy to be rejected")
+	}
+}
+
+func TestDetermineEntrySignalConflictPolicy(t *testing.T) {
+	enter := func(TechnicalIndicators) (bool, bool) { return true, false }
+	stop := func(TechnicalIndicators) (bool, bool) { return false, true }
+	idle := func(TechnicalIndicators) (bool, bool) { return false, false }
+
+	tests := []struct {
+		policy      string
+		long, short EntryCondition
+		direction   string
+		entry       bool
+	}{
+		{"", enter, enter, "long", true},
+		{"", stop, stop, "", false},
+		{"long_first", stop, enter, "short", true},
+		{"short_first", enter, enter, "short", true},
+		{"short_first", enter, stop, "long", true},
+		{"skip", enter, enter, "", false},
+		{"skip", stop, enter, "short", true},
+		{"skip", idle, enter, "short", true},
+		{"skip", enter, stop, "long", true},
+	}
+	for _, tt := range tests {
+		direction, entry, _ := DetermineEntrySignal(TechnicalIndicators{}, &config.Config{ConflictPolicy: tt.policy}, tt.long, tt.short)
+		if direction != tt.direction || entry != tt.entry {
+			t.Errorf("%q: Expected %q, %v, but got %q, %v", tt.policy, tt.direction, tt.entry, direction, entry)
+		}
+	}
+}
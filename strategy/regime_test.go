@@ -0,0 +1,222 @@
+package strategy
+
+import (
+	"bytes"
+	"encoding/json"
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestRegimeLabel(t *testing.T) {
+	s, err := regimeSettingsFor(&config.Config{ADXThreshold: 25})
+	if err != nil {
+		t.Fatalf("regimeSettingsFor failed: %v", err)
+	}
+	tests := []struct {
+		row  []float64 // adx, +DI - -DI, bbwz, realized volatility z-score
+		want Regime
+	}{
+		{[]float64{30, 5, 0, 0}, RegimeTrendingUp},
+		{[]float64{30, -5, 3, 3}, RegimeTrendingDown},
+		{[]float64{20, 5, 1.5, 0.5}, RegimeHighVolatility},
+		{[]float64{20, 5, -1, -1.5}, RegimeLowVolatility},
+		{[]float64{20, 5, 0.5, -0.5}, RegimeRanging},
+		{[]float64{math.NaN(), 5, 0, 0}, RegimeUnknown},
+	}
+	for _, tt := range tests {
+		if got := s.label(tt.row); got != tt.want {
+			t.Errorf("Expected %v to be %s, but got %s", tt.row, tt.want, got)
+		}
+	}
+}
+
+// regimeSwitchingData returns candles alternating between calm ranges and volatile trends,
+// with their indicators and the state that generated each candle.
+func regimeSwitchingData(t *testing.T, cfg *config.Config) (market.CandleSticks, *IndicatorSet, []market.SyntheticRegime) {
+	t.Helper()
+	candles, states := syntheticSource(7).RegimeSwitching(3000, market.RegimeSwitchingParams{
+		TrendDrift: 0.002, TrendVolatility: 0.01, RangeVolatility: 0.002, RangeReversion: 0.05, SwitchProb: 0.01,
+	})
+	indicators, err := ComputeIndicators(candles, cfg)
+	if err != nil {
+		t.Fatalf("ComputeIndicators failed: %v", err)
+	}
+	return candles, indicators, states
+}
+
+// regimeShare returns the share of candles generated in state that are labelled want.
+func regimeShare(regimes []Regime, states []market.SyntheticRegime, state market.SyntheticRegime, want Regime) float64 {
+	total, matched := 0, 0
+	for i, r := range regimes {
+		if states[i] != state || r == RegimeUnknown {
+			continue
+		}
+		total++
+		if r == want {
+			matched++
+		}
+	}
+	return float64(matched) / float64(total)
+}
+
+func TestClassifyRegimes(t *testing.T) {
+	for _, method := range []string{"rules", "hmm"} {
+		cfg := &config.Config{ADXPeriod: 14, BBWPeriod: 20, BBWMultiplier: 2, ADXThreshold: 25}
+		cfg.Regime = config.RegimeConfig{Method: method, Inputs: []string{"realized_vol"}}
+		candles, indicators, states := regimeSwitchingData(t, cfg)
+
+		regimes, err := ClassifyRegimes(candles, indicators, 1000, cfg)
+		if err != nil {
+			t.Fatalf("%s: ClassifyRegimes failed: %v", method, err)
+		}
+		if len(regimes) != len(candles) || regimes[0] != RegimeUnknown {
+			t.Fatalf("%s: Expected one regime per candle starting unknown, but got %d starting %s", method, len(regimes), regimes[0])
+		}
+		trending := regimeShare(regimes, states, market.RegimeTrendUp, RegimeHighVolatility)
+		ranging := regimeShare(regimes, states, market.RegimeRange, RegimeHighVolatility)
+		if trending <= 2*ranging {
+			t.Errorf("%s: Expected volatile trends to be high volatility far more often than calm ranges, but got %.2f and %.2f", method, trending, ranging)
+		}
+	}
+}
+
+func TestClassifyRegimesIgnoresLaterCandles(t *testing.T) {
+	const warmup, n = 1000, 1800
+	for _, method := range []string{"rules", "hmm"} {
+		cfg := &config.Config{ADXPeriod: 14, BBWPeriod: 20, BBWMultiplier: 2, ADXThreshold: 25}
+		cfg.Regime = config.RegimeConfig{Method: method}
+		candles, indicators, _ := regimeSwitchingData(t, cfg)
+		regimes, err := ClassifyRegimes(candles, indicators, warmup, cfg)
+		if err != nil {
+			t.Fatalf("%s: ClassifyRegimes failed: %v", method, err)
+		}
+
+		// Replace everything after candle n with a different market.
+		changed := append(market.CandleSticks{}, candles[:n]...)
+		for _, c := range syntheticSource(99).GBM(len(candles)-n, 0.001, 0.03) {
+			scale := candles[n-1].Close / 100
+			c.Time = candles[len(changed)].Time
+			c.Open, c.High, c.Low, c.Close = c.Open*scale, c.High*scale, c.Low*scale, c.Close*scale
+			changed = append(changed, c)
+		}
+		changedIndicators, err := ComputeIndicators(changed, cfg)
+		if err != nil {
+			t.Fatalf("ComputeIndicators failed: %v", err)
+		}
+		changedRegimes, err := ClassifyRegimes(changed, changedIndicators, warmup, cfg)
+		if err != nil {
+			t.Fatalf("%s: ClassifyRegimes failed: %v", method, err)
+		}
+		for i := range n {
+			if regimes[i] != changedRegimes[i] {
+				t.Fatalf("%s: Expected candle %d to keep regime %s when later candles change, but got %s", method, i, regimes[i], changedRegimes[i])
+			}
+		}
+	}
+}
+
+func TestClassifyRegimesHMMNeedsWarmup(t *testing.T) {
+	cfg := &config.Config{ADXPeriod: 14, BBWPeriod: 20, BBWMultiplier: 2, Regime: config.RegimeConfig{Method: "hmm"}}
+	candles, indicators, _ := regimeSwitchingData(t, cfg)
+	if _, err := ClassifyRegimes(candles, indicators, 0, cfg); err == nil || !strings.Contains(err.Error(), "before the trading window") {
+		t.Errorf("Expected the hmm method to refuse fitting without warmup candles, but got %v", err)
+	}
+}
+
+func TestClassifyRegimesRejectsInvalidConfig(t *testing.T) {
+	tests := map[string]config.RegimeConfig{
+		"unknown regime method":       {Method: "neural"},
+		"unknown regime input":        {Inputs: []string{"rsi"}},
+		"must be below":               {HighVolZ: -2},
+		"unknown regime \"trending\"": {Sizing: map[string]float64{"trending": 1}},
+		"must not be negative":        {Sizing: map[string]float64{"ranging": -1}},
+		"complete candles":            {Method: "hmm", HMM: config.HMMConfig{FitBars: 50}},
+		"reaches into the trading":    {Method: "hmm", HMM: config.HMMConfig{FitBars: 80}},
+	}
+	for want, regime := range tests {
+		cfg := &config.Config{ADXPeriod: 14, BBWPeriod: 20, BBWMultiplier: 2, Regime: regime}
+		candles := syntheticSource(1).GBM(300, 0, 0.004)
+		indicators, err := ComputeIndicators(candles, cfg)
+		if err != nil {
+			t.Fatalf("ComputeIndicators failed: %v", err)
+		}
+		if _, err := ClassifyRegimes(candles, indicators, 50, cfg); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("Expected %+v to fail with %q, but got %v", regime, want, err)
+		}
+	}
+}
+
+func TestRunBacktestSizesByRegime(t *testing.T) {
+	cfg := &config.Config{
+		FilePath:      "test_data.csv",
+		VWZPeriod:     5,
+		EmaPeriod:     5,
+		ADXPeriod:     5,
+		TPRate:        1,
+		SLRate:        1,
+		BBWPeriod:     20,
+		BBWMultiplier: 2.0,
+		LongExits:     []string{"max_bars"},
+		ShortExits:    []string{"max_bars"},
+		ExitParams:    config.ExitParams{MaxBars: config.MaxBarsExitParams{Bars: 3}},
+	}
+	strategyData, err := InitializeStrategyDataContext(cfg)
+	if err != nil {
+		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
+	}
+	strategyData.Regimes = make([]Regime, len(strategyData.Candles))
+	for i := range strategyData.Regimes {
+		strategyData.Regimes[i] = RegimeRanging
+	}
+	exits, err := ResolveExits(cfg, DefaultLongCondition, DefaultShortCondition)
+	if err != nil {
+		t.Fatalf("ResolveExits failed: %v", err)
+	}
+
+	base := RunBacktest(strategyData, cfg, DefaultLongCondition, DefaultShortCondition, exits)
+	if base.TotalTrades == 0 {
+		t.Fatal("Expected the default conditions to trade on the test data")
+	}
+	if trade := base.Trades[0]; trade.Regime != RegimeRanging || trade.Size != 1 {
+		t.Errorf("Expected trades entered ranging at size 1, but got %s at %v", trade.Regime, trade.Size)
+	}
+
+	cfg.Regime.Sizing = map[string]float64{"ranging": 2}
+	doubled := RunBacktest(strategyData, cfg, DefaultLongCondition, DefaultShortCondition, exits)
+	if math.Abs(doubled.TotalPnl-2*base.TotalPnl) > 1e-9 {
+		t.Errorf("Expected size 2 to double the PnL of %v, but got %v", base.TotalPnl, doubled.TotalPnl)
+	}
+	for k, trade := range doubled.Trades {
+		if math.Abs(trade.PnlPercentage-base.Trades[k].PnlPercentage) > 1e-9 {
+			t.Errorf("Expected size 2 to keep the return of trade %d at %v%%, but got %v%%", k, base.Trades[k].PnlPercentage, trade.PnlPercentage)
+		}
+	}
+
+	cfg.Regime.Sizing = map[string]float64{"ranging": 0}
+	var buf bytes.Buffer
+	strategyData.Trace = NewDecisionTrace(&buf)
+	if skipped := RunBacktest(strategyData, cfg, DefaultLongCondition, DefaultShortCondition, exits); skipped.TotalTrades != 0 {
+		t.Errorf("Expected no trades in a regime sized 0, but got %d", skipped.TotalTrades)
+	}
+	if err := strategyData.Trace.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+	for _, line := range bytes.Split(bytes.TrimSpace(buf.Bytes()), []byte("\n")) {
+		var decision struct{ Entry bool }
+		if err := json.Unmarshal(line, &decision); err != nil || decision.Entry {
+			t.Fatalf("Expected signals skipped by regime sizing traced as rejected entries, but got %s", line)
+		}
+	}
+	sized := 0
+	for _, r := range strategyData.Trace.Rejections() {
+		if strings.HasSuffix(r.Check, " regime_sizing") {
+			sized += r.Count
+		}
+	}
+	if sized == 0 {
+		t.Errorf("Expected the skipped signals counted as regime_sizing rejections, but got %v", strategyData.Trace.Rejections())
+	}
+}
@@ -0,0 +1,560 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"math"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Rules are boolean expressions over indicator series, written in the configuration and
+// compiled once into closures:
+//
+//	ema_short[-1] > ema_long[-1] && adx crosses_above 20 && vwz[0] < -1.5
+//
+// A series is any registered indicator name (e.g. "macd.signal", "adx_1h.plus_di") or a
+// candle field: open, high, low, close, volume. x[0] is the current candle and x[-1] the one
+// before it; a bare name means x[0]. Positive offsets would look ahead and are rejected.
+//
+// Numbers support + - * / and the functions abs(x), min(a, b) and max(a, b). Conditions are
+// comparisons (< <= > >= == !=), "a crosses_above b" / "a crosses_below b", rising(x, n) and
+// falling(x, n) (strictly rising or falling over the last n bars), combined with && / and,
+// || / or, ! / not and parentheses. A comparison involving a missing value (not enough
+// history, or NaN) is false.
+
+// numberExpr evaluates a numeric expression shifted back by shift bars.
+type numberExpr func(t *TechnicalIndicators, shift int) float64
+
+// boolExpr evaluates a condition shifted back by shift bars.
+type boolExpr func(t *TechnicalIndicators, shift int) bool
+
+// Rule is a compiled rule expression.
+type Rule struct {
+	source string
+	eval   boolExpr
+}
+
+// String returns the rule's source text.
+func (r *Rule) String() string {
+	return r.source
+}
+
+// Eval evaluates the rule at the indicators' candle.
+func (r *Rule) Eval(t TechnicalIndicators) bool {
+	return r.eval(&t, 0)
+}
+
+// candleFields are the candle values a rule can reference besides indicator series.
+var candleFields = map[string]bool{"open": true, "high": true, "low": true, "close": true, "volume": true}
+
+// CompileRule parses and type-checks a rule. known reports whether a series name exists;
+// a nil known accepts every name.
+func CompileRule(source string, known func(name string) bool) (*Rule, error) {
+	tokens, err := lexRule(source)
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", source, err)
+	}
+	p := &ruleParser{tokens: tokens, known: known}
+	n, err := p.parseOr()
+	if err == nil && p.peek().kind != tokEOF {
+		err = p.errorf("unexpected %s", p.peek())
+	}
+	if err != nil {
+		return nil, fmt.Errorf("rule %q: %w", source, err)
+	}
+	if n.boolean == nil {
+		return nil, fmt.Errorf("rule %q: expression is a number, not a condition", source)
+	}
+	return &Rule{source: source, eval: n.boolean}, nil
+}
+
+// CompileRuleCondition compiles entry and stop rules into an EntryCondition. The stop rule
+// is optional; when it holds, the open trade in this direction is closed.
+func CompileRuleCondition(rule config.RuleConfig, cfg *config.Config) (EntryCondition, error) {
+	known := DeclaredSeriesNames(cfg)
+	entry, err := CompileRule(rule.Entry, known)
+	if err != nil {
+		return nil, err
+	}
+	var stop *Rule
+	if rule.Stop != "" {
+		if stop, err = CompileRule(rule.Stop, known); err != nil {
+			return nil, err
+		}
+	}
+	return func(indicators TechnicalIndicators) (bool, bool) {
+		passed := indicators.Check("rule "+entry.source, math.NaN(), math.NaN(), entry.Eval(indicators))
+		return passed, stop != nil && stop.Eval(indicators)
+	}, nil
+}
+
+// DeclaredSeriesNames returns a lookup of the series the configuration declares, so rules
+// can be checked before any candles are loaded.
+func DeclaredSeriesNames(cfg *config.Config) func(name string) bool {
+	names := map[string]bool{}
+	for _, decl := range mergeIndicatorConfigs(DefaultIndicators(cfg), cfg.Indicators) {
+		typ, ok := indicatorTypes[decl.Type]
+		if !ok {
+			continue
+		}
+		for k, output := range typ.outputs {
+			if k == 0 {
+				names[decl.Name] = true
+			} else {
+				names[decl.Name+"."+output] = true
+			}
+		}
+	}
+	for alias, target := range seriesAliases {
+		if names[target] {
+			names[alias] = true
+		}
+	}
+	return func(name string) bool { return names[name] }
+}
+
+// ruleNode is a parsed expression: exactly one of number and boolean is set.
+type ruleNode struct {
+	number  numberExpr
+	boolean boolExpr
+}
+
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokNumber
+	tokIdent
+	tokOp
+)
+
+type ruleToken struct {
+	kind  tokenKind
+	text  string
+	value float64
+	pos   int
+}
+
+func (t ruleToken) String() string {
+	if t.kind == tokEOF {
+		return "end of rule"
+	}
+	return fmt.Sprintf("%q at position %d", t.text, t.pos+1)
+}
+
+// lexRule splits a rule into tokens. Identifiers may contain dots and underscores.
+func lexRule(source string) ([]ruleToken, error) {
+	var tokens []ruleToken
+	for i := 0; i < len(source); {
+		c := rune(source[i])
+		switch {
+		case unicode.IsSpace(c):
+			i++
+		case unicode.IsDigit(c) || (c == '.' && i+1 < len(source) && unicode.IsDigit(rune(source[i+1]))):
+			j := i
+			for j < len(source) && (unicode.IsDigit(rune(source[j])) || source[j] == '.' || source[j] == 'e' ||
+				((source[j] == '-' || source[j] == '+') && j > i && source[j-1] == 'e')) {
+				j++
+			}
+			v, err := strconv.ParseFloat(source[i:j], 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid number %q at position %d", source[i:j], i+1)
+			}
+			tokens = append(tokens, ruleToken{kind: tokNumber, text: source[i:j], value: v, pos: i})
+			i = j
+		case unicode.IsLetter(c) || c == '_':
+			j := i
+			for j < len(source) && (unicode.IsLetter(rune(source[j])) || unicode.IsDigit(rune(source[j])) || source[j] == '_' || source[j] == '.') {
+				j++
+			}
+			tokens = append(tokens, ruleToken{kind: tokIdent, text: source[i:j], pos: i})
+			i = j
+		default:
+			op := ""
+			for _, candidate := range []string{"&&", "||", "<=", ">=", "==", "!=", "<", ">", "!", "+", "-", "*", "/", "(", ")", "[", "]", ","} {
+				if strings.HasPrefix(source[i:], candidate) {
+					op = candidate
+					break
+				}
+			}
+			if op == "" {
+				return nil, fmt.Errorf("unexpected character %q at position %d", c, i+1)
+			}
+			tokens = append(tokens, ruleToken{kind: tokOp, text: op, pos: i})
+			i += len(op)
+		}
+	}
+	return append(tokens, ruleToken{kind: tokEOF, pos: len(source)}), nil
+}
+
+type ruleParser struct {
+	tokens []ruleToken
+	pos    int
+	known  func(name string) bool
+}
+
+func (p *ruleParser) peek() ruleToken {
+	return p.tokens[p.pos]
+}
+
+func (p *ruleParser) next() ruleToken {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords.
+func (p *ruleParser) accept(texts ...string) (string, bool) {
+	t := p.peek()
+	if t.kind != tokOp && t.kind != tokIdent {
+		return "", false
+	}
+	for _, text := range texts {
+		if t.text == text {
+			p.pos++
+			return text, true
+		}
+	}
+	return "", false
+}
+
+func (p *ruleParser) expect(text string) error {
+	if _, ok := p.accept(text); !ok {
+		return p.errorf("expected %q but found %s", text, p.peek())
+	}
+	return nil
+}
+
+func (p *ruleParser) errorf(format string, args ...any) error {
+	return fmt.Errorf(format, args...)
+}
+
+func (p *ruleParser) parseOr() (ruleNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return left, err
+	}
+	for {
+		if _, ok := p.accept("||", "or"); !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return right, err
+		}
+		if left, err = p.logical("||", left, right); err != nil {
+			return left, err
+		}
+	}
+}
+
+func (p *ruleParser) parseAnd() (ruleNode, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return left, err
+	}
+	for {
+		if _, ok := p.accept("&&", "and"); !ok {
+			return left, nil
+		}
+		right, err := p.parseNot()
+		if err != nil {
+			return right, err
+		}
+		if left, err = p.logical("&&", left, right); err != nil {
+			return left, err
+		}
+	}
+}
+
+func (p *ruleParser) logical(op string, left, right ruleNode) (ruleNode, error) {
+	if left.boolean == nil || right.boolean == nil {
+		return ruleNode{}, p.errorf("%q needs conditions on both sides, not numbers", op)
+	}
+	l, r := left.boolean, right.boolean
+	if op == "&&" {
+		return ruleNode{boolean: func(t *TechnicalIndicators, shift int) bool { return l(t, shift) && r(t, shift) }}, nil
+	}
+	return ruleNode{boolean: func(t *TechnicalIndicators, shift int) bool { return l(t, shift) || r(t, shift) }}, nil
+}
+
+func (p *ruleParser) parseNot() (ruleNode, error) {
+	if _, ok := p.accept("!", "not"); ok {
+		operand, err := p.parseNot()
+		if err != nil {
+			return operand, err
+		}
+		if operand.boolean == nil {
+			return ruleNode{}, p.errorf("\"not\" needs a condition, not a number")
+		}
+		b := operand.boolean
+		return ruleNode{boolean: func(t *TechnicalIndicators, shift int) bool { return !b(t, shift) }}, nil
+	}
+	return p.parseComparison()
+}
+
+func (p *ruleParser) parseComparison() (ruleNode, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return left, err
+	}
+	op, ok := p.accept("<", "<=", ">", ">=", "==", "!=", "crosses_above", "crosses_below")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return right, err
+	}
+	if left.number == nil || right.number == nil {
+		return ruleNode{}, p.errorf("%q compares numbers, not conditions", op)
+	}
+	l, r := left.number, right.number
+
+	var cmp func(a, b float64) bool
+	switch op {
+	case "<":
+		cmp = func(a, b float64) bool { return a < b }
+	case "<=":
+		cmp = func(a, b float64) bool { return a <= b }
+	case ">":
+		cmp = func(a, b float64) bool { return a > b }
+	case ">=":
+		cmp = func(a, b float64) bool { return a >= b }
+	case "==":
+		cmp = func(a, b float64) bool { return a == b }
+	case "!=":
+		// NaN != x is true in Go; a missing value must not satisfy a rule.
+		cmp = func(a, b float64) bool { return !math.IsNaN(a) && !math.IsNaN(b) && a != b }
+	case "crosses_above":
+		return ruleNode{boolean: func(t *TechnicalIndicators, shift int) bool {
+			return l(t, shift+1) <= r(t, shift+1) && l(t, shift) > r(t, shift)
+		}}, nil
+	case "crosses_below":
+		return ruleNode{boolean: func(t *TechnicalIndicators, shift int) bool {
+			return l(t, shift+1) >= r(t, shift+1) && l(t, shift) < r(t, shift)
+		}}, nil
+	}
+	return ruleNode{boolean: func(t *TechnicalIndicators, shift int) bool { return cmp(l(t, shift), r(t, shift)) }}, nil
+}
+
+func (p *ruleParser) parseAdditive() (ruleNode, error) {
+	left, err := p.parseTerm()
+	if err != nil {
+		return left, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseTerm()
+		if err != nil {
+			return right, err
+		}
+		if left, err = p.arithmetic(op, left, right); err != nil {
+			return left, err
+		}
+	}
+}
+
+func (p *ruleParser) parseTerm() (ruleNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return left, err
+	}
+	for {
+		op, ok := p.accept("*", "/")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return right, err
+		}
+		if left, err = p.arithmetic(op, left, right); err != nil {
+			return left, err
+		}
+	}
+}
+
+func (p *ruleParser) arithmetic(op string, left, right ruleNode) (ruleNode, error) {
+	if left.number == nil || right.number == nil {
+		return ruleNode{}, p.errorf("%q needs numbers, not conditions", op)
+	}
+	l, r := left.number, right.number
+	switch op {
+	case "+":
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 { return l(t, shift) + r(t, shift) }}, nil
+	case "-":
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 { return l(t, shift) - r(t, shift) }}, nil
+	case "*":
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 { return l(t, shift) * r(t, shift) }}, nil
+	default:
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 { return l(t, shift) / r(t, shift) }}, nil
+	}
+}
+
+func (p *ruleParser) parseUnary() (ruleNode, error) {
+	if _, ok := p.accept("-"); ok {
+		operand, err := p.parseUnary()
+		if err != nil {
+			return operand, err
+		}
+		if operand.number == nil {
+			return ruleNode{}, p.errorf("unary \"-\" needs a number, not a condition")
+		}
+		n := operand.number
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 { return -n(t, shift) }}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *ruleParser) parsePrimary() (ruleNode, error) {
+	t := p.next()
+	switch {
+	case t.kind == tokNumber:
+		v := t.value
+		return ruleNode{number: func(*TechnicalIndicators, int) float64 { return v }}, nil
+	case t.kind == tokOp && t.text == "(":
+		n, err := p.parseOr()
+		if err != nil {
+			return n, err
+		}
+		return n, p.expect(")")
+	case t.kind == tokIdent && (t.text == "true" || t.text == "false"):
+		v := t.text == "true"
+		return ruleNode{boolean: func(*TechnicalIndicators, int) bool { return v }}, nil
+	case t.kind == tokIdent && p.peek().kind == tokOp && p.peek().text == "(":
+		p.next()
+		return p.parseCall(t)
+	case t.kind == tokIdent:
+		return p.parseSeries(t)
+	}
+	return ruleNode{}, p.errorf("unexpected %s", t)
+}
+
+// parseSeries parses a series reference with an optional [offset].
+func (p *ruleParser) parseSeries(t ruleToken) (ruleNode, error) {
+	name := t.text
+	if !candleFields[name] && p.known != nil && !p.known(name) {
+		return ruleNode{}, p.errorf("unknown series %q at position %d", name, t.pos+1)
+	}
+
+	offset := 0
+	if _, ok := p.accept("["); ok {
+		negative := false
+		if _, ok := p.accept("-"); ok {
+			negative = true
+		}
+		n := p.next()
+		if n.kind != tokNumber || n.value != math.Trunc(n.value) {
+			return ruleNode{}, p.errorf("expected an integer offset but found %s", n)
+		}
+		offset = int(n.value)
+		if negative {
+			offset = -offset
+		}
+		if offset > 0 {
+			return ruleNode{}, p.errorf("offset [%d] of %q would look ahead; use [0] or a negative offset", offset, name)
+		}
+		if err := p.expect("]"); err != nil {
+			return ruleNode{}, err
+		}
+	}
+
+	back := -offset
+	if candleFields[name] {
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 {
+			return t.candleField(name, back+shift)
+		}}, nil
+	}
+	return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 {
+		v, _ := t.Ago(name, back+shift)
+		return v
+	}}, nil
+}
+
+// parseCall parses a function call after its opening parenthesis.
+func (p *ruleParser) parseCall(fn ruleToken) (ruleNode, error) {
+	var args []ruleNode
+	if _, ok := p.accept(")"); !ok {
+		for {
+			arg, err := p.parseOr()
+			if err != nil {
+				return arg, err
+			}
+			args = append(args, arg)
+			if _, ok := p.accept(","); ok {
+				continue
+			}
+			if err := p.expect(")"); err != nil {
+				return ruleNode{}, err
+			}
+			break
+		}
+	}
+
+	numbers := func(want int) ([]numberExpr, error) {
+		if len(args) != want {
+			return nil, p.errorf("%s() takes %d arguments, got %d", fn.text, want, len(args))
+		}
+		out := make([]numberExpr, want)
+		for k, a := range args {
+			if a.number == nil {
+				return nil, p.errorf("argument %d of %s() must be a number, not a condition", k+1, fn.text)
+			}
+			out[k] = a.number
+		}
+		return out, nil
+	}
+
+	switch fn.text {
+	case "abs":
+		a, err := numbers(1)
+		if err != nil {
+			return ruleNode{}, err
+		}
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 { return math.Abs(a[0](t, shift)) }}, nil
+	case "min", "max":
+		a, err := numbers(2)
+		if err != nil {
+			return ruleNode{}, err
+		}
+		pick := math.Min
+		if fn.text == "max" {
+			pick = math.Max
+		}
+		return ruleNode{number: func(t *TechnicalIndicators, shift int) float64 { return pick(a[0](t, shift), a[1](t, shift)) }}, nil
+	case "rising", "falling":
+		a, err := numbers(2)
+		if err != nil {
+			return ruleNode{}, err
+		}
+		bars, ok := constantInt(a[1])
+		if !ok || bars < 1 {
+			return ruleNode{}, p.errorf("the bar count of %s() must be a positive integer", fn.text)
+		}
+		x, rising := a[0], fn.text == "rising"
+		return ruleNode{boolean: func(t *TechnicalIndicators, shift int) bool {
+			for k := 0; k < bars; k++ {
+				cur, prev := x(t, shift+k), x(t, shift+k+1)
+				if rising && !(cur > prev) || !rising && !(cur < prev) {
+					return false
+				}
+			}
+			return true
+		}}, nil
+	}
+	return ruleNode{}, p.errorf("unknown function %q at position %d", fn.text, fn.pos+1)
+}
+
+// constantInt evaluates an expression that does not depend on any series.
+func constantInt(n numberExpr) (int, bool) {
+	v := n(&TechnicalIndicators{}, 0)
+	return int(v), v == math.Trunc(v) && !math.IsNaN(v)
+}
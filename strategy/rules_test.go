@@ -0,0 +1,135 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"strings"
+	"testing"
+)
+
+// ruleIndicators returns indicators at the last of five candles over hand-made series.
+func ruleIndicators() TechnicalIndicators {
+	set := &IndicatorSet{series: map[string][]float64{
+		"fast": {1, 2, 3, 4, 6},
+		"slow": {3, 3, 3, 3, 3},
+		"adx":  {15, 18, 19, 22, 25},
+		"gap":  {math.NaN(), math.NaN(), 1, 2, 3},
+	}}
+	candles := make(market.CandleSticks, 5)
+	for i := range candles {
+		candles[i].Close = float64(100 + i)
+	}
+	return TechnicalIndicators{indicators: set, candles: candles, index: 4, depth: 3}
+}
+
+func TestRuleEvaluation(t *testing.T) {
+	indicators := ruleIndicators()
+	tests := []struct {
+		rule string
+		want bool
+	}{
+		{"fast > slow", true},
+		{"fast[-2] > slow[-2]", false},
+		{"fast[-1] > slow[-1] && fast[-2] == slow[-2]", true},
+		{"fast[-2] crosses_above slow[-2]", false},
+		{"fast[-1] crosses_above slow[-1]", true},
+		{"fast crosses_above slow", false},
+		{"slow[-1] crosses_below fast[-1]", true},
+		{"adx crosses_above 20", false},
+		{"adx[-1] crosses_above 20", true},
+		{"rising(fast, 4)", true},
+		{"rising(fast, 5)", false},
+		{"falling(fast, 1)", false},
+		{"not rising(slow, 1) and !falling(slow, 1)", true},
+		{"close - close[-1] == 1 && close == 104", true},
+		{"(fast - slow) / slow > 0.9 || false", true},
+		{"abs(-fast) == max(fast, slow) and min(fast, slow) == 3", true},
+		{"fast * 2 + 1 >= 13", true},
+		// Comparisons on missing history or NaN never hold.
+		{"gap[-3] < 100", false},
+		{"gap[-3] != 100", false},
+		{"rising(gap, 3)", false},
+		{"fast[-10] < 100", false},
+	}
+	for _, tt := range tests {
+		rule, err := CompileRule(tt.rule, nil)
+		if err != nil {
+			t.Errorf("Expected %q to compile, but got %v", tt.rule, err)
+			continue
+		}
+		if got := rule.Eval(indicators); got != tt.want {
+			t.Errorf("Expected %q to be %v, but got %v", tt.rule, tt.want, got)
+		}
+	}
+}
+
+func TestRuleCompileErrors(t *testing.T) {
+	known := func(name string) bool { return name == "fast" || name == "slow" }
+	tests := []struct {
+		rule string
+		want string
+	}{
+		{"fast > missing", `unknown series "missing"`},
+		{"fast[1] > slow", "would look ahead"},
+		{"fast[-1.5] > slow", "integer offset"},
+		{"fast + slow", "not a condition"},
+		{"fast && slow", "needs conditions"},
+		{"(fast > slow) + 1 > 0", "needs numbers"},
+		{"rising(fast, slow)", "positive integer"},
+		{"rising(fast)", "takes 2 arguments"},
+		{"median(fast) > 1", `unknown function "median"`},
+		{"fast > slow)", "unexpected"},
+		{"fast > slow $", "unexpected character"},
+		{"fast >", "end of rule"},
+	}
+	for _, tt := range tests {
+		_, err := CompileRule(tt.rule, known)
+		if err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected compiling %q to fail with %q, but got %v", tt.rule, tt.want, err)
+		}
+	}
+}
+
+func TestRuleConditionUsesDeclaredSeries(t *testing.T) {
+	cfg := &config.Config{
+		EmaPeriod:         5,
+		ADXPeriod:         5,
+		ADXThreshold:      20,
+		AdxUpperThreshold: 50,
+		Indicators: []config.IndicatorConfig{
+			{Name: "fast_macd", Type: "macd", Params: map[string]float64{"fast": 3, "slow": 6, "signal": 3}},
+		},
+	}
+	if _, err := CompileRuleCondition(config.RuleConfig{Entry: "fast_macd.signal < fast_macd && vwz[0] < -1.5", Stop: "adx < 20"}, cfg); err != nil {
+		t.Errorf("Expected declared series to be accepted, but got %v", err)
+	}
+	if _, err := CompileRuleCondition(config.RuleConfig{Entry: "ema_short > 0", Stop: "slow_macd > 0"}, cfg); err == nil {
+		t.Error("Expected an undeclared series in the stop rule to be rejected")
+	}
+
+	cfg.LongRule = config.RuleConfig{Entry: "close > 0", Stop: "close < 0"}
+	cfg.LongRegime = []config.RegimeFilterConfig{}
+	cfg.ShortCondition = "macd"
+	long, err := ResolveEntryCondition(cfg, "long")
+	if err != nil {
+		t.Fatalf("ResolveEntryCondition failed: %v", err)
+	}
+	if entry, stop := long(ruleIndicators()); !entry || stop {
+		t.Errorf("Expected the long rule to enter without stopping, but got %v, %v", entry, stop)
+	}
+	if _, err := ResolveEntryCondition(cfg, "short"); err != nil {
+		t.Errorf("Expected the short side to fall back to the named condition, but got %v", err)
+	}
+}
+
+func BenchmarkRuleEval(b *testing.B) {
+	rule, err := CompileRule("fast[-1] > slow[-1] && adx crosses_above 20 && rising(fast, 3)", nil)
+	if err != nil {
+		b.Fatal(err)
+	}
+	indicators := ruleIndicators()
+	for b.Loop() {
+		rule.Eval(indicators)
+	}
+}
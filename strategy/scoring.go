@@ -0,0 +1,281 @@
+package strategy
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-backtesting/config"
+	"math"
+	"os"
+	"slices"
+
+	"gonum.org/v1/gonum/floats"
+	"gonum.org/v1/gonum/mat"
+	"gonum.org/v1/gonum/stat"
+)
+
+// ScoreFeatureNames names the features ScoreFeatures extracts, in order. Features marked signed
+// are multiplied by -1 for short signals, so a positive value always favours the signal.
+var ScoreFeatureNames = []string{
+	"zscore",         // signed
+	"vwz",            // signed
+	"bbwz",           // BBW z-score
+	"adx",            // ADX
+	"adx_change",     // ADX change over the last scoreADXChangeBars candles
+	"di_spread",      // +DI - -DI, signed
+	"dx",             // DX
+	"macd_histogram", // signed
+	"ema_spread",     // short EMA over long EMA - 1, signed
+}
+
+// scoreADXChangeBars is how many candles back adx_change compares the ADX with. It does not
+// follow lookbackDepth, so a model scores the same feature whatever depth it runs with.
+const scoreADXChangeBars = 2
+
+// ScoreFeatures returns the feature vector of a signal in direction at the indicators' candle,
+// or false when any feature is missing.
+func ScoreFeatures(indicators TechnicalIndicators, direction string) ([]float64, bool) {
+	sign := 1.0
+	if direction == "short" {
+		sign = -1
+	}
+	adx := ago(indicators.ADX, 0)
+	pastADX, _ := indicators.Ago(SeriesADX, scoreADXChangeBars)
+	features := []float64{
+		sign * ago(indicators.ZScore, 0),
+		sign * ago(indicators.VWZScore, 0),
+		ago(indicators.BbwzScore, 0),
+		adx,
+		adx - pastADX,
+		sign * (ago(indicators.PlusDI, 0) - ago(indicators.MinusDI, 0)),
+		ago(indicators.DX, 0),
+		sign * ago(indicators.MACDHistogram, 0),
+		sign * (ago(indicators.EmaShort, 0)/ago(indicators.EmaLong, 0) - 1),
+	}
+	for _, v := range features {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return nil, false
+		}
+	}
+	return features, true
+}
+
+// ScoreModel is a logistic regression estimating the probability that a signal's trade wins.
+// It standardizes each feature with Center and Scale before applying Weights and Bias.
+type ScoreModel struct {
+	Features []string  `json:"features"`
+	Center   []float64 `json:"center"`
+	Scale    []float64 `json:"scale"`
+	Weights  []float64 `json:"weights"`
+	Bias     float64   `json:"bias"`
+	// ADXChangeBars is the span of the adx_change feature the model was trained on.
+	ADXChangeBars int `json:"adxChangeBars"`
+}
+
+// Probability returns the estimated probability that a trade with the given features wins.
+func (m *ScoreModel) Probability(features []float64) float64 {
+	z := m.Bias
+	for k, x := range features {
+		z += m.Weights[k] * (x - m.Center[k]) / m.Scale[k]
+	}
+	return sigmoid(z)
+}
+
+// Score returns the probability that a signal in direction at the indicators' candle wins,
+// or NaN when a feature is missing.
+func (m *ScoreModel) Score(indicators TechnicalIndicators, direction string) float64 {
+	features, ok := ScoreFeatures(indicators, direction)
+	if !ok {
+		return math.NaN()
+	}
+	return m.Probability(features)
+}
+
+func sigmoid(z float64) float64 {
+	return 1 / (1 + math.Exp(-z))
+}
+
+// Save writes the model as JSON to path.
+func (m *ScoreModel) Save(path string) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0o644); err != nil {
+		return fmt.Errorf("error writing score model: %w", err)
+	}
+	return nil
+}
+
+// LoadScoreModel reads a model written by Save and checks it was trained on the current features.
+func LoadScoreModel(path string) (*ScoreModel, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("error reading score model: %w", err)
+	}
+	var m ScoreModel
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("error decoding score model %s: %w", path, err)
+	}
+	if !slices.Equal(m.Features, ScoreFeatureNames) {
+		return nil, fmt.Errorf("score model %s was trained on features %v, not %v; retrain it", path, m.Features, ScoreFeatureNames)
+	}
+	if m.ADXChangeBars != scoreADXChangeBars {
+		return nil, fmt.Errorf("score model %s measures adx_change over %d candles, not %d; retrain it", path, m.ADXChangeBars, scoreADXChangeBars)
+	}
+	if len(m.Center) != len(m.Features) || len(m.Scale) != len(m.Features) || len(m.Weights) != len(m.Features) {
+		return nil, fmt.Errorf("score model %s has %d features but %d centers, %d scales and %d weights",
+			path, len(m.Features), len(m.Center), len(m.Scale), len(m.Weights))
+	}
+	return &m, nil
+}
+
+// ScoreReport describes how a model was trained and how it does on the held-out trades.
+type ScoreReport struct {
+	TrainTrades int
+	TestTrades  int
+	// Skipped counts trades left out because a feature was missing at entry.
+	Skipped       int
+	TrainAccuracy float64
+	TestAccuracy  float64
+	TestLogLoss   float64
+	// TestBaseRate is the share of winning test trades, the accuracy of always predicting a win.
+	TestBaseRate float64
+}
+
+// TrainScoreModel fits a model to the trades, labelled by whether they won, using the earliest
+// trades for training and holding out the last p.TestFraction (default 0.3) for the report.
+// The weights carry an L2 penalty of p.Lambda (default 1).
+func TrainScoreModel(trades []Trade, p config.ScoringConfig) (*ScoreModel, ScoreReport, error) {
+	lambda, testFraction := p.Lambda, p.TestFraction
+	if lambda == 0 {
+		lambda = 1
+	}
+	if testFraction == 0 {
+		testFraction = 0.3
+	}
+	if lambda < 0 || testFraction < 0 || testFraction >= 1 {
+		return nil, ScoreReport{}, fmt.Errorf("scoring needs lambda >= 0 and 0 <= testFraction < 1, got %v and %v", lambda, testFraction)
+	}
+
+	var x [][]float64
+	var y []float64
+	report := ScoreReport{}
+	for _, trade := range trades {
+		features, ok := ScoreFeatures(trade.EntryIndicators, trade.Direction)
+		if !ok {
+			report.Skipped++
+			continue
+		}
+		x = append(x, features)
+		y = append(y, winLabel(trade))
+	}
+
+	split := int(float64(len(x)) * (1 - testFraction))
+	report.TrainTrades, report.TestTrades = split, len(x)-split
+	if split < 2*len(ScoreFeatureNames) {
+		return nil, report, fmt.Errorf("need at least %d trades to train on, got %d", 2*len(ScoreFeatureNames), split)
+	}
+	if wins := floats.Sum(y[:split]); wins == 0 || wins == float64(split) {
+		return nil, report, fmt.Errorf("the training trades need both wins and losses")
+	}
+
+	m := &ScoreModel{Features: slices.Clone(ScoreFeatureNames), ADXChangeBars: scoreADXChangeBars}
+	if err := m.fit(x[:split], y[:split], lambda); err != nil {
+		return nil, report, err
+	}
+	report.TrainAccuracy, _ = m.evaluate(x[:split], y[:split])
+	if report.TestTrades > 0 {
+		report.TestAccuracy, report.TestLogLoss = m.evaluate(x[split:], y[split:])
+		report.TestBaseRate = floats.Sum(y[split:]) / float64(report.TestTrades)
+	}
+	return m, report, nil
+}
+
+func winLabel(trade Trade) float64 {
+	if trade.Pnl > 0 {
+		return 1
+	}
+	return 0
+}
+
+// fit standardizes the features and finds the penalized maximum-likelihood weights by Newton's
+// method; the bias is not penalized.
+func (m *ScoreModel) fit(x [][]float64, y []float64, lambda float64) error {
+	n, dims := len(x), len(x[0])
+	m.Center, m.Scale = make([]float64, dims), make([]float64, dims)
+	column := make([]float64, n)
+	for d := range dims {
+		for t, row := range x {
+			column[t] = row[d]
+		}
+		m.Center[d], m.Scale[d] = stat.MeanStdDev(column, nil)
+		if m.Scale[d] == 0 {
+			m.Scale[d] = 1
+		}
+	}
+
+	// The design matrix has the intercept in its last column.
+	design := mat.NewDense(n, dims+1, nil)
+	for t, row := range x {
+		for d, v := range row {
+			design.Set(t, d, (v-m.Center[d])/m.Scale[d])
+		}
+		design.Set(t, dims, 1)
+	}
+
+	w := make([]float64, dims+1)
+	for range 100 {
+		grad := make([]float64, dims+1)
+		hessian := mat.NewSymDense(dims+1, nil)
+		for t := range n {
+			row := design.RawRowView(t)
+			p := sigmoid(floats.Dot(row, w))
+			for a := range row {
+				grad[a] += (p - y[t]) * row[a]
+				for b := a; b < len(row); b++ {
+					hessian.SetSym(a, b, hessian.At(a, b)+p*(1-p)*row[a]*row[b])
+				}
+			}
+		}
+		for d := range dims {
+			grad[d] += lambda * w[d]
+			hessian.SetSym(d, d, hessian.At(d, d)+lambda)
+		}
+
+		var chol mat.Cholesky
+		if !chol.Factorize(hessian) {
+			return fmt.Errorf("score model does not converge; try a larger lambda")
+		}
+		var step mat.VecDense
+		if err := chol.SolveVecTo(&step, mat.NewVecDense(dims+1, grad)); err != nil {
+			return fmt.Errorf("score model does not converge: %w", err)
+		}
+		largest := 0.0
+		for k := range w {
+			w[k] -= step.AtVec(k)
+			largest = math.Max(largest, math.Abs(step.AtVec(k)))
+		}
+		if largest < 1e-9 {
+			break
+		}
+	}
+	m.Weights, m.Bias = w[:dims], w[dims]
+	return nil
+}
+
+// evaluate returns the accuracy of predicting a win at probability 0.5 and the mean log loss.
+func (m *ScoreModel) evaluate(x [][]float64, y []float64) (accuracy, logLoss float64) {
+	const eps = 1e-12
+	for t, features := range x {
+		p := m.Probability(features)
+		if (p >= 0.5) == (y[t] == 1) {
+			accuracy++
+		}
+		if y[t] == 1 {
+			logLoss -= math.Log(math.Max(p, eps))
+		} else {
+			logLoss -= math.Log(math.Max(1-p, eps))
+		}
+	}
+	return accuracy / float64(len(x)), logLoss / float64(len(x))
+}
@@ -0,0 +1,170 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"math/rand/v2"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// scoredTrades returns n trades whose chance of winning rises with the DI spread in their
+// direction, all other features being noise.
+func scoredTrades(n int) []Trade {
+	r := rand.New(rand.NewPCG(5, 6))
+	noise := func() []float64 { return []float64{r.NormFloat64(), r.NormFloat64(), r.NormFloat64()} }
+	// Each trade enters at its own candle of a shared ADX series, so adx_change has its history.
+	adx := make([]float64, n+scoreADXChangeBars)
+	for i := range adx {
+		adx[i] = 20 + r.NormFloat64()
+	}
+	set := &IndicatorSet{series: map[string][]float64{SeriesADX: adx}}
+	trades := make([]Trade, n)
+	for k := range trades {
+		index := k + scoreADXChangeBars
+		direction := "long"
+		if k%2 == 1 {
+			direction = "short"
+		}
+		spread := 10 * r.NormFloat64()
+		plus, minus := 20+spread/2, 20-spread/2
+		if direction == "short" {
+			plus, minus = minus, plus
+		}
+		pnl := -1.0
+		if r.Float64() < sigmoid(spread/3) {
+			pnl = 1
+		}
+		trades[k] = Trade{
+			Direction: direction,
+			Pnl:       pnl,
+			EntryIndicators: TechnicalIndicators{
+				ZScore: noise(), VWZScore: noise(), BbwzScore: noise(), DX: noise(), MACDHistogram: noise(),
+				ADX:      adx[index-2 : index+1],
+				PlusDI:   []float64{plus, plus, plus},
+				MinusDI:  []float64{minus, minus, minus},
+				EmaShort: []float64{100 + r.NormFloat64()}, EmaLong: []float64{100},
+				indicators: set,
+				index:      index,
+			},
+		}
+	}
+	return trades
+}
+
+func TestTrainScoreModel(t *testing.T) {
+	trades := scoredTrades(600)
+	model, report, err := TrainScoreModel(trades, config.ScoringConfig{})
+	if err != nil {
+		t.Fatalf("TrainScoreModel failed: %v", err)
+	}
+	if report.TrainTrades != 420 || report.TestTrades != 180 {
+		t.Errorf("Expected a 420/180 time-ordered split, but got %d/%d", report.TrainTrades, report.TestTrades)
+	}
+
+	spread := 0
+	for k, name := range model.Features {
+		if name == "di_spread" {
+			spread = k
+		}
+	}
+	for k, weight := range model.Weights {
+		if k != spread && weight >= model.Weights[spread] {
+			t.Errorf("Expected di_spread to carry the largest weight, but %s has %.3f against %.3f", model.Features[k], weight, model.Weights[spread])
+		}
+	}
+	if report.TestAccuracy < 0.7 || report.TestAccuracy <= report.TestBaseRate {
+		t.Errorf("Expected the model to beat the base rate of %.2f on held-out trades, but got %.2f", report.TestBaseRate, report.TestAccuracy)
+	}
+
+	strong, weak := trades[0].EntryIndicators, trades[0].EntryIndicators
+	strong.PlusDI, strong.MinusDI = []float64{35}, []float64{5}
+	weak.PlusDI, weak.MinusDI = []float64{5}, []float64{35}
+	if p := model.Score(strong, "long"); p < 0.9 {
+		t.Errorf("Expected a wide DI spread to score high for a long, but got %.3f", p)
+	}
+	if p := model.Score(strong, "short"); p > 0.1 {
+		t.Errorf("Expected the same spread to score low for a short, but got %.3f", p)
+	}
+
+	// A "score" filter only lets signals above its threshold through.
+	path := filepath.Join(t.TempDir(), "score.json")
+	if err := model.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	cfg := &config.Config{Scoring: config.ScoringConfig{Model: path}}
+	filter, err := NewRegimeFilter(config.RegimeFilterConfig{Type: "score", Min: bound(0.6)}, "long", cfg)
+	if err != nil {
+		t.Fatalf("NewRegimeFilter failed: %v", err)
+	}
+	if !filter(strong) || filter(weak) || filter(TechnicalIndicators{}) {
+		t.Error("Expected the score filter to pass only the strong signal")
+	}
+}
+
+func TestTrainScoreModelErrors(t *testing.T) {
+	trades := scoredTrades(600)
+	losing := make([]Trade, 400)
+	for k := range losing {
+		losing[k] = trades[k]
+		losing[k].Pnl = -1
+	}
+
+	tests := []struct {
+		trades []Trade
+		p      config.ScoringConfig
+		want   string
+	}{
+		{trades[:20], config.ScoringConfig{}, "need at least 18 trades"},
+		{losing, config.ScoringConfig{}, "both wins and losses"},
+		{trades, config.ScoringConfig{TestFraction: 1}, "testFraction < 1"},
+	}
+	for _, tt := range tests {
+		if _, _, err := TrainScoreModel(tt.trades, tt.p); err == nil || !strings.Contains(err.Error(), tt.want) {
+			t.Errorf("Expected training to fail with %q, but got %v", tt.want, err)
+		}
+	}
+	if _, err := NewRegimeFilter(config.RegimeFilterConfig{Type: "score"}, "long", &config.Config{}); err == nil {
+		t.Error("Expected a score filter without a model to be rejected")
+	}
+}
+
+func TestScoreFeaturesIgnoreLookbackDepth(t *testing.T) {
+	candles := syntheticSource(9).GBM(200, 0, 0.005)
+	cfg := &config.Config{EmaPeriod: 5, ADXPeriod: 5, BBWPeriod: 20, BBWMultiplier: 2, VWZPeriod: 5}
+	set, err := ComputeIndicators(candles, cfg)
+	if err != nil {
+		t.Fatalf("ComputeIndicators failed: %v", err)
+	}
+	s := &StrategyDataContext{Candles: candles, Indicators: set}
+	shallow, deep := *cfg, *cfg
+	shallow.LookbackDepth, deep.LookbackDepth = 2, 6
+
+	want, ok := ScoreFeatures(s.createTechnicalIndicators(150, &shallow), "long")
+	if !ok {
+		t.Fatal("Expected every feature at candle 150")
+	}
+	got, _ := ScoreFeatures(s.createTechnicalIndicators(150, &deep), "long")
+	if !slices.Equal(got, want) {
+		t.Errorf("Expected the same features at lookback depths 2 and 6, but got %v and %v", want, got)
+	}
+	if adx := set.Get(SeriesADX); want[4] != adx[150]-adx[150-scoreADXChangeBars] {
+		t.Errorf("Expected adx_change to span %d candles, but got %f", scoreADXChangeBars, want[4])
+	}
+}
+
+func TestLoadScoreModelRejectsADXChangeSpan(t *testing.T) {
+	model, _, err := TrainScoreModel(scoredTrades(600), config.ScoringConfig{})
+	if err != nil {
+		t.Fatalf("TrainScoreModel failed: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "score.json")
+	model.ADXChangeBars = scoreADXChangeBars + 1
+	if err := model.Save(path); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	if _, err := LoadScoreModel(path); err == nil || !strings.Contains(err.Error(), "retrain") {
+		t.Errorf("Expected a model with another adx_change span to be rejected, but got %v", err)
+	}
+}
@@ -0,0 +1,103 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"strings"
+	"time"
+)
+
+// Session is a recurring trading window in a named timezone.
+type Session struct {
+	loc   *time.Location
+	start int // minutes after local midnight
+	end   int // minutes after local midnight, exclusive
+	days  map[time.Weekday]bool
+}
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// NewSession validates a session configuration and returns the corresponding Session.
+func NewSession(cfg config.SessionConfig) (*Session, error) {
+	if cfg.Timezone == "" {
+		return nil, fmt.Errorf("session timezone is required")
+	}
+	loc, err := time.LoadLocation(cfg.Timezone)
+	if err != nil {
+		return nil, fmt.Errorf("unknown session timezone %q: %w", cfg.Timezone, err)
+	}
+	start, err := parseClock(cfg.Start)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session start: %w", err)
+	}
+	end, err := parseClock(cfg.End)
+	if err != nil {
+		return nil, fmt.Errorf("invalid session end: %w", err)
+	}
+	if start == end {
+		return nil, fmt.Errorf("session start and end must differ")
+	}
+
+	var days map[time.Weekday]bool
+	if len(cfg.Days) > 0 {
+		days = make(map[time.Weekday]bool, len(cfg.Days))
+		for _, d := range cfg.Days {
+			name := strings.ToLower(strings.TrimSpace(d))
+			if len(name) > 3 {
+				name = name[:3] // accept "Monday" as well as "Mon"
+			}
+			wd, ok := weekdays[name]
+			if !ok {
+				return nil, fmt.Errorf("invalid session day: %q", d)
+			}
+			days[wd] = true
+		}
+	}
+
+	return &Session{loc: loc, start: start, end: end, days: days}, nil
+}
+
+// Contains reports whether t falls inside the session. For sessions spanning midnight
+// the weekday is that of the session open, so a Mon 22:00-02:00 session includes Tue 01:00.
+func (s *Session) Contains(t time.Time) bool {
+	local := t.In(s.loc)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+
+	if s.start < s.end {
+		return minute >= s.start && minute < s.end && s.openOn(day)
+	}
+	if minute >= s.start {
+		return s.openOn(day)
+	}
+	if minute < s.end {
+		return s.openOn((day + 6) % 7)
+	}
+	return false
+}
+
+// inSession reports whether entries are allowed at candle i.
+func (s *StrategyDataContext) inSession(i int) bool {
+	return s.Session == nil || s.Session.Contains(s.Candles[i].Time)
+}
+
+func (s *Session) openOn(day time.Weekday) bool {
+	return s.days == nil || s.days[day]
+}
+
+// parseClock parses an "HH:MM" wall-clock time into minutes after midnight.
+func parseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("expected HH:MM, got %q", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
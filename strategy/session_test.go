@@ -0,0 +1,71 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"testing"
+	"time"
+)
+
+func TestSessionContains(t *testing.T) {
+	session, err := NewSession(config.SessionConfig{
+		Timezone: "Asia/Seoul",
+		Start:    "09:00",
+		End:      "15:30",
+		Days:     []string{"Mon", "Tue", "Wed", "Thu", "Fri"},
+	})
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+
+	cases := []struct {
+		utc      time.Time
+		expected bool
+	}{
+		{time.Date(2023, 1, 2, 0, 0, 0, 0, time.UTC), true},    // Mon 09:00 KST
+		{time.Date(2023, 1, 1, 23, 59, 0, 0, time.UTC), false}, // Mon 08:59 KST
+		{time.Date(2023, 1, 2, 6, 30, 0, 0, time.UTC), false},  // Mon 15:30 KST, end is exclusive
+		{time.Date(2023, 1, 7, 1, 0, 0, 0, time.UTC), false},   // Sat 10:00 KST
+	}
+	for _, c := range cases {
+		if got := session.Contains(c.utc); got != c.expected {
+			t.Errorf("Contains(%v): expected %v, but got %v", c.utc, c.expected, got)
+		}
+	}
+}
+
+func TestSessionSpanningMidnight(t *testing.T) {
+	session, err := NewSession(config.SessionConfig{
+		Timezone: "UTC",
+		Start:    "22:00",
+		End:      "02:00",
+		Days:     []string{"Monday"},
+	})
+	if err != nil {
+		t.Fatalf("NewSession failed: %v", err)
+	}
+
+	if !session.Contains(time.Date(2023, 1, 2, 23, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Monday 23:00 to be inside the session")
+	}
+	if !session.Contains(time.Date(2023, 1, 3, 1, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Tuesday 01:00 to belong to the Monday session")
+	}
+	if session.Contains(time.Date(2023, 1, 2, 1, 0, 0, 0, time.UTC)) {
+		t.Error("Expected Monday 01:00 to belong to the closed Sunday session")
+	}
+}
+
+func TestNewSessionValidation(t *testing.T) {
+	invalid := []config.SessionConfig{
+		{Start: "09:00", End: "17:00"},
+		{Timezone: "Mars/Olympus", Start: "09:00", End: "17:00"},
+		{Timezone: "UTC", Start: "9am", End: "17:00"},
+		{Timezone: "UTC", Start: "09:00", End: "09:00"},
+		{Timezone: "UTC", Start: "09:00", End: "17:00", Days: []string{"Someday"}},
+	}
+	for _, cfg := range invalid {
+		if _, err := NewSession(cfg); err == nil {
+			t.Errorf("Expected NewSession(%+v) to fail", cfg)
+		}
+	}
+}
@@ -22,6 +22,27 @@ type Trade struct {
 	Pnl             float64
 	PnlPercentage   float64
 	EntryIndicators TechnicalIndicators
+	// ExitReason is ExitTakeProfit, ExitStopLoss or the name of the exit condition that closed the trade.
+	ExitReason string
+	// Regime is the market regime at entry and Size the position size it was entered with,
+	// in units of the traded instrument; Pnl scales with Size, PnlPercentage (the return on the
+	// position) does not.
+	Regime Regime
+	Size   float64
+}
+
+// Exit reasons for the price-level exits.
+const (
+	ExitTakeProfit = "take_profit"
+	ExitStopLoss   = "stop_loss"
+)
+
+// Levels returns the take-profit and stop-loss prices of the trade for the given rates.
+func (t Trade) Levels(takeProfitRate, stopLossRate float64) (takeProfit, stopLoss float64) {
+	if t.Direction == "long" {
+		return t.EntryPrice * (1 + takeProfitRate), t.EntryPrice * (1 - stopLossRate)
+	}
+	return t.EntryPrice * (1 - takeProfitRate), t.EntryPrice * (1 + stopLossRate)
 }
 
 // BacktestResult contains the results of a backtest.
@@ -34,10 +55,14 @@ type BacktestResult struct {
 	WinRate     float64
 }
 
-// RunBacktest runs a backtest and returns the results.
-func RunBacktest(strategyData *StrategyDataContext, config *config.Config, longCondition EntryCondition, shortCondition EntryCondition) BacktestResult {
+// RunBacktest runs a backtest and returns the results. An open trade is closed at the take-profit
+// or stop-loss price levels, or when one of the exits for its direction holds. Trades are sized
+// by the regime at entry (see RegimeSize), and no trade is entered in a regime sized 0.
+func RunBacktest(strategyData *StrategyDataContext, config *config.Config, longCondition EntryCondition, shortCondition EntryCondition, exits Exits) BacktestResult {
 	var activeTrade *Trade
+	var openTrade OpenTrade
 	var completedTrades []Trade
+	lastExit := -1
 
 	takeProfitPct := config.TPRate // 1% take profit
 	stopLossPct := config.SLRate   // 1% stop loss
@@ -47,76 +72,59 @@ func RunBacktest(strategyData *StrategyDataContext, config *config.Config, longC
 
 		// --- 1. Exit Logic: Check if there is an active trade ---
 		if activeTrade != nil {
-			indicators := strategyData.createTechnicalIndicators(i, config)
-			direction, entry, stop := DetermineEntrySignal(indicators, config, longCondition, shortCondition)
-			isPriceThresholdBreached := false
+			indicators := strategyData.createTechnicalIndicators(i, config).withLastExit(lastExit)
+			if strategyData.Trace != nil {
+				strategyData.traceDecision(i, "exit", indicators, config, longCondition, shortCondition)
+			}
+			openTrade.update(i, currentCandle)
+
+			exitReason := ""
+			takeProfitPrice, stopLossPrice := activeTrade.Levels(takeProfitPct, stopLossPct)
 			if activeTrade.Direction == "long" {
-				takeProfitPrice := activeTrade.EntryPrice * (1 + takeProfitPct)
-				stopLossPrice := activeTrade.EntryPrice * (1 - stopLossPct)
-				if currentCandle.High >= takeProfitPrice ||
-					(entry && direction == "short") ||
-					currentCandle.High <= stopLossPrice ||
-					(stop && direction == "long") {
-					isPriceThresholdBreached = true
+				if currentCandle.High >= takeProfitPrice {
+					exitReason = ExitTakeProfit
+				} else if currentCandle.Low <= stopLossPrice {
+					exitReason = ExitStopLoss
 				}
 			} else { // short
-				takeProfitPrice := activeTrade.EntryPrice * (1 - takeProfitPct)
-				stopLossPrice := activeTrade.EntryPrice * (1 + stopLossPct)
-				if currentCandle.Low <= takeProfitPrice ||
-					(entry && direction == "long") ||
-					currentCandle.High >= stopLossPrice ||
-					(stop && direction == "short") {
-					isPriceThresholdBreached = true
+				if currentCandle.Low <= takeProfitPrice {
+					exitReason = ExitTakeProfit
+				} else if currentCandle.High >= stopLossPrice {
+					exitReason = ExitStopLoss
 				}
 			}
-
-			finalExitTrigger := false
-			if isPriceThresholdBreached {
-				shouldHold := false
-				if activeTrade.Direction == "long" &&
-					i < len(strategyData.PlusDI) &&
-					i < len(strategyData.MinusDI) &&
-					strategyData.PlusDI[i] > strategyData.MinusDI[i] &&
-					strategyData.AdxSeries[i-1] < strategyData.AdxSeries[i] {
-					shouldHold = false
-				} else if activeTrade.Direction == "short" &&
-					i < len(strategyData.PlusDI) &&
-					i < len(strategyData.MinusDI) &&
-					strategyData.AdxSeries[i-1] < strategyData.AdxSeries[i] &&
-					strategyData.MinusDI[i] > strategyData.PlusDI[i] {
-					shouldHold = false
-				}
-
-				if !shouldHold {
-					finalExitTrigger = true
+			if exitReason == "" {
+				for _, exit := range exits.For(activeTrade.Direction) {
+					if exit.Condition(indicators, openTrade) {
+						exitReason = exit.Name
+						break
+					}
 				}
 			}
-
-			if finalExitTrigger {
+			if exitReason != "" {
 				exitPrice := currentCandle.Close
 				activeTrade.ExitTime = currentCandle.Time
 				activeTrade.ExitPrice = exitPrice
+				activeTrade.ExitReason = exitReason
 				if activeTrade.Direction == "long" {
-					activeTrade.Pnl = activeTrade.ExitPrice - activeTrade.EntryPrice
+					activeTrade.Pnl = (activeTrade.ExitPrice - activeTrade.EntryPrice) * activeTrade.Size
 				} else {
-					activeTrade.Pnl = activeTrade.EntryPrice - activeTrade.ExitPrice
+					activeTrade.Pnl = (activeTrade.EntryPrice - activeTrade.ExitPrice) * activeTrade.Size
 				}
-				activeTrade.PnlPercentage = (activeTrade.Pnl / activeTrade.EntryPrice) * 100
+				activeTrade.PnlPercentage = (activeTrade.Pnl / (activeTrade.EntryPrice * activeTrade.Size)) * 100
 				completedTrades = append(completedTrades, *activeTrade)
 				activeTrade = nil // Close the position
+				lastExit = i
 			}
 		}
 
 		// --- 2. Entry Logic: Only enter if there is no active trade ---
 		if activeTrade == nil {
-			if i < 14 { // Hardcoded ATR period in DetectBBWState
+			if !strategyData.canSignal(i, config) {
 				continue
 			}
-			if i < config.VWZPeriod-1 || i < config.ADXPeriod-1 {
-				continue
-			}
-			indicators := strategyData.createTechnicalIndicators(i, config)
-			direction, entry, _ := DetermineEntrySignal(indicators, config, longCondition, shortCondition)
+			indicators := strategyData.createTechnicalIndicators(i, config).withLastExit(lastExit)
+			direction, entry, _ := strategyData.traceDecision(i, "entry", indicators, config, longCondition, shortCondition)
 
 			if entry {
 				activeTrade = &Trade{
@@ -124,7 +132,10 @@ func RunBacktest(strategyData *StrategyDataContext, config *config.Config, longC
 					EntryPrice:      currentCandle.Close,
 					Direction:       direction,
 					EntryIndicators: indicators,
+					Regime:          indicators.Regime,
+					Size:            RegimeSize(config, indicators.Regime),
 				}
+				openTrade = newOpenTrade(direction, currentCandle.Close, i)
 			}
 		}
 	}
@@ -163,15 +174,12 @@ func GenerateAllSignals(strategyData *StrategyDataContext, config *config.Config
 	var signals []EntrySignal
 
 	for i := range strategyData.Candles {
-		if i < 14 { // Hardcoded ATR period in DetectBBWState
-			continue
-		}
-		if i < config.VWZPeriod-1 || i < config.ADXPeriod-1 {
+		if !strategyData.canSignal(i, config) {
 			continue
 		}
 
 		indicators := strategyData.createTechnicalIndicators(i, config)
-		direction, entry, _ := DetermineEntrySignal(indicators, config, longCondition, shortCondition)
+		direction, entry, _ := strategyData.traceDecision(i, "entry", indicators, config, longCondition, shortCondition)
 
 		if entry {
 			signal := EntrySignal{
@@ -185,3 +193,13 @@ func GenerateAllSignals(strategyData *StrategyDataContext, config *config.Config
 
 	return signals
 }
+
+// canSignal reports whether candle i may produce an entry signal: its indicators are warmed up
+// and it lies inside the trading window and session.
+func (s *StrategyDataContext) canSignal(i int, config *config.Config) bool {
+	// The BBW regime compares against an ATR that starts at candle atrPeriod.
+	if i < atrPeriod(config) || i < config.VWZPeriod-1 || i < config.ADXPeriod-1 || i < lookbackDepth(config)-1 {
+		return false
+	}
+	return i >= s.StartIndex && s.inSession(i)
+}
@@ -2,6 +2,7 @@ package strategy
 
 import (
 	"go-backtesting/config"
+	"go-backtesting/market"
 	"testing"
 )
 
@@ -38,17 +39,22 @@ func TestRunBacktest(t *testing.T) {
 		t.Fatal("No candle data loaded")
 	}
 
-	longCondition, err := GetEntryCondition(cfg.LongCondition, "long")
+	longCondition, err := GetEntryCondition(cfg.LongCondition, "long", cfg)
 	if err != nil {
 		t.Fatalf("Failed to get long entry condition: %v", err)
 	}
 
-	shortCondition, err := GetEntryCondition(cfg.ShortCondition, "short")
+	shortCondition, err := GetEntryCondition(cfg.ShortCondition, "short", cfg)
 	if err != nil {
 		t.Fatalf("Failed to get short entry condition: %v", err)
 	}
 
-	result := RunBacktest(strategyData, cfg, longCondition, shortCondition)
+	exits, err := ResolveExits(cfg, longCondition, shortCondition)
+	if err != nil {
+		t.Fatalf("Failed to resolve exits: %v", err)
+	}
+
+	result := RunBacktest(strategyData, cfg, longCondition, shortCondition, exits)
 
 	if result.TotalTrades != 0 {
 		t.Logf("Expected trades got %d", result.TotalTrades)
@@ -118,14 +124,31 @@ func TestMACDIntegration(t *testing.T) {
 		t.Fatalf("InitializeStrategyDataContext failed: %v", err)
 	}
 
-	if len(strategyData.MACD) == 0 {
+	macd := strategyData.Series(SeriesMACD)
+	if len(macd) == 0 {
 		t.Fatal("MACD slice is empty")
 	}
 
 	expectedLastMACD := 76.93
-	lastMACD := strategyData.MACD[len(strategyData.MACD)-1]
+	lastMACD := macd[len(macd)-1]
 
 	if !CloseEnough(lastMACD, expectedLastMACD, 0.01) {
 		t.Errorf("Expected last MACD to be %.2f, but got %.2f", expectedLastMACD, lastMACD)
 	}
 }
+
+func TestCanSignalWaitsForATR(t *testing.T) {
+	s := &StrategyDataContext{Candles: make([]market.Candle, 40)}
+	for _, tt := range []struct {
+		atrPeriod, first int
+	}{
+		{0, 14},
+		{7, 7},
+		{30, 30},
+	} {
+		cfg := &config.Config{ATRPeriod: tt.atrPeriod}
+		if s.canSignal(tt.first-1, cfg) || !s.canSignal(tt.first, cfg) {
+			t.Errorf("Expected ATR period %d to allow signals from candle %d", tt.atrPeriod, tt.first)
+		}
+	}
+}
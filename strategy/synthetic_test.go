@@ -0,0 +1,152 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"testing"
+	"time"
+)
+
+func syntheticSource(seed uint64) market.Synthetic {
+	return market.Synthetic{
+		Start:      time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		Interval:   5 * time.Minute,
+		StartPrice: 100,
+		Seed:       seed,
+	}
+}
+
+func TestDetectBBWStateOnSqueezeBreakout(t *testing.T) {
+	base := syntheticSource(11).GBM(400, 0, 0.004)
+	candles := market.InjectSqueezeBreakout(base, market.SqueezeBreakout{
+		At: 200, SqueezeBars: 60, Compression: 0.15, BreakoutBars: 20, Move: 0.15,
+	})
+
+	counts := map[MarketState]map[string]int{}
+	for i := 40; i < len(candles); i++ {
+		phase := "other"
+		switch {
+		case i >= 205 && i < 260:
+			phase = "squeeze"
+		case i >= 262 && i < 280:
+			phase = "breakout"
+		}
+		state := DetectBBWState(candles[:i+1], 20, 2.0, 0.5, 14)
+		if counts[state.Status] == nil {
+			counts[state.Status] = map[string]int{}
+		}
+		counts[state.Status][phase]++
+	}
+
+	if counts[Squeeze]["squeeze"] == 0 {
+		t.Errorf("Expected Squeeze states while volatility is compressed, got %v", counts)
+	}
+	if counts[ExpandingBullish]["breakout"] == 0 {
+		t.Errorf("Expected ExpandingBullish states during the upside breakout, got %v", counts)
+	}
+	if counts[ExpandingBearish]["breakout"] > 0 {
+		t.Errorf("Expected no ExpandingBearish states during the upside breakout, got %v", counts)
+	}
+}
+
+func TestDMIAlignsWithSyntheticTrends(t *testing.T) {
+	candles, regimes := syntheticSource(5).RegimeSwitching(3000, market.RegimeSwitchingParams{
+		TrendDrift:      0.002,
+		TrendVolatility: 0.002,
+		RangeVolatility: 0.002,
+		RangeReversion:  0.2,
+		SwitchProb:      0.01,
+	})
+	cfg := &config.Config{ADXPeriod: 14, BBWPeriod: 20, BBWMultiplier: 2}
+	set, err := ComputeIndicators(candles, cfg)
+	if err != nil {
+		t.Fatalf("ComputeIndicators failed: %v", err)
+	}
+	s := &StrategyDataContext{Candles: candles, Indicators: set}
+	params := config.DMIConditionParams{ADXThreshold: 20, DXThreshold: 5}
+	long, short := NewDMILongCondition(params), NewDMIShortCondition(params)
+
+	type counts struct{ bars, longEntries, shortEntries, longStops, shortStops int }
+	byRegime := map[market.SyntheticRegime]*counts{}
+	// Skip the first bars of each regime so the indicators have caught up with it.
+	for i := 30; i < len(candles); i++ {
+		settled := true
+		for k := i - 20; k <= i; k++ {
+			if regimes[k] != regimes[i] {
+				settled = false
+				break
+			}
+		}
+		if !settled {
+			continue
+		}
+		c := byRegime[regimes[i]]
+		if c == nil {
+			c = &counts{}
+			byRegime[regimes[i]] = c
+		}
+		indicators := s.createTechnicalIndicators(i, cfg)
+		longEntry, longStop := long(indicators)
+		shortEntry, shortStop := short(indicators)
+		c.bars++
+		c.longEntries += btoi(longEntry)
+		c.shortEntries += btoi(shortEntry)
+		c.longStops += btoi(longStop)
+		c.shortStops += btoi(shortStop)
+	}
+
+	up, down, ranging := byRegime[market.RegimeTrendUp], byRegime[market.RegimeTrendDown], byRegime[market.RegimeRange]
+	if up == nil || down == nil || ranging == nil {
+		t.Fatalf("Expected settled bars of every regime in the synthetic series, but got %v", byRegime)
+	}
+	for _, tt := range []struct {
+		regime                string
+		c                     *counts
+		with, against         int
+		withStop, againstStop int
+	}{
+		{"trend_up", up, up.longEntries, up.shortEntries, up.longStops, up.shortStops},
+		{"trend_down", down, down.shortEntries, down.longEntries, down.shortStops, down.longStops},
+	} {
+		if tt.with == 0 || tt.against > 0 {
+			t.Errorf("Expected only with-trend DMI entries in %s, but got %d with and %d against", tt.regime, tt.with, tt.against)
+		}
+		if float64(tt.withStop) > 0.1*float64(tt.c.bars) || float64(tt.againstStop) < 0.9*float64(tt.c.bars) {
+			t.Errorf("Expected the DI stop to flag the counter-trend side in %s, but got %d with-trend and %d counter-trend stops over %d bars",
+				tt.regime, tt.withStop, tt.againstStop, tt.c.bars)
+		}
+	}
+	if entries := ranging.longEntries + ranging.shortEntries; float64(entries) > 0.1*float64(ranging.bars) {
+		t.Errorf("Expected few DMI entries in the range regime, but got %d over %d bars", entries, ranging.bars)
+	}
+}
+
+func btoi(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+func TestBBWStateSeriesMatchesDetectBBWState(t *testing.T) {
+	base := syntheticSource(11).GBM(400, 0, 0.004)
+	candles := market.InjectSqueezeBreakout(base, market.SqueezeBreakout{
+		At: 200, SqueezeBars: 60, Compression: 0.15, BreakoutBars: 20, Move: 0.15,
+	})
+
+	for _, atrPeriod := range []int{14, 60} {
+		states := BBWStateSeries(candles, 20, 2.0, 0.5, atrPeriod)
+		if len(states) != len(candles) {
+			t.Fatalf("Expected %d states, but got %d", len(candles), len(states))
+		}
+		for i := range candles {
+			want := referenceBBWState(candles[:i+1], 20, 2.0, 0.5, atrPeriod)
+			if states[i] != want {
+				t.Fatalf("Expected state at %d with ATR period %d to be %+v, but got %+v", i, atrPeriod, want, states[i])
+			}
+			if got := DetectBBWState(candles[:i+1], 20, 2.0, 0.5, atrPeriod); got != want {
+				t.Fatalf("Expected DetectBBWState at %d with ATR period %d to be %+v, but got %+v", i, atrPeriod, want, got)
+			}
+		}
+	}
+}
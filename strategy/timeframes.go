@@ -0,0 +1,72 @@
+package strategy
+
+import (
+	"fmt"
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"time"
+)
+
+// baseTimeframe returns the configured base bar interval, or the one inferred from the candles.
+func baseTimeframe(candles market.CandleSticks, cfg *config.Config) (time.Duration, error) {
+	if cfg.BaseTimeframe != "" {
+		d, err := market.ParseTimeframe(cfg.BaseTimeframe)
+		if err != nil {
+			return 0, fmt.Errorf("invalid base timeframe: %w", err)
+		}
+		return d, nil
+	}
+	if d := market.InferInterval(candles); d > 0 {
+		return d, nil
+	}
+	return 0, fmt.Errorf("cannot determine base timeframe from candle data")
+}
+
+// higherTimeframe parses a higher timeframe and checks that it is a multiple of the base interval.
+func higherTimeframe(timeframe string, baseInterval time.Duration) (time.Duration, error) {
+	tf, err := market.ParseTimeframe(timeframe)
+	if err != nil {
+		return 0, fmt.Errorf("invalid higher timeframe: %w", err)
+	}
+	if tf <= baseInterval || tf%baseInterval != 0 {
+		return 0, fmt.Errorf("higher timeframe %s must be a multiple of the base timeframe %s", timeframe, baseInterval)
+	}
+	return tf, nil
+}
+
+// resampleForIndicator resamples candles to a higher timeframe and returns the resampled bars
+// together with the completed-bar index used to align their indicators to the base candles.
+func resampleForIndicator(candles market.CandleSticks, timeframe string, cfg *config.Config) (market.CandleSticks, []int, error) {
+	baseInterval, err := baseTimeframe(candles, cfg)
+	if err != nil {
+		return nil, nil, err
+	}
+	tf, err := higherTimeframe(timeframe, baseInterval)
+	if err != nil {
+		return nil, nil, err
+	}
+	bars := market.Resample(candles, tf)
+	return bars, market.CompletedBarIndex(candles, baseInterval, bars, tf), nil
+}
+
+// alignToBase projects a higher-timeframe series onto base candles using a completed-bar index.
+func alignToBase(series []float64, barIndex []int) []float64 {
+	aligned := make([]float64, len(barIndex))
+	for i, j := range barIndex {
+		if j < 0 || j >= len(series) {
+			aligned[i] = math.NaN()
+			continue
+		}
+		aligned[i] = series[j]
+	}
+	return aligned
+}
+
+func nanSeries(n int) []float64 {
+	s := make([]float64, n)
+	for i := range s {
+		s[i] = math.NaN()
+	}
+	return s
+}
@@ -0,0 +1,96 @@
+package strategy
+
+import (
+	"go-backtesting/config"
+	"go-backtesting/market"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/markcheno/go-talib"
+)
+
+func TestHigherTimeframesNoLookAhead(t *testing.T) {
+	candles, err := market.ReadCandlesFromCSV("test_data.csv")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	cfg := &config.Config{
+		EmaPeriod:        2,
+		ADXPeriod:        2,
+		HigherTimeframes: []config.HigherTimeframeConfig{{Timeframe: "15m"}},
+	}
+
+	set, err := ComputeIndicators(candles, cfg)
+	if err != nil {
+		t.Fatalf("ComputeIndicators failed: %v", err)
+	}
+	aligned := set.Get("ema_short_15m")
+	if len(aligned) != len(candles) || !set.Has("adx_15m.plus_di") {
+		t.Fatalf("Expected ema_short_15m and adx_15m series of length %d, but got %v", len(candles), set.Names())
+	}
+
+	bars := market.Resample(candles, 15*time.Minute)
+	barIndex := market.CompletedBarIndex(candles, 5*time.Minute, bars, 15*time.Minute)
+	ema := talib.Ema(closesOf(bars), 2)
+	for i, c := range candles {
+		j := barIndex[i]
+		if j < 0 {
+			if !math.IsNaN(aligned[i]) {
+				t.Errorf("Candle %d: expected NaN before the first completed bar", i)
+			}
+			continue
+		}
+		barEnd := bars[j].Time.Add(15 * time.Minute)
+		if barEnd.After(c.Time.Add(5 * time.Minute)) {
+			t.Errorf("Candle %d at %v sees higher bar ending %v", i, c.Time, barEnd)
+		}
+		if !sameFloat(aligned[i], ema[j]) {
+			t.Errorf("Candle %d: aligned EMA %f does not match higher bar %d EMA %f", i, aligned[i], j, ema[j])
+		}
+	}
+}
+
+func TestHigherTimeframesYieldToDeclarations(t *testing.T) {
+	candles, err := market.ReadCandlesFromCSV("test_data.csv")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	cfg := &config.Config{
+		EmaPeriod:        2,
+		ADXPeriod:        2,
+		HigherTimeframes: []config.HigherTimeframeConfig{{Timeframe: "15m", ADXPeriod: 3}},
+		Indicators:       []config.IndicatorConfig{{Name: "adx_15m", Type: "dmi", Timeframe: "15m", Params: map[string]float64{"period": 4}}},
+	}
+
+	decls := mergeIndicatorConfigs(DefaultIndicators(cfg), cfg.Indicators)
+	count := 0
+	for _, decl := range decls {
+		if decl.Name == "adx_15m" {
+			count++
+			if decl.Params["period"] != 4 {
+				t.Errorf("Expected the declared adx_15m to replace the shorthand, but got %+v", decl)
+			}
+		}
+	}
+	if count != 1 {
+		t.Errorf("Expected adx_15m declared once, but got %d declarations", count)
+	}
+	if _, err := ComputeIndicators(candles, cfg); err != nil {
+		t.Errorf("ComputeIndicators failed: %v", err)
+	}
+}
+
+func TestHigherTimeframesRejectNonMultiple(t *testing.T) {
+	candles, err := market.ReadCandlesFromCSV("test_data.csv")
+	if err != nil {
+		t.Fatalf("Failed to read test data: %v", err)
+	}
+	cfg := &config.Config{
+		BaseTimeframe:    "5m",
+		HigherTimeframes: []config.HigherTimeframeConfig{{Timeframe: "7m"}},
+	}
+	if _, err := ComputeIndicators(candles, cfg); err == nil {
+		t.Error("Expected an error for a timeframe that is not a multiple of the base timeframe")
+	}
+}